@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/auth"
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	accountStatusActive       = "active"
+	accountStatusSuspended    = "suspended"
+	accountStatusReadOnly     = "read_only"
+	accountStatusShadowBanned = "shadow_banned"
+
+	actionMakeReadOnly = "make_read_only"
+	actionShadowBan    = "shadow_ban"
+	actionRestoreUser  = "restore_user"
+)
+
+var (
+	errAccountSuspended = errors.New("account is suspended")
+	errAccountReadOnly  = errors.New("account is read-only")
+)
+
+// effectiveAccountStatus is the user's account status, treating a
+// restriction whose end time has passed as lifted.
+func effectiveAccountStatus(user database.User) string {
+	if user.AccountStatusUntil.Valid && time.Now().After(user.AccountStatusUntil.Time) {
+		return accountStatusActive
+	}
+	return user.AccountStatus
+}
+
+// authenticateAnyStatus validates the request's bearer JWT and loads its
+// user whatever their account status.
+func (cfg *apiConfig) authenticateAnyStatus(r *http.Request) (database.User, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return database.User{}, err
+	}
+	user, _, err := cfg.userFromToken(r.Context(), token)
+	return user, err
+}
+
+// userFromToken validates an access token and returns its user, whatever
+// their account status, and when the token expires.
+func (cfg *apiConfig) userFromToken(ctx context.Context, token string) (database.User, time.Time, error) {
+	userID, expiresAt, err := auth.ValidateJWTWithExpiry(token, cfg.JWTSecret)
+	if err != nil {
+		return database.User{}, time.Time{}, err
+	}
+	user, err := cfg.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return database.User{}, time.Time{}, err
+	}
+	return user, expiresAt, nil
+}
+
+// authenticateUser is authenticateAnyStatus but refuses suspended users even
+// while their access token is still valid.
+func (cfg *apiConfig) authenticateUser(r *http.Request) (database.User, error) {
+	user, err := cfg.authenticateAnyStatus(r)
+	if err != nil {
+		return database.User{}, err
+	}
+	if effectiveAccountStatus(user) == accountStatusSuspended {
+		return database.User{}, errAccountSuspended
+	}
+	return user, nil
+}
+
+// authenticate returns the ID of the request's authenticated user.
+func (cfg *apiConfig) authenticate(r *http.Request) (uuid.UUID, error) {
+	user, err := cfg.authenticateUser(r)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	return user.ID, nil
+}
+
+// authenticateWriter is authenticate for handlers that publish content, which
+// read-only accounts may not do.
+func (cfg *apiConfig) authenticateWriter(r *http.Request) (uuid.UUID, error) {
+	user, err := cfg.authenticateUser(r)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	if effectiveAccountStatus(user) == accountStatusReadOnly {
+		return uuid.UUID{}, errAccountReadOnly
+	}
+	return user.ID, nil
+}
+
+// writeAuthError answers a request that failed authentication, telling
+// restricted accounts why instead of claiming the token was bad.
+func writeAuthError(w http.ResponseWriter, err error) {
+	log.Printf("Error failed to authenticate: %s\n", err)
+	if errors.Is(err, errAccountSuspended) || errors.Is(err, errAccountReadOnly) {
+		w.WriteHeader(403)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.WriteHeader(401)
+	w.Write([]byte("Failed to validate JWT"))
+}
+
+// setAccountStatus changes a user's restriction. Suspending also revokes
+// every refresh token so the account cannot mint new access tokens.
+func (cfg *apiConfig) setAccountStatus(ctx context.Context, q *database.Queries, userID uuid.UUID, status string, until sql.NullTime, reason string) error {
+	err := q.UpdateUserAccountStatus(ctx, database.UpdateUserAccountStatusParams{
+		AccountStatus:       status,
+		AccountStatusUntil:  until,
+		AccountStatusReason: reason,
+		ID:                  userID,
+	})
+	if err != nil {
+		return err
+	}
+	if status == accountStatusSuspended {
+		return q.RevokeAllRefreshTokensForUser(ctx, userID)
+	}
+	return nil
+}
+
+type AccountStatus struct {
+	UserID uuid.UUID  `json:"user_id"`
+	Status string     `json:"status"`
+	Until  *time.Time `json:"until"`
+	Reason string     `json:"reason"`
+}
+
+func (cfg *apiConfig) handlerAdminUsersSuspend(w http.ResponseWriter, r *http.Request) {
+	cfg.restrictUser(w, r, accountStatusSuspended, actionSuspendUser)
+}
+
+func (cfg *apiConfig) handlerAdminUsersReadOnly(w http.ResponseWriter, r *http.Request) {
+	cfg.restrictUser(w, r, accountStatusReadOnly, actionMakeReadOnly)
+}
+
+func (cfg *apiConfig) handlerAdminUsersShadowBan(w http.ResponseWriter, r *http.Request) {
+	cfg.restrictUser(w, r, accountStatusShadowBanned, actionShadowBan)
+}
+
+func (cfg *apiConfig) handlerAdminUsersRestore(w http.ResponseWriter, r *http.Request) {
+	cfg.restrictUser(w, r, accountStatusActive, actionRestoreUser)
+}
+
+func (cfg *apiConfig) restrictUser(w http.ResponseWriter, r *http.Request, status, action string) {
+	type parameters struct {
+		Until  *time.Time `json:"until"`
+		Reason string     `json:"reason"`
+	}
+	moderator, err := cfg.authenticateModerator(r)
+	if err != nil {
+		log.Printf("Error failed to authenticate moderator: %s\n", err)
+		w.WriteHeader(403)
+		w.Write([]byte("Moderator access required"))
+		return
+	}
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing userID"))
+		return
+	}
+	params := parameters{}
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		err = decoder.Decode(&params)
+		if err != nil {
+			log.Printf("Error decoding account status JSON: %s", err)
+			w.WriteHeader(400)
+			w.Write([]byte("Failed to decode account status"))
+			return
+		}
+	}
+	until := sql.NullTime{}
+	if params.Until != nil && status != accountStatusActive {
+		if !params.Until.After(time.Now()) {
+			w.WriteHeader(400)
+			w.Write([]byte("until must be in the future"))
+			return
+		}
+		until = sql.NullTime{Time: *params.Until, Valid: true}
+	}
+	user, err := cfg.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("User does not exist"))
+		return
+	}
+
+	// The recorded action is what the user appeals, so the status only
+	// changes along with it.
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		err := cfg.setAccountStatus(r.Context(), q, user.ID, status, until, params.Reason)
+		if err != nil {
+			return err
+		}
+		_, err = q.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			ModeratorID:  uuid.NullUUID{UUID: moderator.ID, Valid: true},
+			Action:       action,
+			TargetUserID: user.ID,
+			Reason:       params.Reason,
+		})
+		return err
+	})
+	if err != nil {
+		log.Printf("Error updating account status: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to update account status"))
+		return
+	}
+
+	dat, err := json.Marshal(AccountStatus{
+		UserID: user.ID,
+		Status: status,
+		Until:  nullTimePtr(until),
+		Reason: params.Reason,
+	})
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
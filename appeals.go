@@ -0,0 +1,489 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/auth"
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	appealStatusPending  = "pending"
+	appealStatusUpheld   = "upheld"
+	appealStatusReversed = "reversed"
+
+	actionFileAppeal    = "file_appeal"
+	actionUpholdAppeal  = "uphold_appeal"
+	actionReverseAppeal = "reverse_appeal"
+	actionRestoreChirp  = "restore_chirp"
+)
+
+// appealableActions maps each action a user may appeal to the chirp or
+// account status it left behind, which reversing the appeal undoes.
+var appealableActions = map[string]string{
+	actionHoldChirp:    chirpStatusHeld,
+	actionHideChirp:    chirpStatusHidden,
+	actionSuspendUser:  accountStatusSuspended,
+	actionMakeReadOnly: accountStatusReadOnly,
+	actionShadowBan:    accountStatusShadowBanned,
+}
+
+type Appeal struct {
+	ID           uuid.UUID  `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	UserID       uuid.UUID  `json:"user_id"`
+	ActionID     uuid.UUID  `json:"action_id"`
+	Statement    string     `json:"statement"`
+	Status       string     `json:"status"`
+	DecidedBy    *uuid.UUID `json:"decided_by,omitempty"`
+	DecidedAt    *time.Time `json:"decided_at,omitempty"`
+	DecisionNote string     `json:"decision_note,omitempty"`
+}
+
+func formatAppeal(appeal database.Appeal) Appeal {
+	return Appeal{
+		ID:           appeal.ID,
+		CreatedAt:    appeal.CreatedAt,
+		UpdatedAt:    appeal.UpdatedAt,
+		UserID:       appeal.UserID,
+		ActionID:     appeal.ActionID,
+		Statement:    appeal.Statement,
+		Status:       appeal.Status,
+		DecidedBy:    nullUUIDPtr(appeal.DecidedBy),
+		DecidedAt:    nullTimePtr(appeal.DecidedAt),
+		DecisionNote: appeal.DecisionNote,
+	}
+}
+
+// listAppealableActions returns the actions taken against the user that
+// they may appeal.
+func (cfg *apiConfig) listAppealableActions(ctx context.Context, userID uuid.UUID) ([]ModerationAction, error) {
+	actions, err := cfg.queries.ListModerationActionsForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	formattedActions := []ModerationAction{}
+	for _, action := range actions {
+		if _, ok := appealableActions[action.Action]; ok {
+			formattedActions = append(formattedActions, formatModerationAction(action))
+		}
+	}
+	return formattedActions, nil
+}
+
+// writeSuspendedLogin refuses a login to a suspended account. Without a
+// JWT the user cannot list their moderation actions, so the ones they may
+// appeal are included for POST /api/appeals.
+func (cfg *apiConfig) writeSuspendedLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	actions, err := cfg.listAppealableActions(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Error listing moderation actions: %s", err)
+		actions = []ModerationAction{}
+	}
+	resp := struct {
+		Error             string             `json:"error"`
+		Until             *time.Time         `json:"until"`
+		Reason            string             `json:"reason"`
+		AppealableActions []ModerationAction `json:"appealable_actions"`
+	}{
+		Error:             "Account is suspended",
+		Until:             nullTimePtr(user.AccountStatusUntil),
+		Reason:            user.AccountStatusReason,
+		AppealableActions: actions,
+	}
+	dat, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		w.WriteHeader(403)
+		return
+	}
+	w.WriteHeader(403)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerModerationActionsListMine(w http.ResponseWriter, r *http.Request) {
+	user, err := cfg.authenticateAnyStatus(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	formattedActions, err := cfg.listAppealableActions(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Error listing moderation actions: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list moderation actions"))
+		return
+	}
+	dat, err := json.Marshal(formattedActions)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerAppealsCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ActionID  uuid.UUID `json:"action_id"`
+		Statement string    `json:"statement"`
+		Email     string    `json:"email"`
+		Password  string    `json:"password"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding appeal JSON: %s", err)
+		w.WriteHeader(400)
+		w.Write([]byte("Failed to decode appeal"))
+		return
+	}
+
+	// Suspended users cannot log in, so they may prove who they are with
+	// their credentials instead of a JWT.
+	var user database.User
+	if r.Header.Get("Authorization") != "" {
+		user, err = cfg.authenticateAnyStatus(r)
+		if err != nil {
+			writeAuthError(w, err)
+			return
+		}
+	} else {
+		user, err = cfg.queries.GetUserByEmail(r.Context(), params.Email)
+		if err == nil {
+			err = auth.CheckPasswordHash(user.HashedPassword, params.Password)
+		}
+		if err != nil {
+			w.WriteHeader(401)
+			w.Write([]byte("incorrect email or password"))
+			return
+		}
+	}
+
+	if params.Statement == "" {
+		w.WriteHeader(400)
+		w.Write([]byte("Appeal needs a statement"))
+		return
+	}
+	action, err := cfg.queries.GetModerationAction(r.Context(), params.ActionID)
+	if err != nil || action.TargetUserID != user.ID {
+		w.WriteHeader(404)
+		w.Write([]byte("Moderation action does not exist"))
+		return
+	}
+	if _, ok := appealableActions[action.Action]; !ok {
+		w.WriteHeader(400)
+		w.Write([]byte("Moderation action cannot be appealed"))
+		return
+	}
+
+	var appeal database.Appeal
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		appeal, err = q.CreateAppeal(r.Context(), database.CreateAppealParams{
+			UserID:    user.ID,
+			ActionID:  action.ID,
+			Statement: params.Statement,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAppealTransition(r.Context(), q, appeal, action, uuid.NullUUID{}, actionFileAppeal, params.Statement)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(409)
+		w.Write([]byte("Moderation action was already appealed"))
+		return
+	}
+	if err != nil {
+		log.Printf("Error creating appeal: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to create appeal"))
+		return
+	}
+
+	dat, err := json.Marshal(formatAppeal(appeal))
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(201)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerAppealsListMine(w http.ResponseWriter, r *http.Request) {
+	user, err := cfg.authenticateAnyStatus(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	appeals, err := cfg.queries.ListAppealsForUser(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Error listing appeals: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list appeals"))
+		return
+	}
+	formattedAppeals := []Appeal{}
+	for _, appeal := range appeals {
+		formattedAppeals = append(formattedAppeals, formatAppeal(appeal))
+	}
+	dat, err := json.Marshal(formattedAppeals)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerAdminAppealsList(w http.ResponseWriter, r *http.Request) {
+	_, err := cfg.authenticateModerator(r)
+	if err != nil {
+		log.Printf("Error failed to authenticate moderator: %s\n", err)
+		w.WriteHeader(403)
+		w.Write([]byte("Moderator access required"))
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = appealStatusPending
+	}
+	if status != appealStatusPending && status != appealStatusUpheld && status != appealStatusReversed {
+		w.WriteHeader(400)
+		w.Write([]byte("Unknown appeal status"))
+		return
+	}
+	appeals, err := cfg.queries.ListAppealsByStatus(r.Context(), status)
+	if err != nil {
+		log.Printf("Error listing appeals: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list appeals"))
+		return
+	}
+	formattedAppeals := []Appeal{}
+	for _, appeal := range appeals {
+		formattedAppeals = append(formattedAppeals, formatAppeal(appeal))
+	}
+	dat, err := json.Marshal(formattedAppeals)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerAdminAppealsGet(w http.ResponseWriter, r *http.Request) {
+	_, err := cfg.authenticateModerator(r)
+	if err != nil {
+		log.Printf("Error failed to authenticate moderator: %s\n", err)
+		w.WriteHeader(403)
+		w.Write([]byte("Moderator access required"))
+		return
+	}
+	appealID, err := uuid.Parse(r.PathValue("appealID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing appealID"))
+		return
+	}
+	appeal, err := cfg.queries.GetAppeal(r.Context(), appealID)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("Appeal does not exist"))
+		return
+	}
+	action, err := cfg.queries.GetModerationAction(r.Context(), appeal.ActionID)
+	if err != nil {
+		log.Printf("Error getting appealed action: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to get appealed action"))
+		return
+	}
+	history, err := cfg.queries.ListModerationActionsForAppeal(r.Context(), uuid.NullUUID{UUID: appeal.ID, Valid: true})
+	if err != nil {
+		log.Printf("Error listing moderation actions: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list moderation actions"))
+		return
+	}
+	resp := struct {
+		Appeal
+		Action  ModerationAction   `json:"action"`
+		History []ModerationAction `json:"history"`
+	}{
+		Appeal:  formatAppeal(appeal),
+		Action:  formatModerationAction(action),
+		History: []ModerationAction{},
+	}
+	for _, transition := range history {
+		resp.History = append(resp.History, formatModerationAction(transition))
+	}
+	dat, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerAdminAppealsUphold(w http.ResponseWriter, r *http.Request) {
+	cfg.decideAppeal(w, r, appealStatusUpheld)
+}
+
+func (cfg *apiConfig) handlerAdminAppealsReverse(w http.ResponseWriter, r *http.Request) {
+	cfg.decideAppeal(w, r, appealStatusReversed)
+}
+
+func (cfg *apiConfig) decideAppeal(w http.ResponseWriter, r *http.Request, status string) {
+	type parameters struct {
+		Note string `json:"note"`
+	}
+	moderator, err := cfg.authenticateModerator(r)
+	if err != nil {
+		log.Printf("Error failed to authenticate moderator: %s\n", err)
+		w.WriteHeader(403)
+		w.Write([]byte("Moderator access required"))
+		return
+	}
+	appealID, err := uuid.Parse(r.PathValue("appealID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing appealID"))
+		return
+	}
+	params := parameters{}
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		err = decoder.Decode(&params)
+		if err != nil {
+			log.Printf("Error decoding appeal decision JSON: %s", err)
+			w.WriteHeader(400)
+			w.Write([]byte("Failed to decode appeal decision"))
+			return
+		}
+	}
+
+	moderatorID := uuid.NullUUID{UUID: moderator.ID, Valid: true}
+	var appeal database.Appeal
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		appeal, err = q.DecideAppeal(r.Context(), database.DecideAppealParams{
+			ID:           appealID,
+			Status:       status,
+			DecidedBy:    moderatorID,
+			DecisionNote: params.Note,
+		})
+		if err != nil {
+			return err
+		}
+		action, err := q.GetModerationAction(r.Context(), appeal.ActionID)
+		if err != nil {
+			return fmt.Errorf("getting appealed action: %w", err)
+		}
+		if status == appealStatusUpheld {
+			return recordAppealTransition(r.Context(), q, appeal, action, moderatorID, actionUpholdAppeal, params.Note)
+		}
+		err = recordAppealTransition(r.Context(), q, appeal, action, moderatorID, actionReverseAppeal, params.Note)
+		if err != nil {
+			return err
+		}
+		return cfg.undoModerationAction(r.Context(), q, appeal, action, moderatorID)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(409)
+		w.Write([]byte("Appeal does not exist or was already decided"))
+		return
+	}
+	if err != nil {
+		log.Printf("Error deciding appeal: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to decide appeal"))
+		return
+	}
+
+	dat, err := json.Marshal(formatAppeal(appeal))
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+// undoModerationAction restores the chirp or account an appealed action
+// restricted. It leaves things alone when a later action has already
+// changed the state, so reversing an old appeal cannot lift a newer
+// restriction.
+func (cfg *apiConfig) undoModerationAction(ctx context.Context, q *database.Queries, appeal database.Appeal, action database.ModerationAction, moderatorID uuid.NullUUID) error {
+	restrictedStatus := appealableActions[action.Action]
+	switch action.Action {
+	case actionHoldChirp, actionHideChirp:
+		if !action.TargetChirpID.Valid {
+			return nil
+		}
+		chirp, err := q.GetChirp(ctx, action.TargetChirpID.UUID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if chirp.Status != restrictedStatus {
+			return nil
+		}
+		// A held chirp was never published, so it gets what publishing a
+		// new chirp does.
+		if chirp.Status == chirpStatusHeld {
+			err = publishHeldChirp(ctx, q, chirp)
+		} else {
+			err = q.UpdateChirpStatus(ctx, database.UpdateChirpStatusParams{
+				Status: chirpStatusPublished,
+				ID:     chirp.ID,
+			})
+		}
+		if err != nil {
+			return err
+		}
+		return recordAppealTransition(ctx, q, appeal, action, moderatorID, actionRestoreChirp, "")
+	default:
+		user, err := q.GetUserByID(ctx, action.TargetUserID)
+		if err != nil {
+			return err
+		}
+		if user.AccountStatus != restrictedStatus {
+			return nil
+		}
+		err = cfg.setAccountStatus(ctx, q, user.ID, accountStatusActive, sql.NullTime{}, "")
+		if err != nil {
+			return err
+		}
+		return recordAppealTransition(ctx, q, appeal, action, moderatorID, actionRestoreUser, "")
+	}
+}
+
+// recordAppealTransition logs a step in an appeal's life to the moderation
+// action history.
+func recordAppealTransition(ctx context.Context, q *database.Queries, appeal database.Appeal, action database.ModerationAction, moderatorID uuid.NullUUID, transition, reason string) error {
+	_, err := q.CreateModerationAction(ctx, database.CreateModerationActionParams{
+		ModeratorID:   moderatorID,
+		Action:        transition,
+		ReportID:      action.ReportID,
+		TargetUserID:  action.TargetUserID,
+		TargetChirpID: action.TargetChirpID,
+		Reason:        reason,
+		AppealID:      uuid.NullUUID{UUID: appeal.ID, Valid: true},
+	})
+	return err
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/David-Bosnic/chirpy/internal/jobs"
+	"github.com/David-Bosnic/chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+const (
+	chirpStatusPublished = "published"
+	chirpStatusHeld      = "held"
+)
+
+func chirpStatusFor(outcome moderation.Outcome) string {
+	if outcome.Action == moderation.Hold {
+		return chirpStatusHeld
+	}
+	return chirpStatusPublished
+}
+
+// enqueueChirpCreated queues the work that follows a chirp being created or
+// published: fan-out, the live stream and notifications. q should be bound
+// to the transaction that wrote the chirp.
+func enqueueChirpCreated(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	err := enqueueJob(ctx, q, jobFanOutChirp, fanOutJob{ChirpID: chirp.ID}, jobs.Options{})
+	if err != nil {
+		return err
+	}
+	err = enqueueJob(ctx, q, jobNotifyChirp, notifyChirpJob{ChirpID: chirp.ID}, jobs.Options{})
+	if err != nil {
+		return err
+	}
+	return enqueue(ctx, q, outboxChirpCreated, chirp)
+}
+
+// publishHeldChirp publishes a chirp that was waiting for review.
+func publishHeldChirp(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	err := q.UpdateChirpStatus(ctx, database.UpdateChirpStatusParams{
+		Status: chirpStatusPublished,
+		ID:     chirp.ID,
+	})
+	if err != nil {
+		return err
+	}
+	chirp.Status = chirpStatusPublished
+	return enqueueChirpCreated(ctx, q, chirp)
+}
+
+func writeRejectedChirp(w http.ResponseWriter, outcome moderation.Outcome) {
+	resp := struct {
+		Error      string             `json:"error"`
+		Moderation moderation.Outcome `json:"moderation"`
+	}{
+		Error:      "Chirp was rejected",
+		Moderation: outcome,
+	}
+	dat, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(400)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) recentChirps(ctx context.Context, authorID uuid.UUID, since time.Time) ([]moderation.RecentChirp, error) {
+	chirps, err := cfg.queries.ListRecentChirpsFromAuthorID(ctx, database.ListRecentChirpsFromAuthorIDParams{
+		UserID:    authorID,
+		CreatedAt: since,
+	})
+	if err != nil {
+		return nil, err
+	}
+	recent := []moderation.RecentChirp{}
+	for _, chirp := range chirps {
+		recent = append(recent, moderation.RecentChirp{
+			ID:   chirp.ID,
+			Body: chirp.Body,
+		})
+	}
+	return recent, nil
+}
+
+func (cfg *apiConfig) accountCreatedAt(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	user, err := cfg.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return user.CreatedAt, nil
+}
+
+func (cfg *apiConfig) handlerChirpsUpdate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+	parsedID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing chirpID"))
+		return
+	}
+	userID, err := cfg.authenticateWriter(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	currentChirp, err := cfg.queries.GetChirp(r.Context(), parsedID)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("Chirp does not exist"))
+		return
+	}
+	if currentChirp.UserID != userID {
+		w.WriteHeader(403)
+		w.Write([]byte("Error User id and chirp owner do not match"))
+		return
+	}
+	if currentChirp.Status == chirpStatusHidden {
+		w.WriteHeader(403)
+		w.Write([]byte("Chirp was hidden by a moderator"))
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding chirps JSON: %s", err)
+		w.WriteHeader(400)
+		w.Write([]byte("Failed to decode chirp"))
+		return
+	}
+	if len(params.Body) > 140 {
+		w.WriteHeader(400)
+		w.Write([]byte("Chirp is too long"))
+		return
+	}
+	if len(params.Body) == 0 {
+		w.WriteHeader(400)
+		w.Write([]byte("Chirp has nothing in the body"))
+		return
+	}
+
+	outcome, err := cfg.moderator.Run(r.Context(), moderation.Submission{
+		AuthorID: userID,
+		ChirpID:  currentChirp.ID,
+		Body:     params.Body,
+	})
+	if err != nil {
+		log.Printf("Error moderating chirp: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to moderate chirp"))
+		return
+	}
+	if outcome.Action == moderation.Reject {
+		writeRejectedChirp(w, outcome)
+		return
+	}
+	// Editing a chirp that is waiting for review must not publish it.
+	status := chirpStatusFor(outcome)
+	if currentChirp.Status == chirpStatusHeld {
+		status = chirpStatusHeld
+	}
+	var chirp database.Chirp
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		chirp, err = q.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+			Body:   outcome.Body,
+			Status: status,
+			ID:     currentChirp.ID,
+		})
+		if err != nil {
+			return err
+		}
+		// A chirp the edit holds needs a report for a moderator to review.
+		if chirp.Status == chirpStatusHeld && currentChirp.Status != chirpStatusHeld {
+			return cfg.queueHeldChirp(r.Context(), q, chirp, outcome)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error updating chirp: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to update chirp"))
+		return
+	}
+	formattedChirp := addTagsToChirp(chirp)
+	formattedChirp.Moderation = &outcome
+
+	dat, err := json.Marshal(formattedChirp)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
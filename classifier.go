@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/David-Bosnic/chirpy/internal/moderation"
+)
+
+const toxicityThreshold = 0.9
+
+// retrainClassifier fits the toxicity classifier to every chirp a moderator
+// has resolved a report on. Chirps that ended up hidden are the toxic
+// examples, so reversed appeals count as clean.
+func (cfg *apiConfig) retrainClassifier(ctx context.Context) (moderation.ClassifierMetrics, error) {
+	chirps, err := cfg.queries.ListResolvedChirpsForTraining(ctx)
+	if err != nil {
+		return moderation.ClassifierMetrics{}, err
+	}
+	examples := []moderation.Example{}
+	for _, chirp := range chirps {
+		examples = append(examples, moderation.Example{
+			Body:  chirp.Body,
+			Toxic: chirp.Status == chirpStatusHidden,
+		})
+	}
+	return cfg.classifier.Train(examples), nil
+}
+
+func (cfg *apiConfig) handlerAdminClassifierRetrain(w http.ResponseWriter, r *http.Request) {
+	_, err := cfg.authenticateModerator(r)
+	if err != nil {
+		log.Printf("Error failed to authenticate moderator: %s\n", err)
+		w.WriteHeader(403)
+		w.Write([]byte("Moderator access required"))
+		return
+	}
+	metrics, err := cfg.retrainClassifier(r.Context())
+	if err != nil {
+		log.Printf("Error retraining classifier: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to retrain classifier"))
+		return
+	}
+	dat, err := json.Marshal(metrics)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerAdminClassifierMetrics(w http.ResponseWriter, r *http.Request) {
+	_, err := cfg.authenticateModerator(r)
+	if err != nil {
+		log.Printf("Error failed to authenticate moderator: %s\n", err)
+		w.WriteHeader(403)
+		w.Write([]byte("Moderator access required"))
+		return
+	}
+	dat, err := json.Marshal(cfg.classifier.Metrics())
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
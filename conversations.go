@@ -0,0 +1,602 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/David-Bosnic/chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+const (
+	maxConversationMembers = 10
+	maxMessageLength       = 1000
+)
+
+var (
+	errConversationBlocked       = errors.New("cannot message a user who is blocking you or whom you block")
+	errConversationFollowersOnly = errors.New("user only accepts messages from followers")
+)
+
+type Conversation struct {
+	ID            uuid.UUID            `json:"id"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+	IsGroup       bool                 `json:"is_group"`
+	LastMessageAt time.Time            `json:"last_message_at"`
+	UnreadCount   int64                `json:"unread_count"`
+	Members       []ConversationMember `json:"members,omitempty"`
+}
+
+type ConversationMember struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	JoinedAt   time.Time  `json:"joined_at"`
+	LastReadAt *time.Time `json:"last_read_at"`
+	LeftAt     *time.Time `json:"left_at,omitempty"`
+}
+
+type Message struct {
+	ID             uuid.UUID           `json:"id"`
+	CreatedAt      time.Time           `json:"created_at"`
+	ConversationID uuid.UUID           `json:"conversation_id"`
+	SenderID       uuid.UUID           `json:"sender_id"`
+	Body           string              `json:"body"`
+	Moderation     *moderation.Outcome `json:"moderation,omitempty"`
+}
+
+func formatConversation(conversation database.Conversation, members []database.ConversationMember) Conversation {
+	formatted := Conversation{
+		ID:            conversation.ID,
+		CreatedAt:     conversation.CreatedAt,
+		UpdatedAt:     conversation.UpdatedAt,
+		IsGroup:       conversation.IsGroup,
+		LastMessageAt: conversation.LastMessageAt,
+		Members:       []ConversationMember{},
+	}
+	for _, member := range members {
+		formatted.Members = append(formatted.Members, ConversationMember{
+			UserID:     member.UserID,
+			JoinedAt:   member.JoinedAt,
+			LastReadAt: nullTimePtr(member.LastReadAt),
+			LeftAt:     nullTimePtr(member.LeftAt),
+		})
+	}
+	return formatted
+}
+
+func formatMessage(message database.Message) Message {
+	return Message{
+		ID:             message.ID,
+		CreatedAt:      message.CreatedAt,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		Body:           message.Body,
+	}
+}
+
+// messagingQueries is what canMessage reads. *database.Queries satisfies
+// it.
+type messagingQueries interface {
+	IsBlockedEitherWay(ctx context.Context, arg database.IsBlockedEitherWayParams) (bool, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error)
+	IsFollowing(ctx context.Context, arg database.IsFollowingParams) (bool, error)
+}
+
+// canMessage reports why senderID may not message recipientID, if anything:
+// a block in either direction, or a recipient who only accepts messages
+// from their followers.
+func canMessage(ctx context.Context, q messagingQueries, senderID, recipientID uuid.UUID) error {
+	blocked, err := q.IsBlockedEitherWay(ctx, database.IsBlockedEitherWayParams{
+		BlockerID: senderID,
+		BlockedID: recipientID,
+	})
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return errConversationBlocked
+	}
+	recipient, err := q.GetUserByID(ctx, recipientID)
+	if err != nil {
+		return err
+	}
+	if !recipient.DmsFollowersOnly {
+		return nil
+	}
+	following, err := q.IsFollowing(ctx, database.IsFollowingParams{
+		FollowerID: senderID,
+		FolloweeID: recipientID,
+	})
+	if err != nil {
+		return err
+	}
+	if !following {
+		return errConversationFollowersOnly
+	}
+	return nil
+}
+
+func writeConversationError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errConversationBlocked) || errors.Is(err, errConversationFollowersOnly) {
+		w.WriteHeader(403)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	log.Printf("Error checking messaging permissions: %s", err)
+	w.WriteHeader(500)
+	w.Write([]byte("Failed to check messaging permissions"))
+}
+
+// conversationMember authenticates the caller and resolves {conversationID}
+// to a conversation they are still a member of. Conversations the caller
+// never joined or has left look missing.
+func (cfg *apiConfig) conversationMember(w http.ResponseWriter, r *http.Request, authenticate func(*http.Request) (uuid.UUID, error)) (database.Conversation, database.ConversationMember, bool) {
+	userID, err := authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return database.Conversation{}, database.ConversationMember{}, false
+	}
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing conversationID"))
+		return database.Conversation{}, database.ConversationMember{}, false
+	}
+	member, err := cfg.queries.GetConversationMember(r.Context(), database.GetConversationMemberParams{
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil || member.LeftAt.Valid {
+		w.WriteHeader(404)
+		w.Write([]byte("Conversation does not exist"))
+		return database.Conversation{}, database.ConversationMember{}, false
+	}
+	conversation, err := cfg.queries.GetConversation(r.Context(), conversationID)
+	if err != nil {
+		log.Printf("Error getting conversation: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to get conversation"))
+		return database.Conversation{}, database.ConversationMember{}, false
+	}
+	return conversation, member, true
+}
+
+func (cfg *apiConfig) writeConversation(w http.ResponseWriter, r *http.Request, code int, conversation database.Conversation) {
+	members, err := cfg.queries.ListConversationMembers(r.Context(), conversation.ID)
+	if err != nil {
+		log.Printf("Error listing conversation members: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to get conversation"))
+		return
+	}
+	dat, err := json.Marshal(formatConversation(conversation, members))
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(code)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerConversationsCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticateWriter(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	type parameters struct {
+		MemberIDs []uuid.UUID `json:"member_ids"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error decoding parameters"))
+		return
+	}
+	seen := map[uuid.UUID]bool{userID: true}
+	memberIDs := []uuid.UUID{}
+	for _, memberID := range params.MemberIDs {
+		if seen[memberID] {
+			continue
+		}
+		seen[memberID] = true
+		memberIDs = append(memberIDs, memberID)
+	}
+	if len(memberIDs) == 0 {
+		w.WriteHeader(400)
+		w.Write([]byte("A conversation needs at least one other member"))
+		return
+	}
+	if len(memberIDs)+1 > maxConversationMembers {
+		w.WriteHeader(400)
+		w.Write([]byte("Too many conversation members"))
+		return
+	}
+	for _, memberID := range memberIDs {
+		_, err = cfg.queries.GetUserByID(r.Context(), memberID)
+		if err != nil {
+			w.WriteHeader(404)
+			w.Write([]byte("User does not exist"))
+			return
+		}
+		err = canMessage(r.Context(), cfg.queries, userID, memberID)
+		if err != nil {
+			writeConversationError(w, err)
+			return
+		}
+	}
+	isGroup := len(memberIDs) > 1
+	if !isGroup {
+		existing, err := cfg.queries.FindDirectConversation(r.Context(), database.FindDirectConversationParams{
+			UserID:  userID,
+			OtherID: memberIDs[0],
+		})
+		if err == nil {
+			cfg.writeConversation(w, r, 200, existing)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error finding direct conversation: %s", err)
+			w.WriteHeader(500)
+			w.Write([]byte("Failed to create conversation"))
+			return
+		}
+	}
+	var conversation database.Conversation
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		conversation, err = q.CreateConversation(r.Context(), database.CreateConversationParams{
+			CreatorID: uuid.NullUUID{UUID: userID, Valid: true},
+			IsGroup:   isGroup,
+		})
+		if err != nil {
+			return err
+		}
+		for _, memberID := range append([]uuid.UUID{userID}, memberIDs...) {
+			err = q.AddConversationMember(r.Context(), database.AddConversationMemberParams{
+				ConversationID: conversation.ID,
+				UserID:         memberID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error creating conversation: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to create conversation"))
+		return
+	}
+	cfg.writeConversation(w, r, 201, conversation)
+}
+
+func (cfg *apiConfig) handlerConversationsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	rows, err := cfg.queries.ListConversationsForUser(r.Context(), database.ListConversationsForUserParams{
+		UserID:     userID,
+		BeforeTime: cursor.CreatedAt,
+		BeforeID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		log.Printf("Error listing conversations: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list conversations"))
+		return
+	}
+	page := Page[Conversation]{Items: []Conversation{}}
+	for _, row := range rows {
+		page.Items = append(page.Items, Conversation{
+			ID:            row.ID,
+			CreatedAt:     row.CreatedAt,
+			UpdatedAt:     row.UpdatedAt,
+			IsGroup:       row.IsGroup,
+			LastMessageAt: row.LastMessageAt,
+			UnreadCount:   row.UnreadCount,
+		})
+	}
+	if len(rows) > 0 {
+		last := rows[len(rows)-1]
+		page.NextCursor = nextCursor(len(rows), limit, pageCursor{CreatedAt: last.LastMessageAt, ID: last.ID})
+	}
+	dat, err := json.Marshal(page)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerConversationsGet(w http.ResponseWriter, r *http.Request) {
+	conversation, _, ok := cfg.conversationMember(w, r, cfg.authenticate)
+	if !ok {
+		return
+	}
+	cfg.writeConversation(w, r, 200, conversation)
+}
+
+func (cfg *apiConfig) handlerMessagesList(w http.ResponseWriter, r *http.Request) {
+	conversation, member, ok := cfg.conversationMember(w, r, cfg.authenticate)
+	if !ok {
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	messages, err := cfg.queries.ListMessages(r.Context(), database.ListMessagesParams{
+		ConversationID: conversation.ID,
+		JoinedAt:       member.JoinedAt,
+		BeforeTime:     cursor.CreatedAt,
+		BeforeID:       cursor.ID,
+		PageSize:       limit,
+	})
+	if err != nil {
+		log.Printf("Error listing messages: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list messages"))
+		return
+	}
+	page := Page[Message]{Items: []Message{}}
+	for _, message := range messages {
+		page.Items = append(page.Items, formatMessage(message))
+	}
+	if len(messages) > 0 {
+		last := messages[len(messages)-1]
+		page.NextCursor = nextCursor(len(messages), limit, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	dat, err := json.Marshal(page)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerMessagesCreate(w http.ResponseWriter, r *http.Request) {
+	conversation, member, ok := cfg.conversationMember(w, r, cfg.authenticateWriter)
+	if !ok {
+		return
+	}
+	type parameters struct {
+		Body string `json:"body"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error decoding parameters"))
+		return
+	}
+	if len(params.Body) == 0 {
+		w.WriteHeader(400)
+		w.Write([]byte("Message has nothing in the body"))
+		return
+	}
+	if len(params.Body) > maxMessageLength {
+		w.WriteHeader(400)
+		w.Write([]byte("Message is too long"))
+		return
+	}
+	members, err := cfg.queries.ListConversationMembers(r.Context(), conversation.ID)
+	if err != nil {
+		log.Printf("Error listing conversation members: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to send message"))
+		return
+	}
+	for _, other := range members {
+		if other.UserID == member.UserID || other.LeftAt.Valid {
+			continue
+		}
+		err = canMessage(r.Context(), cfg.queries, member.UserID, other.UserID)
+		if err != nil {
+			writeConversationError(w, err)
+			return
+		}
+	}
+	outcome, accepted, err := moderateMessage(r.Context(), cfg.messageModerator, member.UserID, params.Body)
+	if err != nil {
+		log.Printf("Error moderating message: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to moderate message"))
+		return
+	}
+	if !accepted {
+		writeRejectedMessage(w, outcome)
+		return
+	}
+	message, err := cfg.queries.CreateMessage(r.Context(), database.CreateMessageParams{
+		ConversationID: conversation.ID,
+		SenderID:       member.UserID,
+		Body:           outcome.Body,
+	})
+	if err != nil {
+		log.Printf("Error creating message: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to send message"))
+		return
+	}
+	err = cfg.queries.TouchConversation(r.Context(), conversation.ID)
+	if err != nil {
+		log.Printf("Error updating conversation: %s", err)
+	}
+	// Sending a message implies having read everything before it.
+	err = cfg.queries.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		ReadAt:         message.CreatedAt,
+		ConversationID: conversation.ID,
+		UserID:         member.UserID,
+	})
+	if err != nil {
+		log.Printf("Error marking conversation read: %s", err)
+	}
+	formatted := formatMessage(message)
+	for _, other := range members {
+		if other.LeftAt.Valid {
+			continue
+		}
+		cfg.deliver(r.Context(), other.UserID, gatewayEvent{Type: gatewayEventMessage, Data: formatted})
+	}
+	formatted.Moderation = &outcome
+	dat, err := json.Marshal(formatted)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(201)
+	w.Write(dat)
+}
+
+// moderateMessage runs a message through the moderators and reports
+// whether it may be sent. There is no review queue for private messages, so
+// anything the filters would hold is refused outright.
+func moderateMessage(ctx context.Context, moderators moderation.Chain, senderID uuid.UUID, body string) (moderation.Outcome, bool, error) {
+	outcome, err := moderators.Run(ctx, moderation.Submission{
+		AuthorID: senderID,
+		Body:     body,
+	})
+	if err != nil {
+		return moderation.Outcome{}, false, err
+	}
+	return outcome, outcome.Action != moderation.Reject && outcome.Action != moderation.Hold, nil
+}
+
+func writeRejectedMessage(w http.ResponseWriter, outcome moderation.Outcome) {
+	resp := struct {
+		Error      string             `json:"error"`
+		Moderation moderation.Outcome `json:"moderation"`
+	}{
+		Error:      "Message was rejected",
+		Moderation: outcome,
+	}
+	dat, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(400)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerConversationsMarkRead(w http.ResponseWriter, r *http.Request) {
+	conversation, member, ok := cfg.conversationMember(w, r, cfg.authenticate)
+	if !ok {
+		return
+	}
+	// Without a message_id everything up to now is marked read.
+	type parameters struct {
+		MessageID *uuid.UUID `json:"message_id"`
+	}
+	params := parameters{}
+	if r.ContentLength != 0 {
+		err := json.NewDecoder(r.Body).Decode(&params)
+		if err != nil {
+			w.WriteHeader(400)
+			w.Write([]byte("Error decoding parameters"))
+			return
+		}
+	}
+	readAt := time.Now().UTC()
+	if params.MessageID != nil {
+		message, err := cfg.queries.GetMessage(r.Context(), *params.MessageID)
+		if err != nil || message.ConversationID != conversation.ID {
+			w.WriteHeader(404)
+			w.Write([]byte("Message does not exist"))
+			return
+		}
+		readAt = message.CreatedAt
+	}
+	err := cfg.queries.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		ReadAt:         readAt,
+		ConversationID: conversation.ID,
+		UserID:         member.UserID,
+	})
+	if err != nil {
+		log.Printf("Error marking conversation read: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to mark conversation read"))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerConversationsLeave(w http.ResponseWriter, r *http.Request) {
+	conversation, member, ok := cfg.conversationMember(w, r, cfg.authenticate)
+	if !ok {
+		return
+	}
+	_, err := cfg.queries.LeaveConversation(r.Context(), database.LeaveConversationParams{
+		ConversationID: conversation.ID,
+		UserID:         member.UserID,
+	})
+	if err != nil {
+		log.Printf("Error leaving conversation: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to leave conversation"))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerUsersDMSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	type parameters struct {
+		DMsFollowersOnly bool `json:"dms_followers_only"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error decoding parameters"))
+		return
+	}
+	user, err := cfg.queries.UpdateUserDMSettings(r.Context(), database.UpdateUserDMSettingsParams{
+		DmsFollowersOnly: params.DMsFollowersOnly,
+		ID:               userID,
+	})
+	if err != nil {
+		log.Printf("Error updating DM settings: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to update account"))
+		return
+	}
+	resp := formatUser(user)
+	err = cfg.followCounts(r.Context(), &resp)
+	if err != nil {
+		log.Printf("Error counting follows: %s", err)
+	}
+	dat, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/David-Bosnic/chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+func TestFormatConversation(t *testing.T) {
+	readAt := time.Now().UTC()
+	conversation := database.Conversation{ID: uuid.New(), IsGroup: true}
+	members := []database.ConversationMember{
+		{ConversationID: conversation.ID, UserID: uuid.New(), LastReadAt: sql.NullTime{Time: readAt, Valid: true}},
+		{ConversationID: conversation.ID, UserID: uuid.New()},
+	}
+
+	formatted := formatConversation(conversation, members)
+	if len(formatted.Members) != 2 {
+		t.Fatalf("Got %d members, Expected 2", len(formatted.Members))
+	}
+	if formatted.Members[0].LastReadAt == nil || !formatted.Members[0].LastReadAt.Equal(readAt) {
+		t.Errorf("Expected read receipt %v, got %v", readAt, formatted.Members[0].LastReadAt)
+	}
+	if formatted.Members[1].LastReadAt != nil || formatted.Members[1].LeftAt != nil {
+		t.Errorf("Expected member without read receipt, got %+v", formatted.Members[1])
+	}
+}
+
+// messagingStore answers canMessage's queries from memory. blocks and
+// follows hold directed pairs: blocker and blocked, follower and followee.
+type messagingStore struct {
+	users   map[uuid.UUID]database.User
+	blocks  map[[2]uuid.UUID]bool
+	follows map[[2]uuid.UUID]bool
+}
+
+func (s *messagingStore) IsBlockedEitherWay(ctx context.Context, arg database.IsBlockedEitherWayParams) (bool, error) {
+	return s.blocks[[2]uuid.UUID{arg.BlockerID, arg.BlockedID}] || s.blocks[[2]uuid.UUID{arg.BlockedID, arg.BlockerID}], nil
+}
+
+func (s *messagingStore) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, ok := s.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (s *messagingStore) IsFollowing(ctx context.Context, arg database.IsFollowingParams) (bool, error) {
+	return s.follows[[2]uuid.UUID{arg.FollowerID, arg.FolloweeID}], nil
+}
+
+func TestCanMessage(t *testing.T) {
+	sender := uuid.New()
+	open := uuid.New()
+	blockedBySender := uuid.New()
+	blockingSender := uuid.New()
+	followersOnly := uuid.New()
+	followedFollowersOnly := uuid.New()
+	followsSender := uuid.New()
+	store := &messagingStore{
+		users: map[uuid.UUID]database.User{
+			sender:                {ID: sender},
+			open:                  {ID: open},
+			blockedBySender:       {ID: blockedBySender},
+			blockingSender:        {ID: blockingSender},
+			followersOnly:         {ID: followersOnly, DmsFollowersOnly: true},
+			followedFollowersOnly: {ID: followedFollowersOnly, DmsFollowersOnly: true},
+			followsSender:         {ID: followsSender, DmsFollowersOnly: true},
+		},
+		blocks: map[[2]uuid.UUID]bool{
+			{sender, blockedBySender}: true,
+			{blockingSender, sender}:  true,
+		},
+		follows: map[[2]uuid.UUID]bool{
+			{sender, followedFollowersOnly}: true,
+			// Being followed by the recipient does not count.
+			{followsSender, sender}: true,
+		},
+	}
+	cases := []struct {
+		name      string
+		recipient uuid.UUID
+		expected  error
+	}{
+		{"open", open, nil},
+		{"blocked by the sender", blockedBySender, errConversationBlocked},
+		{"blocking the sender", blockingSender, errConversationBlocked},
+		{"followers only", followersOnly, errConversationFollowersOnly},
+		{"followers only, followed", followedFollowersOnly, nil},
+		{"followers only, following the sender", followsSender, errConversationFollowersOnly},
+	}
+	for _, c := range cases {
+		err := canMessage(context.Background(), store, sender, c.recipient)
+		if !errors.Is(err, c.expected) {
+			t.Errorf("%s: Got: %v, Expected: %v", c.name, err, c.expected)
+		}
+	}
+}
+
+func TestModerateMessage(t *testing.T) {
+	verdict := func(action moderation.Action) moderation.Moderator {
+		return moderation.ModeratorFunc(func(ctx context.Context, sub moderation.Submission) (moderation.Verdict, error) {
+			return moderation.Verdict{Action: action, Body: "****", Reason: action.String()}, nil
+		})
+	}
+	cases := []struct {
+		action   moderation.Action
+		accepted bool
+	}{
+		{moderation.Allow, true},
+		{moderation.Mask, true},
+		{moderation.Hold, false},
+		{moderation.Reject, false},
+	}
+	for _, c := range cases {
+		outcome, accepted, err := moderateMessage(context.Background(), moderation.Chain{verdict(c.action)}, uuid.New(), "hello")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accepted != c.accepted || outcome.Action != c.action {
+			t.Errorf("%s: Got: %s accepted %v, Expected accepted %v", c.action, outcome.Action, accepted, c.accepted)
+		}
+	}
+
+	failing := moderation.ModeratorFunc(func(ctx context.Context, sub moderation.Submission) (moderation.Verdict, error) {
+		return moderation.Verdict{}, errors.New("classifier is down")
+	})
+	if _, accepted, err := moderateMessage(context.Background(), moderation.Chain{failing}, uuid.New(), "hello"); err == nil || accepted {
+		t.Error("Expected a moderator error not to let the message through")
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// eventBusChannel is the Postgres channel every instance listens on.
+const eventBusChannel = "chirpy_events"
+
+// Events carried between instances. Likes need no event of their own: the
+// only instance state they touch is the author's connections, which the
+// like notification already reaches through eventGatewayDelivery.
+const (
+	eventChirpCreated    = "chirp.created"
+	eventFollowCreated   = "follow.created"
+	eventGatewayDelivery = "gateway.delivery"
+	eventMetricsHits     = "metrics.hits"
+	eventMetricsReset    = "metrics.reset"
+)
+
+// metricsFlushInterval is how often fileserver hits are shared with the
+// other instances. Hits are batched rather than sent one NOTIFY each.
+const metricsFlushInterval = 5 * time.Second
+
+type followEvent struct {
+	FollowerID uuid.UUID `json:"follower_id"`
+	FolloweeID uuid.UUID `json:"followee_id"`
+}
+
+type gatewayDelivery struct {
+	UserID uuid.UUID    `json:"user_id"`
+	Event  gatewayEvent `json:"event"`
+}
+
+type metricsHits struct {
+	Count int32 `json:"count"`
+}
+
+// publish sends an event to every instance, this one included. Failing to
+// reach the others is logged rather than failing the request.
+func (cfg *apiConfig) publish(ctx context.Context, eventType string, data any) {
+	err := cfg.bus.Publish(ctx, eventType, data)
+	if err != nil {
+		log.Printf("Error publishing %s event: %s", eventType, err)
+	}
+}
+
+// deliver sends a gateway event to the user's connections on every
+// instance.
+func (cfg *apiConfig) deliver(ctx context.Context, userID uuid.UUID, event gatewayEvent) {
+	cfg.publish(ctx, eventGatewayDelivery, gatewayDelivery{UserID: userID, Event: event})
+}
+
+// subscribeEvents keeps this instance's in-memory state in step with
+// events from every instance.
+func (cfg *apiConfig) subscribeEvents() {
+	cfg.bus.Subscribe(eventChirpCreated, func(ctx context.Context, data json.RawMessage) {
+		chirp := database.Chirp{}
+		if decodeEvent(eventChirpCreated, data, &chirp) {
+			cfg.chirpStream.Publish(chirp)
+		}
+	})
+	cfg.bus.Subscribe(eventFollowCreated, func(ctx context.Context, data json.RawMessage) {
+		follow := followEvent{}
+		if decodeEvent(eventFollowCreated, data, &follow) {
+			cfg.suggestions.Invalidate(follow.FollowerID)
+		}
+	})
+	cfg.bus.Subscribe(eventGatewayDelivery, func(ctx context.Context, data json.RawMessage) {
+		delivery := struct {
+			UserID uuid.UUID `json:"user_id"`
+			Event  struct {
+				Type string          `json:"type"`
+				Data json.RawMessage `json:"data"`
+			} `json:"event"`
+		}{}
+		if decodeEvent(eventGatewayDelivery, data, &delivery) {
+			cfg.gateway.deliver(delivery.UserID, gatewayEvent{Type: delivery.Event.Type, Data: delivery.Event.Data})
+		}
+	})
+	cfg.bus.Subscribe(eventMetricsHits, func(ctx context.Context, data json.RawMessage) {
+		hits := metricsHits{}
+		if decodeEvent(eventMetricsHits, data, &hits) {
+			cfg.fileserverHit.Add(hits.Count)
+		}
+	})
+	cfg.bus.Subscribe(eventMetricsReset, func(ctx context.Context, data json.RawMessage) {
+		cfg.fileserverHit.Store(0)
+		cfg.fileserverHitUnsent.Store(0)
+	})
+}
+
+func decodeEvent(eventType string, data json.RawMessage, v any) bool {
+	err := json.Unmarshal(data, v)
+	if err != nil {
+		log.Printf("Error decoding %s event: %s", eventType, err)
+		return false
+	}
+	return true
+}
+
+// flushMetrics shares this instance's new fileserver hits with the others
+// every metricsFlushInterval until ctx is done.
+func (cfg *apiConfig) flushMetrics(ctx context.Context) {
+	ticker := time.NewTicker(metricsFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		count := cfg.fileserverHitUnsent.Swap(0)
+		if count == 0 {
+			continue
+		}
+		err := cfg.bus.Broadcast(ctx, eventMetricsHits, metricsHits{Count: count})
+		if err != nil {
+			log.Printf("Error sharing fileserver hits: %s", err)
+			cfg.fileserverHitUnsent.Add(count)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerUsersProtect(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	type parameters struct {
+		IsProtected bool `json:"is_protected"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error decoding parameters"))
+		return
+	}
+	user, err := cfg.queries.UpdateUserProtected(r.Context(), database.UpdateUserProtectedParams{
+		IsProtected: params.IsProtected,
+		ID:          userID,
+	})
+	if err != nil {
+		log.Printf("Error updating account protection: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to update account"))
+		return
+	}
+	// Unprotecting an account lets everyone waiting in its queue through.
+	if !user.IsProtected {
+		followerIDs, err := cfg.queries.ApproveAllFollowRequests(r.Context(), userID)
+		if err != nil {
+			log.Printf("Error approving follow requests: %s", err)
+			w.WriteHeader(500)
+			w.Write([]byte("Failed to update account"))
+			return
+		}
+		for _, followerID := range followerIDs {
+			cfg.backfillTimeline(r.Context(), followerID, userID)
+			cfg.unnotify(r.Context(), userID, followerID, notificationFollowRequest, uuid.NullUUID{})
+		}
+	}
+	resp := formatUser(user)
+	err = cfg.followCounts(r.Context(), &resp)
+	if err != nil {
+		log.Printf("Error counting follows: %s", err)
+	}
+	dat, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerFollowRequestsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	requests, err := cfg.queries.ListFollowRequests(r.Context(), database.ListFollowRequestsParams{
+		TargetID:   userID,
+		BeforeTime: cursor.CreatedAt,
+		BeforeID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		log.Printf("Error listing follow requests: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list follow requests"))
+		return
+	}
+	page := Page[Relationship]{Items: []Relationship{}}
+	for _, request := range requests {
+		page.Items = append(page.Items, Relationship{
+			UserID:    request.RequesterID,
+			CreatedAt: request.CreatedAt,
+		})
+	}
+	if len(requests) > 0 {
+		last := requests[len(requests)-1]
+		page.NextCursor = nextCursor(len(requests), limit, pageCursor{CreatedAt: last.CreatedAt, ID: last.RequesterID})
+	}
+	dat, err := json.Marshal(page)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerFollowRequestsApprove(w http.ResponseWriter, r *http.Request) {
+	userID, requesterID, ok := cfg.takeFollowRequest(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.follow(r.Context(), requesterID, userID)
+	if err != nil {
+		log.Printf("Error creating follow: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to approve follow request"))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerFollowRequestsDeny(w http.ResponseWriter, r *http.Request) {
+	_, _, ok := cfg.takeFollowRequest(w, r)
+	if !ok {
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// takeFollowRequest authenticates the account owner and deletes the pending
+// request from the {id} user along with its notification, writing a 404 if
+// there is none.
+func (cfg *apiConfig) takeFollowRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	requesterID, ok := cfg.userIDFromPath(w, r)
+	if !ok {
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	deleted, err := cfg.queries.DeleteFollowRequest(r.Context(), database.DeleteFollowRequestParams{
+		RequesterID: requesterID,
+		TargetID:    userID,
+	})
+	if err != nil {
+		log.Printf("Error deleting follow request: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to update follow request"))
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	if deleted == 0 {
+		w.WriteHeader(404)
+		w.Write([]byte("Follow request does not exist"))
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	cfg.unnotify(r.Context(), userID, requesterID, notificationFollowRequest, uuid.NullUUID{})
+	return userID, requesterID, true
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// followCounts fills in the follower and following counts of a user
+// response.
+func (cfg *apiConfig) followCounts(ctx context.Context, user *User) error {
+	followers, err := cfg.queries.CountFollowers(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	following, err := cfg.queries.CountFollowing(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	user.FollowerCount = followers
+	user.FollowingCount = following
+	return nil
+}
+
+func (cfg *apiConfig) handlerFollowsCreate(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationshipTarget(w, r)
+	if !ok {
+		return
+	}
+	blocked, err := cfg.queries.IsBlockedEitherWay(r.Context(), database.IsBlockedEitherWayParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+	})
+	if err != nil {
+		log.Printf("Error checking blocks: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to follow user"))
+		return
+	}
+	if blocked {
+		w.WriteHeader(403)
+		w.Write([]byte("Cannot follow this user"))
+		return
+	}
+	target, err := cfg.queries.GetUserByID(r.Context(), targetID)
+	if err != nil {
+		log.Printf("Error getting user: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to follow user"))
+		return
+	}
+	following, err := cfg.queries.IsFollowing(r.Context(), database.IsFollowingParams{
+		FollowerID: userID,
+		FolloweeID: targetID,
+	})
+	if err != nil {
+		log.Printf("Error checking follows: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to follow user"))
+		return
+	}
+	if target.IsProtected && !following {
+		err = cfg.queries.CreateFollowRequest(r.Context(), database.CreateFollowRequestParams{
+			RequesterID: userID,
+			TargetID:    targetID,
+		})
+		if err != nil {
+			log.Printf("Error creating follow request: %s", err)
+			w.WriteHeader(500)
+			w.Write([]byte("Failed to follow user"))
+			return
+		}
+		cfg.notify(r.Context(), database.CreateNotificationParams{
+			UserID:  targetID,
+			ActorID: userID,
+			Type:    notificationFollowRequest,
+		})
+		w.WriteHeader(202)
+		return
+	}
+	err = cfg.follow(r.Context(), userID, targetID)
+	if err != nil {
+		log.Printf("Error creating follow: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to follow user"))
+		return
+	}
+	cfg.notify(r.Context(), database.CreateNotificationParams{
+		UserID:  targetID,
+		ActorID: userID,
+		Type:    notificationFollow,
+	})
+	w.WriteHeader(204)
+}
+
+// follow makes followerID follow followeeID and backfills the follower's
+// timeline with the followee's recent chirps.
+func (cfg *apiConfig) follow(ctx context.Context, followerID, followeeID uuid.UUID) error {
+	err := cfg.queries.CreateFollow(ctx, database.CreateFollowParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		return err
+	}
+	cfg.backfillTimeline(ctx, followerID, followeeID)
+	cfg.publish(ctx, eventFollowCreated, followEvent{FollowerID: followerID, FolloweeID: followeeID})
+	return nil
+}
+
+func (cfg *apiConfig) backfillTimeline(ctx context.Context, userID, authorID uuid.UUID) {
+	err := cfg.queries.BackfillTimeline(ctx, database.BackfillTimelineParams{
+		UserID:        userID,
+		AuthorID:      authorID,
+		BackfillLimit: timelineBackfillLimit,
+	})
+	if err != nil {
+		log.Printf("Error backfilling timeline: %s", err)
+	}
+}
+
+func (cfg *apiConfig) handlerFollowsDelete(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationshipTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.queries.DeleteFollow(r.Context(), database.DeleteFollowParams{
+		FollowerID: userID,
+		FolloweeID: targetID,
+	})
+	if err != nil {
+		log.Printf("Error deleting follow: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to unfollow user"))
+		return
+	}
+	_, err = cfg.queries.DeleteFollowRequest(r.Context(), database.DeleteFollowRequestParams{
+		RequesterID: userID,
+		TargetID:    targetID,
+	})
+	if err != nil {
+		log.Printf("Error cancelling follow request: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to unfollow user"))
+		return
+	}
+	cfg.unnotify(r.Context(), targetID, userID, notificationFollow, uuid.NullUUID{})
+	cfg.unnotify(r.Context(), targetID, userID, notificationFollowRequest, uuid.NullUUID{})
+	err = cfg.queries.DeleteTimelineEntriesFromAuthor(r.Context(), database.DeleteTimelineEntriesFromAuthorParams{
+		UserID:   userID,
+		AuthorID: targetID,
+	})
+	if err != nil {
+		log.Printf("Error removing timeline entries after unfollow: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to unfollow user"))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerFollowersList(w http.ResponseWriter, r *http.Request) {
+	userID, cursor, limit, ok := cfg.followListParams(w, r)
+	if !ok {
+		return
+	}
+	follows, err := cfg.queries.ListFollowers(r.Context(), database.ListFollowersParams{
+		FolloweeID: userID,
+		BeforeTime: cursor.CreatedAt,
+		BeforeID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		log.Printf("Error listing followers: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list followers"))
+		return
+	}
+	page := Page[Relationship]{Items: []Relationship{}}
+	for _, follow := range follows {
+		page.Items = append(page.Items, Relationship{
+			UserID:    follow.FollowerID,
+			CreatedAt: follow.CreatedAt,
+		})
+	}
+	if len(follows) > 0 {
+		last := follows[len(follows)-1]
+		page.NextCursor = nextCursor(len(follows), limit, pageCursor{CreatedAt: last.CreatedAt, ID: last.FollowerID})
+	}
+	dat, err := json.Marshal(page)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerFollowingList(w http.ResponseWriter, r *http.Request) {
+	userID, cursor, limit, ok := cfg.followListParams(w, r)
+	if !ok {
+		return
+	}
+	follows, err := cfg.queries.ListFollowing(r.Context(), database.ListFollowingParams{
+		FollowerID: userID,
+		BeforeTime: cursor.CreatedAt,
+		BeforeID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		log.Printf("Error listing following: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list following"))
+		return
+	}
+	page := Page[Relationship]{Items: []Relationship{}}
+	for _, follow := range follows {
+		page.Items = append(page.Items, Relationship{
+			UserID:    follow.FolloweeID,
+			CreatedAt: follow.CreatedAt,
+		})
+	}
+	if len(follows) > 0 {
+		last := follows[len(follows)-1]
+		page.NextCursor = nextCursor(len(follows), limit, pageCursor{CreatedAt: last.CreatedAt, ID: last.FolloweeID})
+	}
+	dat, err := json.Marshal(page)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+// followListParams parses the {id} path value and the page parameters shared
+// by the follower and following listings.
+func (cfg *apiConfig) followListParams(w http.ResponseWriter, r *http.Request) (uuid.UUID, pageCursor, int32, bool) {
+	userID, ok := cfg.userIDFromPath(w, r)
+	if !ok {
+		return uuid.UUID{}, pageCursor{}, 0, false
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte(err.Error()))
+		return uuid.UUID{}, pageCursor{}, 0, false
+	}
+	_, err = cfg.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("User does not exist"))
+		return uuid.UUID{}, pageCursor{}, 0, false
+	}
+	return userID, cursor, limit, true
+}
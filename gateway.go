@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/auth"
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/David-Bosnic/chirpy/internal/pubsub"
+	"github.com/David-Bosnic/chirpy/internal/websocket"
+	"github.com/google/uuid"
+)
+
+const (
+	// gatewaySendBuffer is how many events a connection may fall behind
+	// before it is closed with CloseTryAgainLater.
+	gatewaySendBuffer   = 64
+	gatewayPingInterval = 30 * time.Second
+	gatewayPongWait     = 60 * time.Second
+	gatewayWriteTimeout = 10 * time.Second
+	gatewayReadLimit    = 4096
+	// gatewayReauthWarning is how long before the access token expires the
+	// client is asked for a fresh one.
+	gatewayReauthWarning = time.Minute
+	// gatewayTypingInterval rate-limits typing indicators per conversation.
+	gatewayTypingInterval = 3 * time.Second
+	// gatewayAuthTimeout is how long a connection opened without an
+	// Authorization header has to send its token.
+	gatewayAuthTimeout = 10 * time.Second
+
+	// closeTokenExpired is sent when the client did not re-authenticate in
+	// time. Codes 4000-4999 are for applications.
+	closeTokenExpired = 4001
+)
+
+// Events sent to clients.
+const (
+	gatewayEventReady        = "ready"
+	gatewayEventAuthRequired = "auth_required"
+	gatewayEventNotification = "notification"
+	gatewayEventMessage      = "message"
+	gatewayEventTyping       = "typing"
+	gatewayEventError        = "error"
+)
+
+// Requests clients send.
+const (
+	gatewayRequestAuth   = "auth"
+	gatewayRequestTyping = "typing"
+)
+
+type gatewayEvent struct {
+	Type string `json:"type"`
+	Data any    `json:"data,omitempty"`
+}
+
+// gatewayRequest is a message from the client: a fresh access token, or a
+// typing indicator for a conversation.
+type gatewayRequest struct {
+	Type           string    `json:"type"`
+	Token          string    `json:"token,omitempty"`
+	ConversationID uuid.UUID `json:"conversation_id,omitempty"`
+}
+
+type gatewaySessionInfo struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type gatewayTyping struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+}
+
+// gateway routes events to every connection a user has open.
+type gateway struct {
+	mu   sync.Mutex
+	hubs map[uuid.UUID]*pubsub.Hub[gatewayEvent]
+
+	// ctx is cancelled by shutdown, which closes every session.
+	ctx      context.Context
+	cancel   context.CancelFunc
+	sessions sync.WaitGroup
+}
+
+func newGateway() *gateway {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &gateway{
+		hubs:   map[uuid.UUID]*pubsub.Hub[gatewayEvent]{},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// serve runs a session until the connection ends or the gateway shuts
+// down. Sessions outlive the request that opened them, so they run on the
+// gateway's context rather than the request's.
+func (g *gateway) serve(s *gatewaySession) {
+	g.mu.Lock()
+	if g.ctx.Err() != nil {
+		g.mu.Unlock()
+		s.conn.WriteClose(websocket.CloseGoingAway, "server shutting down")
+		s.conn.Close()
+		return
+	}
+	g.sessions.Add(1)
+	g.mu.Unlock()
+	defer g.sessions.Done()
+	s.run(g.ctx)
+}
+
+// shutdown closes every session with CloseGoingAway and waits for them to
+// finish. http.Server.Shutdown does not track hijacked connections, so it
+// never closes these.
+func (g *gateway) shutdown() {
+	g.mu.Lock()
+	g.cancel()
+	g.mu.Unlock()
+	g.sessions.Wait()
+}
+
+func (g *gateway) subscribe(userID uuid.UUID) *pubsub.Subscription[gatewayEvent] {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	hub, ok := g.hubs[userID]
+	if !ok {
+		hub = pubsub.NewHub[gatewayEvent]()
+		g.hubs[userID] = hub
+	}
+	return hub.Subscribe(gatewaySendBuffer)
+}
+
+func (g *gateway) unsubscribe(userID uuid.UUID, sub *pubsub.Subscription[gatewayEvent]) {
+	sub.Close()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if hub, ok := g.hubs[userID]; ok && hub.Len() == 0 {
+		delete(g.hubs, userID)
+	}
+}
+
+// deliver sends the event to the user's open connections, if any. It never
+// blocks; connections too far behind are dropped.
+func (g *gateway) deliver(userID uuid.UUID, event gatewayEvent) {
+	g.mu.Lock()
+	hub := g.hubs[userID]
+	g.mu.Unlock()
+	if hub != nil {
+		hub.Publish(event)
+	}
+}
+
+type gatewayAuthFunc func(ctx context.Context, token string) (uuid.UUID, time.Time, error)
+
+type gatewayHandleFunc func(ctx context.Context, userID uuid.UUID, req gatewayRequest) error
+
+// gatewaySession serves one connection. The reader handles client
+// requests; the writer alone sends events, pings and the re-authentication
+// prompts, and closes the connection once the token lapses.
+type gatewaySession struct {
+	conn         *websocket.Conn
+	gateway      *gateway
+	userID       uuid.UUID
+	expiresAt    time.Time
+	authenticate gatewayAuthFunc
+	handle       gatewayHandleFunc
+
+	pingInterval  time.Duration
+	pongWait      time.Duration
+	reauthWarning time.Duration
+
+	// direct carries replies from the reader to the writer.
+	direct   chan gatewayEvent
+	reauthed chan time.Time
+	// typingSentAt is only touched by the reader.
+	typingSentAt map[uuid.UUID]time.Time
+}
+
+func newGatewaySession(conn *websocket.Conn, g *gateway, userID uuid.UUID, expiresAt time.Time, authenticate gatewayAuthFunc, handle gatewayHandleFunc) *gatewaySession {
+	return &gatewaySession{
+		conn:          conn,
+		gateway:       g,
+		userID:        userID,
+		expiresAt:     expiresAt,
+		authenticate:  authenticate,
+		handle:        handle,
+		pingInterval:  gatewayPingInterval,
+		pongWait:      gatewayPongWait,
+		reauthWarning: gatewayReauthWarning,
+		direct:        make(chan gatewayEvent, 8),
+		reauthed:      make(chan time.Time, 1),
+		typingSentAt:  map[uuid.UUID]time.Time{},
+	}
+}
+
+func (s *gatewaySession) run(ctx context.Context) {
+	defer s.conn.Close()
+	sub := s.gateway.subscribe(s.userID)
+	defer s.gateway.unsubscribe(s.userID, sub)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.conn.SetReadLimit(gatewayReadLimit)
+	s.conn.SetWriteTimeout(gatewayWriteTimeout)
+	readerDone := make(chan *websocket.CloseError, 1)
+	go func() {
+		readerDone <- s.read(ctx)
+	}()
+	s.write(ctx, sub, readerDone)
+}
+
+// reply queues an event for the writer, dropping it if the writer is that
+// far behind.
+func (s *gatewaySession) reply(event gatewayEvent) {
+	select {
+	case s.direct <- event:
+	default:
+	}
+}
+
+// read handles client requests until the connection fails. It returns the
+// close the writer should send, if any.
+func (s *gatewaySession) read(ctx context.Context) *websocket.CloseError {
+	s.conn.SetReadDeadline(time.Now().Add(s.pongWait))
+	s.conn.SetPongHandler(func(string) {
+		s.conn.SetReadDeadline(time.Now().Add(s.pongWait))
+	})
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return nil
+		}
+		req := gatewayRequest{}
+		err = json.Unmarshal(data, &req)
+		if err != nil {
+			s.reply(gatewayEvent{Type: gatewayEventError, Data: "Error decoding request"})
+			continue
+		}
+		switch req.Type {
+		case gatewayRequestAuth:
+			userID, expiresAt, err := s.authenticate(ctx, req.Token)
+			if err != nil || userID != s.userID {
+				return &websocket.CloseError{Code: websocket.ClosePolicyViolation, Text: "invalid token"}
+			}
+			select {
+			case s.reauthed <- expiresAt:
+			case <-ctx.Done():
+				return nil
+			}
+		case gatewayRequestTyping:
+			if time.Since(s.typingSentAt[req.ConversationID]) < gatewayTypingInterval {
+				continue
+			}
+			s.typingSentAt[req.ConversationID] = time.Now()
+			err = s.handle(ctx, s.userID, req)
+			if err != nil {
+				s.reply(gatewayEvent{Type: gatewayEventError, Data: err.Error()})
+			}
+		default:
+			s.reply(gatewayEvent{Type: gatewayEventError, Data: fmt.Sprintf("Unknown request type %q", req.Type)})
+		}
+	}
+}
+
+func (s *gatewaySession) write(ctx context.Context, sub *pubsub.Subscription[gatewayEvent], readerDone <-chan *websocket.CloseError) {
+	ping := time.NewTicker(s.pingInterval)
+	defer ping.Stop()
+	warn := time.NewTimer(time.Until(s.expiresAt.Add(-s.reauthWarning)))
+	defer warn.Stop()
+	expire := time.NewTimer(time.Until(s.expiresAt))
+	defer expire.Stop()
+
+	send := func(event gatewayEvent) error {
+		dat, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return s.conn.WriteMessage(websocket.TextMessage, dat)
+	}
+	err := send(gatewayEvent{Type: gatewayEventReady, Data: gatewaySessionInfo{UserID: s.userID, ExpiresAt: s.expiresAt}})
+	for err == nil {
+		select {
+		case <-ctx.Done():
+			s.conn.WriteClose(websocket.CloseGoingAway, "server shutting down")
+			return
+		case closeErr := <-readerDone:
+			if closeErr != nil {
+				s.conn.WriteClose(closeErr.Code, closeErr.Text)
+			}
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				s.conn.WriteClose(websocket.CloseTryAgainLater, "too far behind")
+				return
+			}
+			err = send(event)
+		case event := <-s.direct:
+			err = send(event)
+		case expiresAt := <-s.reauthed:
+			s.expiresAt = expiresAt
+			warn.Reset(time.Until(expiresAt.Add(-s.reauthWarning)))
+			expire.Reset(time.Until(expiresAt))
+			err = send(gatewayEvent{Type: gatewayEventReady, Data: gatewaySessionInfo{UserID: s.userID, ExpiresAt: expiresAt}})
+		case <-warn.C:
+			err = send(gatewayEvent{Type: gatewayEventAuthRequired, Data: gatewaySessionInfo{UserID: s.userID, ExpiresAt: s.expiresAt}})
+		case <-expire.C:
+			s.conn.WriteClose(closeTokenExpired, "token expired")
+			return
+		case <-ping.C:
+			err = s.conn.WriteMessage(websocket.PingMessage, nil)
+		}
+	}
+}
+
+// gatewayAuthenticate validates an access token for the gateway, refusing
+// suspended accounts like every other endpoint.
+func (cfg *apiConfig) gatewayAuthenticate(ctx context.Context, token string) (uuid.UUID, time.Time, error) {
+	user, expiresAt, err := cfg.userFromToken(ctx, token)
+	if err != nil {
+		return uuid.UUID{}, time.Time{}, err
+	}
+	if effectiveAccountStatus(user) == accountStatusSuspended {
+		return uuid.UUID{}, time.Time{}, errAccountSuspended
+	}
+	return user.ID, expiresAt, nil
+}
+
+// handleGatewayRequest passes a typing indicator on to the other members of
+// the conversation, except those on either side of a block.
+func (cfg *apiConfig) handleGatewayRequest(ctx context.Context, userID uuid.UUID, req gatewayRequest) error {
+	member, err := cfg.queries.GetConversationMember(ctx, database.GetConversationMemberParams{
+		ConversationID: req.ConversationID,
+		UserID:         userID,
+	})
+	if err != nil || member.LeftAt.Valid {
+		return errors.New("Conversation does not exist")
+	}
+	members, err := cfg.queries.ListConversationMembers(ctx, req.ConversationID)
+	if err != nil {
+		log.Printf("Error listing conversation members: %s", err)
+		return errors.New("Failed to send typing indicator")
+	}
+	for _, other := range members {
+		if other.UserID == userID || other.LeftAt.Valid {
+			continue
+		}
+		blocked, err := cfg.queries.IsBlockedEitherWay(ctx, database.IsBlockedEitherWayParams{
+			BlockerID: userID,
+			BlockedID: other.UserID,
+		})
+		if err != nil || blocked {
+			continue
+		}
+		cfg.deliver(ctx, other.UserID, gatewayEvent{
+			Type: gatewayEventTyping,
+			Data: gatewayTyping{ConversationID: req.ConversationID, UserID: userID},
+		})
+	}
+	return nil
+}
+
+// acceptGateway authenticates and upgrades a gateway connection.
+// Browsers cannot set headers on WebSocket requests, and tokens in URLs end
+// up in logs, so without an Authorization header the client must send an
+// auth request as its first message instead. On failure the request or
+// connection has already been answered.
+func acceptGateway(w http.ResponseWriter, r *http.Request, authenticate gatewayAuthFunc) (*websocket.Conn, uuid.UUID, time.Time, bool) {
+	if r.Header.Get("Authorization") != "" {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			writeAuthError(w, err)
+			return nil, uuid.UUID{}, time.Time{}, false
+		}
+		userID, expiresAt, err := authenticate(r.Context(), token)
+		if err != nil {
+			writeAuthError(w, err)
+			return nil, uuid.UUID{}, time.Time{}, false
+		}
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			log.Printf("Error upgrading gateway connection: %s", err)
+			return nil, uuid.UUID{}, time.Time{}, false
+		}
+		return conn, userID, expiresAt, true
+	}
+
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		log.Printf("Error upgrading gateway connection: %s", err)
+		return nil, uuid.UUID{}, time.Time{}, false
+	}
+	conn.SetReadLimit(gatewayReadLimit)
+	conn.SetWriteTimeout(gatewayWriteTimeout)
+	conn.SetReadDeadline(time.Now().Add(gatewayAuthTimeout))
+	userID, expiresAt, err := readGatewayAuth(r.Context(), conn, authenticate)
+	if err != nil {
+		log.Printf("Error failed to authenticate gateway connection: %s", err)
+		conn.WriteClose(websocket.ClosePolicyViolation, "authentication required")
+		conn.Close()
+		return nil, uuid.UUID{}, time.Time{}, false
+	}
+	return conn, userID, expiresAt, true
+}
+
+// readGatewayAuth reads the auth request a connection must open with.
+func readGatewayAuth(ctx context.Context, conn *websocket.Conn, authenticate gatewayAuthFunc) (uuid.UUID, time.Time, error) {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return uuid.UUID{}, time.Time{}, err
+	}
+	req := gatewayRequest{}
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		return uuid.UUID{}, time.Time{}, err
+	}
+	if req.Type != gatewayRequestAuth {
+		return uuid.UUID{}, time.Time{}, fmt.Errorf("expected an auth request, got %q", req.Type)
+	}
+	return authenticate(ctx, req.Token)
+}
+
+// handlerGateway upgrades to a WebSocket that delivers notifications, DM
+// messages and typing indicators.
+func (cfg *apiConfig) handlerGateway(w http.ResponseWriter, r *http.Request) {
+	conn, userID, expiresAt, ok := acceptGateway(w, r, cfg.gatewayAuthenticate)
+	if !ok {
+		return
+	}
+	cfg.gateway.serve(newGatewaySession(conn, cfg.gateway, userID, expiresAt, cfg.gatewayAuthenticate, cfg.handleGatewayRequest))
+}
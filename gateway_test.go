@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/auth"
+	"github.com/David-Bosnic/chirpy/internal/websocket"
+	"github.com/google/uuid"
+)
+
+const gatewayTestSecret = "gateway-test-secret"
+
+type receivedEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// gatewayTestServer serves sessions that authenticate with JWTs alone, so no
+// database is needed.
+func gatewayTestServer(t *testing.T, g *gateway, handle gatewayHandleFunc, configure func(*gatewaySession)) *httptest.Server {
+	t.Helper()
+	authenticate := func(ctx context.Context, token string) (uuid.UUID, time.Time, error) {
+		return auth.ValidateJWTWithExpiry(token, gatewayTestSecret)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, userID, expiresAt, ok := acceptGateway(w, r, authenticate)
+		if !ok {
+			return
+		}
+		session := newGatewaySession(conn, g, userID, expiresAt, authenticate, handle)
+		if configure != nil {
+			configure(session)
+		}
+		g.serve(session)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func gatewayToken(t *testing.T, userID uuid.UUID, expiresIn time.Duration) string {
+	t.Helper()
+	token, err := auth.MakeJWT(userID, gatewayTestSecret, expiresIn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func dialGateway(t *testing.T, server *httptest.Server, token string) *websocket.Conn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), header)
+	if err != nil {
+		t.Fatalf("Failed to dial gateway: %s", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) receivedEvent {
+	t.Helper()
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read event: %s", err)
+	}
+	event := receivedEvent{}
+	if err := json.Unmarshal(data, &event); err != nil {
+		t.Fatalf("Failed to decode event %s: %s", data, err)
+	}
+	return event
+}
+
+func sendRequest(t *testing.T, conn *websocket.Conn, req gatewayRequest) {
+	t.Helper()
+	dat, _ := json.Marshal(req)
+	if err := conn.WriteMessage(websocket.TextMessage, dat); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectClose(t *testing.T, conn *websocket.Conn, code int) {
+	t.Helper()
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != code {
+			t.Fatalf("Got: %v, Expected close code %d", err, code)
+		}
+		return
+	}
+}
+
+// waitForSubscribers waits until the gateway has n connections for the user.
+func waitForSubscribers(t *testing.T, g *gateway, userID uuid.UUID, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		g.mu.Lock()
+		hub := g.hubs[userID]
+		g.mu.Unlock()
+		if (hub == nil && n == 0) || (hub != nil && hub.Len() == n) {
+			return
+		}
+	}
+	t.Fatalf("Expected %d gateway connections for %s", n, userID)
+}
+
+func TestGatewayDelivers(t *testing.T) {
+	g := newGateway()
+	server := gatewayTestServer(t, g, nil, nil)
+	userID := uuid.New()
+	phone := dialGateway(t, server, gatewayToken(t, userID, time.Hour))
+	laptop := dialGateway(t, server, gatewayToken(t, userID, time.Hour))
+	for _, conn := range []*websocket.Conn{phone, laptop} {
+		if event := readEvent(t, conn); event.Type != gatewayEventReady {
+			t.Fatalf("Got: %s, Expected the ready event first", event.Type)
+		}
+	}
+	waitForSubscribers(t, g, userID, 2)
+
+	g.deliver(uuid.New(), gatewayEvent{Type: gatewayEventNotification, Data: "for someone else"})
+	g.deliver(userID, gatewayEvent{Type: gatewayEventMessage, Data: "hello"})
+	for _, conn := range []*websocket.Conn{phone, laptop} {
+		event := readEvent(t, conn)
+		if event.Type != gatewayEventMessage || string(event.Data) != `"hello"` {
+			t.Errorf("Got: %s %s, Expected the message on every connection", event.Type, event.Data)
+		}
+	}
+
+	phone.WriteClose(websocket.CloseNormal, "")
+	expectClose(t, phone, websocket.CloseNormal)
+	waitForSubscribers(t, g, userID, 1)
+}
+
+func TestGatewayTyping(t *testing.T) {
+	g := newGateway()
+	var calls atomic.Int32
+	handle := func(ctx context.Context, userID uuid.UUID, req gatewayRequest) error {
+		calls.Add(1)
+		return nil
+	}
+	server := gatewayTestServer(t, g, handle, nil)
+	conn := dialGateway(t, server, gatewayToken(t, uuid.New(), time.Hour))
+	readEvent(t, conn)
+
+	conversationID := uuid.New()
+	for i := 0; i < 3; i++ {
+		sendRequest(t, conn, gatewayRequest{Type: gatewayRequestTyping, ConversationID: conversationID})
+	}
+	sendRequest(t, conn, gatewayRequest{Type: "bogus"})
+	if event := readEvent(t, conn); event.Type != gatewayEventError {
+		t.Fatalf("Got: %s, Expected an error for the unknown request", event.Type)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Got %d typing indicators, Expected repeats to be rate-limited to 1", calls.Load())
+	}
+}
+
+func TestGatewayReauth(t *testing.T) {
+	g := newGateway()
+	server := gatewayTestServer(t, g, nil, func(s *gatewaySession) {
+		s.reauthWarning = 1500 * time.Millisecond
+	})
+	userID := uuid.New()
+
+	renewed := dialGateway(t, server, gatewayToken(t, userID, 2*time.Second))
+	readEvent(t, renewed)
+	if event := readEvent(t, renewed); event.Type != gatewayEventAuthRequired {
+		t.Fatalf("Got: %s, Expected a prompt to re-authenticate", event.Type)
+	}
+	sendRequest(t, renewed, gatewayRequest{Type: gatewayRequestAuth, Token: gatewayToken(t, userID, time.Hour)})
+	event := readEvent(t, renewed)
+	info := gatewaySessionInfo{}
+	json.Unmarshal(event.Data, &info)
+	if event.Type != gatewayEventReady || time.Until(info.ExpiresAt) < 30*time.Minute {
+		t.Fatalf("Got: %s %s, Expected the new expiry to be acknowledged", event.Type, event.Data)
+	}
+
+	lapsed := dialGateway(t, server, gatewayToken(t, userID, 2*time.Second))
+	expectClose(t, lapsed, closeTokenExpired)
+
+	// The renewed connection outlived the first token.
+	g.deliver(userID, gatewayEvent{Type: gatewayEventMessage})
+	if event := readEvent(t, renewed); event.Type != gatewayEventMessage {
+		t.Errorf("Got: %s, Expected the renewed connection to stay open", event.Type)
+	}
+
+	hijacked := dialGateway(t, server, gatewayToken(t, userID, time.Hour))
+	readEvent(t, hijacked)
+	sendRequest(t, hijacked, gatewayRequest{Type: gatewayRequestAuth, Token: gatewayToken(t, uuid.New(), time.Hour)})
+	expectClose(t, hijacked, websocket.ClosePolicyViolation)
+}
+
+func TestGatewayKeepalive(t *testing.T) {
+	g := newGateway()
+	server := gatewayTestServer(t, g, nil, func(s *gatewaySession) {
+		s.pingInterval = 20 * time.Millisecond
+		s.pongWait = 100 * time.Millisecond
+	})
+	userID := uuid.New()
+
+	// ReadMessage answers pings, so a reading client stays connected well
+	// past pongWait.
+	alive := dialGateway(t, server, gatewayToken(t, userID, time.Hour))
+	readEvent(t, alive)
+	waitForSubscribers(t, g, userID, 1)
+	time.AfterFunc(300*time.Millisecond, func() {
+		g.deliver(userID, gatewayEvent{Type: gatewayEventMessage})
+	})
+	if event := readEvent(t, alive); event.Type != gatewayEventMessage {
+		t.Errorf("Got: %s, Expected the connection to survive on pongs", event.Type)
+	}
+	alive.WriteClose(websocket.CloseNormal, "")
+	expectClose(t, alive, websocket.CloseNormal)
+	waitForSubscribers(t, g, userID, 0)
+
+	// A client that stops answering pings is dropped once pongWait passes.
+	dialGateway(t, server, gatewayToken(t, userID, time.Hour))
+	waitForSubscribers(t, g, userID, 1)
+	time.Sleep(300 * time.Millisecond)
+	waitForSubscribers(t, g, userID, 0)
+}
+
+func TestGatewayBackpressure(t *testing.T) {
+	g := newGateway()
+	userID := uuid.New()
+	sub := g.subscribe(userID)
+	for i := 0; i <= gatewaySendBuffer; i++ {
+		g.deliver(userID, gatewayEvent{Type: gatewayEventMessage})
+	}
+	received := 0
+	for range sub.C {
+		received++
+	}
+	if received != gatewaySendBuffer {
+		t.Errorf("Got %d events, Expected %d before the slow connection was dropped", received, gatewaySendBuffer)
+	}
+	g.unsubscribe(userID, sub)
+	if len(g.hubs) != 0 {
+		t.Errorf("Expected the user's hub to be removed with their last connection")
+	}
+}
+
+func TestGatewayAuthMessage(t *testing.T) {
+	g := newGateway()
+	server := gatewayTestServer(t, g, nil, nil)
+	userID := uuid.New()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	dial := func(rawURL string) *websocket.Conn {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, _, err := websocket.Dial(ctx, rawURL, nil)
+		if err != nil {
+			t.Fatalf("Failed to dial gateway: %s", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	conn := dial(wsURL)
+	sendRequest(t, conn, gatewayRequest{Type: gatewayRequestAuth, Token: gatewayToken(t, userID, time.Hour)})
+	event := readEvent(t, conn)
+	info := gatewaySessionInfo{}
+	json.Unmarshal(event.Data, &info)
+	if event.Type != gatewayEventReady || info.UserID != userID {
+		t.Fatalf("Got: %s %s, Expected the session to start once the token arrives", event.Type, event.Data)
+	}
+
+	// Tokens in the URL are ignored.
+	query := dial(wsURL + "?access_token=" + gatewayToken(t, userID, time.Hour))
+	sendRequest(t, query, gatewayRequest{Type: gatewayRequestTyping, ConversationID: uuid.New()})
+	expectClose(t, query, websocket.ClosePolicyViolation)
+
+	invalid := dial(wsURL)
+	sendRequest(t, invalid, gatewayRequest{Type: gatewayRequestAuth, Token: "not a token"})
+	expectClose(t, invalid, websocket.ClosePolicyViolation)
+}
+
+func TestGatewayShutdown(t *testing.T) {
+	g := newGateway()
+	server := gatewayTestServer(t, g, nil, nil)
+	userID := uuid.New()
+	conn := dialGateway(t, server, gatewayToken(t, userID, time.Hour))
+	readEvent(t, conn)
+	waitForSubscribers(t, g, userID, 1)
+
+	done := make(chan struct{})
+	go func() {
+		g.shutdown()
+		close(done)
+	}()
+	expectClose(t, conn, websocket.CloseGoingAway)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected shutdown to return once its sessions closed")
+	}
+	waitForSubscribers(t, g, userID, 0)
+
+	late := dialGateway(t, server, gatewayToken(t, userID, time.Hour))
+	expectClose(t, late, websocket.CloseGoingAway)
+}
+
+func TestHandlerGatewayRequiresToken(t *testing.T) {
+	cfg := &apiConfig{JWTSecret: gatewayTestSecret, gateway: newGateway()}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/ws", nil)
+	r.Header.Set("Authorization", "Bearer not-a-token")
+	cfg.handlerGateway(w, r)
+	if w.Code != 401 {
+		t.Errorf("Got: %d, Expected: 401", w.Code)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	// handleGracePeriod is how long a released handle keeps redirecting to
+	// its previous owner and stays unavailable to everyone else.
+	handleGracePeriod  = 30 * 24 * time.Hour
+	handleChangeLimit  = 2
+	handleChangeWindow = 30 * 24 * time.Hour
+)
+
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,15}$`)
+
+// reservedHandles can never be claimed, either because they name parts of
+// the site or because they would impersonate staff. Handles too short for
+// handlePattern, such as "me", need no entry.
+var reservedHandles = map[string]bool{
+	"about":     true,
+	"admin":     true,
+	"api":       true,
+	"chirpy":    true,
+	"help":      true,
+	"login":     true,
+	"logout":    true,
+	"moderator": true,
+	"mod":       true,
+	"null":      true,
+	"official":  true,
+	"root":      true,
+	"security":  true,
+	"settings":  true,
+	"signup":    true,
+	"staff":     true,
+	"support":   true,
+	"system":    true,
+	"undefined": true,
+}
+
+var (
+	errHandleInvalid     = errors.New("handle must be 3-15 letters, digits or underscores")
+	errHandleReserved    = errors.New("handle is reserved")
+	errHandleUnavailable = errors.New("handle is already taken")
+)
+
+func validateHandle(handle string) error {
+	if !handlePattern.MatchString(handle) {
+		return errHandleInvalid
+	}
+	if reservedHandles[strings.ToLower(handle)] {
+		return errHandleReserved
+	}
+	return nil
+}
+
+// defaultHandle generates a handle for users who sign up without one.
+func defaultHandle() string {
+	return "user_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:10]
+}
+
+// checkHandleAvailable reports errHandleUnavailable when another user holds
+// the handle or released it within the grace period. Users may take back
+// their own released handles.
+func (cfg *apiConfig) checkHandleAvailable(r *http.Request, handle string, userID uuid.UUID) error {
+	owner, err := cfg.queries.GetUserByHandle(r.Context(), handle)
+	if err == nil && owner.ID != userID {
+		return errHandleUnavailable
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	previousOwnerID, err := cfg.queries.GetUserIDByReleasedHandle(r.Context(), database.GetUserIDByReleasedHandleParams{
+		Handle:        handle,
+		ReleasedAfter: time.Now().UTC().Add(-handleGracePeriod),
+	})
+	if err == nil && previousOwnerID != userID {
+		return errHandleUnavailable
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+// isHandleTaken reports whether err is a unique violation on the handle
+// index, which is how two users claiming the same handle at once ends for
+// the one that loses.
+func isHandleTaken(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" && pqErr.Constraint == "users_handle_idx"
+}
+
+// writeHandleError writes the response for a handle that failed validation
+// or availability checks.
+func writeHandleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errHandleInvalid), errors.Is(err, errHandleReserved):
+		w.WriteHeader(400)
+	case errors.Is(err, errHandleUnavailable):
+		w.WriteHeader(409)
+	default:
+		log.Printf("Error checking handle: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to check handle"))
+		return
+	}
+	w.Write([]byte(err.Error()))
+}
+
+// userIDFromPath resolves the {id} path value, which is either a user ID or
+// an @handle. A handle released within the grace period redirects to the
+// same route under its owner's current handle. It writes the error or
+// redirect itself and returns ok=false when the request should stop.
+func (cfg *apiConfig) userIDFromPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	value := r.PathValue("id")
+	handle, isHandle := strings.CutPrefix(value, "@")
+	if !isHandle {
+		userID, err := uuid.Parse(value)
+		if err != nil {
+			w.WriteHeader(400)
+			w.Write([]byte("Error parsing userID"))
+			return uuid.UUID{}, false
+		}
+		return userID, true
+	}
+	user, err := cfg.queries.GetUserByHandle(r.Context(), handle)
+	if err == nil {
+		return user.ID, true
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error getting user by handle: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to find user"))
+		return uuid.UUID{}, false
+	}
+	previousOwnerID, err := cfg.queries.GetUserIDByReleasedHandle(r.Context(), database.GetUserIDByReleasedHandleParams{
+		Handle:        handle,
+		ReleasedAfter: time.Now().UTC().Add(-handleGracePeriod),
+	})
+	if err == nil {
+		user, err = cfg.queries.GetUserByID(r.Context(), previousOwnerID)
+	}
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("User does not exist"))
+		return uuid.UUID{}, false
+	}
+	target := *r.URL
+	target.Path = strings.Replace(r.URL.Path, "/"+value, "/@"+user.Handle, 1)
+	target.RawPath = ""
+	http.Redirect(w, r, target.String(), http.StatusTemporaryRedirect)
+	return uuid.UUID{}, false
+}
+
+func (cfg *apiConfig) handlerUsersUpdateHandle(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticateWriter(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	type parameters struct {
+		Handle string `json:"handle"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error decoding parameters"))
+		return
+	}
+	err = validateHandle(params.Handle)
+	if err != nil {
+		writeHandleError(w, err)
+		return
+	}
+	user, err := cfg.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error getting user: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to update handle"))
+		return
+	}
+	// Changing only the capitalisation keeps the same handle, so it is
+	// neither rate limited nor recorded in the history.
+	renamed := !strings.EqualFold(user.Handle, params.Handle)
+	if renamed {
+		changes, err := cfg.queries.CountHandleChangesSince(r.Context(), database.CountHandleChangesSinceParams{
+			UserID:     userID,
+			ReleasedAt: time.Now().UTC().Add(-handleChangeWindow),
+		})
+		if err != nil {
+			log.Printf("Error counting handle changes: %s", err)
+			w.WriteHeader(500)
+			w.Write([]byte("Failed to update handle"))
+			return
+		}
+		if changes >= handleChangeLimit {
+			w.WriteHeader(429)
+			w.Write([]byte("Too many handle changes, try again later"))
+			return
+		}
+		err = cfg.checkHandleAvailable(r, params.Handle, userID)
+		if err != nil {
+			writeHandleError(w, err)
+			return
+		}
+	}
+	// The history row both enforces the change limit and keeps the old
+	// handle reserved for the grace period, so it is written with the
+	// change or not at all.
+	var updated database.User
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		updated, err = q.UpdateUserHandle(r.Context(), database.UpdateUserHandleParams{
+			Handle: params.Handle,
+			ID:     userID,
+		})
+		if err != nil || !renamed {
+			return err
+		}
+		return q.CreateHandleHistory(r.Context(), database.CreateHandleHistoryParams{
+			UserID: userID,
+			Handle: user.Handle,
+		})
+	})
+	if isHandleTaken(err) {
+		writeHandleError(w, errHandleUnavailable)
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating handle: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to update handle"))
+		return
+	}
+	resp := formatUser(updated)
+	err = cfg.followCounts(r.Context(), &resp)
+	if err != nil {
+		log.Printf("Error counting follows: %s", err)
+	}
+	dat, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
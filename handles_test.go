@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestValidateHandle(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected error
+	}{
+		{input: "chirper_42", expected: nil},
+		{input: "ABC", expected: nil},
+		{input: "ab", expected: errHandleInvalid},
+		{input: "this_handle_is_too_long", expected: errHandleInvalid},
+		{input: "has space", expected: errHandleInvalid},
+		{input: "dash-ed", expected: errHandleInvalid},
+		{input: "Admin", expected: errHandleReserved},
+		{input: "me", expected: errHandleInvalid},
+		{input: "support", expected: errHandleReserved},
+	}
+	for i, c := range cases {
+		actual := validateHandle(c.input)
+		if !errors.Is(actual, c.expected) {
+			t.Errorf("Case %d: Got: %v, Expected: %v", i, actual, c.expected)
+		}
+	}
+}
+
+func TestIsHandleTaken(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected bool
+	}{
+		{err: &pq.Error{Code: "23505", Constraint: "users_handle_idx"}, expected: true},
+		{err: fmt.Errorf("updating handle: %w", &pq.Error{Code: "23505", Constraint: "users_handle_idx"}), expected: true},
+		{err: &pq.Error{Code: "23505", Constraint: "users_email_key"}, expected: false},
+		{err: &pq.Error{Code: "23502", Constraint: "users_handle_idx"}, expected: false},
+		{err: errors.New("connection refused"), expected: false},
+		{err: nil, expected: false},
+	}
+	for i, c := range cases {
+		if actual := isHandleTaken(c.err); actual != c.expected {
+			t.Errorf("Case %d: Got: %v, Expected: %v", i, actual, c.expected)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// visibleChirp fetches a published chirp the viewer is allowed to see,
+// writing a 404 otherwise.
+func (cfg *apiConfig) visibleChirp(w http.ResponseWriter, r *http.Request, viewerID, chirpID uuid.UUID) (database.Chirp, bool) {
+	viewer, err := cfg.newViewerFilter(r.Context(), viewerID)
+	if err != nil {
+		log.Printf("Error building viewer filter: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to get chirp"))
+		return database.Chirp{}, false
+	}
+	chirp, err := cfg.queries.GetChirp(r.Context(), chirpID)
+	if err != nil || chirp.Status != chirpStatusPublished || !viewer.canSee(chirp.UserID) {
+		w.WriteHeader(404)
+		w.Write([]byte("Chirp does not exist"))
+		return database.Chirp{}, false
+	}
+	return chirp, true
+}
+
+// interactionTarget authenticates a user allowed to write and resolves the
+// {chirpID} path value for likes and rechirps. Creating an interaction
+// needs the chirp to be visible; removing one only needs it to exist, so
+// users can undo interactions with authors who have since blocked them.
+func (cfg *apiConfig) interactionTarget(w http.ResponseWriter, r *http.Request, mustSee bool) (uuid.UUID, database.Chirp, bool) {
+	userID, err := cfg.authenticateWriter(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return uuid.UUID{}, database.Chirp{}, false
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing chirpID"))
+		return uuid.UUID{}, database.Chirp{}, false
+	}
+	if mustSee {
+		chirp, ok := cfg.visibleChirp(w, r, userID, chirpID)
+		return userID, chirp, ok
+	}
+	chirp, err := cfg.queries.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("Chirp does not exist"))
+		return uuid.UUID{}, database.Chirp{}, false
+	}
+	return userID, chirp, true
+}
+
+func (cfg *apiConfig) handlerLikesCreate(w http.ResponseWriter, r *http.Request) {
+	userID, chirp, ok := cfg.interactionTarget(w, r, true)
+	if !ok {
+		return
+	}
+	created, err := cfg.queries.CreateLike(r.Context(), database.CreateLikeParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		log.Printf("Error creating like: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to like chirp"))
+		return
+	}
+	if created > 0 {
+		cfg.notify(r.Context(), database.CreateNotificationParams{
+			UserID:  chirp.UserID,
+			ActorID: userID,
+			Type:    notificationLike,
+			ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		})
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerLikesDelete(w http.ResponseWriter, r *http.Request) {
+	userID, chirp, ok := cfg.interactionTarget(w, r, false)
+	if !ok {
+		return
+	}
+	err := cfg.queries.DeleteLike(r.Context(), database.DeleteLikeParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		log.Printf("Error deleting like: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to unlike chirp"))
+		return
+	}
+	cfg.unnotify(r.Context(), chirp.UserID, userID, notificationLike, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerRechirpsCreate(w http.ResponseWriter, r *http.Request) {
+	userID, chirp, ok := cfg.interactionTarget(w, r, true)
+	if !ok {
+		return
+	}
+	author, err := cfg.queries.GetUserByID(r.Context(), chirp.UserID)
+	if err != nil {
+		log.Printf("Error getting user: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to rechirp"))
+		return
+	}
+	// Followers may read a protected account, but not spread it further.
+	if author.IsProtected && author.ID != userID {
+		w.WriteHeader(403)
+		w.Write([]byte("Chirps from protected accounts cannot be rechirped"))
+		return
+	}
+	created, err := cfg.queries.CreateRechirp(r.Context(), database.CreateRechirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		log.Printf("Error creating rechirp: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to rechirp"))
+		return
+	}
+	if created > 0 {
+		cfg.notify(r.Context(), database.CreateNotificationParams{
+			UserID:  chirp.UserID,
+			ActorID: userID,
+			Type:    notificationRechirp,
+			ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		})
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerRechirpsDelete(w http.ResponseWriter, r *http.Request) {
+	userID, chirp, ok := cfg.interactionTarget(w, r, false)
+	if !ok {
+		return
+	}
+	err := cfg.queries.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		log.Printf("Error deleting rechirp: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to undo rechirp"))
+		return
+	}
+	cfg.unnotify(r.Context(), chirp.UserID, userID, notificationRechirp, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+	w.WriteHeader(204)
+}
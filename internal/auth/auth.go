@@ -23,14 +23,14 @@ func HashPassword(password string) (string, error) {
 func CheckPasswordHash(hash, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
-func MakeJWT(userID uuid.UUID, tokenSecret string) (string, error) {
+func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
 		Issuer: "chirpy",
 		IssuedAt: &jwt.NumericDate{
 			Time: time.Now(),
 		},
 		ExpiresAt: &jwt.NumericDate{
-			Time: time.Now().Add(time.Hour),
+			Time: time.Now().Add(expiresIn),
 		},
 		Subject: userID.String(),
 	})
@@ -41,21 +41,32 @@ func MakeJWT(userID uuid.UUID, tokenSecret string) (string, error) {
 	return signedJWT, nil
 }
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	userID, _, err := ValidateJWTWithExpiry(tokenString, tokenSecret)
+	return userID, err
+}
+
+// ValidateJWTWithExpiry is ValidateJWT that also returns when the token
+// expires, for connections that outlive a single request.
+func ValidateJWTWithExpiry(tokenString, tokenSecret string) (uuid.UUID, time.Time, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(t *jwt.Token) (interface{}, error) {
 		return []byte(tokenSecret), nil
 	})
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, time.Time{}, err
 	}
 	val, err := token.Claims.GetSubject()
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, time.Time{}, err
 	}
 	userID, err := uuid.Parse(val)
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, time.Time{}, err
 	}
-	return userID, err
+	expiresAt, err := token.Claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return uuid.UUID{}, time.Time{}, fmt.Errorf("Error JWT has no expiration time")
+	}
+	return userID, expiresAt.Time, nil
 }
 func GetBearerToken(headers http.Header) (string, error) {
 	token := headers.Get("Authorization")
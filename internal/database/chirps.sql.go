@@ -7,25 +7,33 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, status, reply_to_id)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, status, reply_to_id
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	Status    string
+	ReplyToID uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.Status,
+		arg.ReplyToID,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -33,6 +41,8 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Status,
+		&i.ReplyToID,
 	)
 	return i, err
 }
@@ -57,7 +67,7 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, status, reply_to_id FROM chirps
 WHERE id = $1
 `
 
@@ -70,12 +80,15 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Status,
+		&i.ReplyToID,
 	)
 	return i, err
 }
 
 const listChirps = `-- name: ListChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, status, reply_to_id FROM chirps
+WHERE status = 'published'
 ORDER BY created_at ASC
 `
 
@@ -94,6 +107,8 @@ func (q *Queries) ListChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.Status,
+			&i.ReplyToID,
 		); err != nil {
 			return nil, err
 		}
@@ -109,8 +124,8 @@ func (q *Queries) ListChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const listChirpsFromAuthorID = `-- name: ListChirpsFromAuthorID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE user_id = $1
+SELECT id, created_at, updated_at, body, user_id, status, reply_to_id FROM chirps
+WHERE user_id = $1 AND status = 'published'
 ORDER BY created_at ASC
 `
 
@@ -129,6 +144,124 @@ func (q *Queries) ListChirpsFromAuthorID(ctx context.Context, userID uuid.UUID)
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.Status,
+			&i.ReplyToID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPublicChirpsAfter = `-- name: ListPublicChirpsAfter :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.status, chirps.reply_to_id FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.status = 'published'
+    AND (chirps.created_at, chirps.id) > ($1::timestamp, $2::uuid)
+    AND chirps.created_at < $3::timestamp
+    AND NOT users.is_protected
+    AND NOT (
+        users.account_status = 'shadow_banned'
+        AND (users.account_status_until IS NULL OR users.account_status_until > NOW())
+    )
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $4
+`
+
+type ListPublicChirpsAfterParams struct {
+	AfterTime time.Time
+	AfterID   uuid.UUID
+	UntilTime time.Time
+	BatchSize int32
+}
+
+func (q *Queries) ListPublicChirpsAfter(ctx context.Context, arg ListPublicChirpsAfterParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listPublicChirpsAfter,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.UntilTime,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Status,
+			&i.ReplyToID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPublishedChirpsAfter = `-- name: ListPublishedChirpsAfter :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.status, chirps.reply_to_id FROM chirps
+WHERE chirps.status = 'published'
+    AND (chirps.created_at, chirps.id) > ($1::timestamp, $2::uuid)
+    AND chirps.created_at < $3::timestamp
+    AND NOT EXISTS (
+        SELECT 1 FROM users
+        WHERE users.id = chirps.user_id
+            AND users.account_status = 'shadow_banned'
+            AND (users.account_status_until IS NULL OR users.account_status_until > NOW())
+    )
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $4
+`
+
+type ListPublishedChirpsAfterParams struct {
+	AfterTime time.Time
+	AfterID   uuid.UUID
+	UntilTime time.Time
+	BatchSize int32
+}
+
+func (q *Queries) ListPublishedChirpsAfter(ctx context.Context, arg ListPublishedChirpsAfterParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listPublishedChirpsAfter,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.UntilTime,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Status,
+			&i.ReplyToID,
 		); err != nil {
 			return nil, err
 		}
@@ -142,3 +275,137 @@ func (q *Queries) ListChirpsFromAuthorID(ctx context.Context, userID uuid.UUID)
 	}
 	return items, nil
 }
+
+const listRecentChirpsFromAuthorID = `-- name: ListRecentChirpsFromAuthorID :many
+SELECT id, created_at, updated_at, body, user_id, status, reply_to_id FROM chirps
+WHERE user_id = $1 AND created_at > $2
+ORDER BY created_at DESC
+`
+
+type ListRecentChirpsFromAuthorIDParams struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) ListRecentChirpsFromAuthorID(ctx context.Context, arg ListRecentChirpsFromAuthorIDParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listRecentChirpsFromAuthorID, arg.UserID, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Status,
+			&i.ReplyToID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecentPublishedChirps = `-- name: ListRecentPublishedChirps :many
+SELECT id, created_at, updated_at, body, user_id, status, reply_to_id FROM chirps
+WHERE status = 'published' AND created_at > $1
+ORDER BY created_at DESC
+LIMIT $2
+`
+
+type ListRecentPublishedChirpsParams struct {
+	CreatedAt time.Time
+	Limit     int32
+}
+
+func (q *Queries) ListRecentPublishedChirps(ctx context.Context, arg ListRecentPublishedChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listRecentPublishedChirps, arg.CreatedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Status,
+			&i.ReplyToID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET
+    body = $1,
+    status = $2,
+    updated_at = NOW()
+WHERE id = $3
+RETURNING id, created_at, updated_at, body, user_id, status, reply_to_id
+`
+
+type UpdateChirpBodyParams struct {
+	Body   string
+	Status string
+	ID     uuid.UUID
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.Body, arg.Status, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Status,
+		&i.ReplyToID,
+	)
+	return i, err
+}
+
+const updateChirpStatus = `-- name: UpdateChirpStatus :exec
+UPDATE chirps
+SET
+    status = $1,
+    updated_at = NOW()
+WHERE id = $2
+`
+
+type UpdateChirpStatusParams struct {
+	Status string
+	ID     uuid.UUID
+}
+
+func (q *Queries) UpdateChirpStatus(ctx context.Context, arg UpdateChirpStatusParams) error {
+	_, err := q.db.ExecContext(ctx, updateChirpStatus, arg.Status, arg.ID)
+	return err
+}
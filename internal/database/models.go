@@ -11,12 +11,184 @@ import (
 	"github.com/google/uuid"
 )
 
+type Appeal struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	UserID       uuid.UUID
+	ActionID     uuid.UUID
+	Statement    string
+	Status       string
+	DecidedBy    uuid.NullUUID
+	DecidedAt    sql.NullTime
+	DecisionNote string
+}
+
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Body      string
 	UserID    uuid.UUID
+	Status    string
+	ReplyToID uuid.NullUUID
+}
+
+type Conversation struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	CreatorID     uuid.NullUUID
+	IsGroup       bool
+	LastMessageAt time.Time
+}
+
+type ConversationMember struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	JoinedAt       time.Time
+	LastReadAt     sql.NullTime
+	LeftAt         sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type FollowRequest struct {
+	RequesterID uuid.UUID
+	TargetID    uuid.UUID
+	CreatedAt   time.Time
+}
+
+type HandleHistory struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Handle     string
+	ReleasedAt time.Time
+}
+
+type Job struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Type        string
+	Payload     string
+	Priority    int32
+	Status      string
+	Attempts    int32
+	MaxAttempts int32
+	RunAt       time.Time
+	LockedUntil sql.NullTime
+	LastError   string
+	FinishedAt  sql.NullTime
+	UniqueKey   sql.NullString
+}
+
+type Like struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type List struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	OwnerID     uuid.UUID
+	Name        string
+	Description string
+	IsPrivate   bool
+}
+
+type ListMember struct {
+	ListID    uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ListSubscription struct {
+	ListID    uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type Message struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+}
+
+type ModerationAction struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	ModeratorID   uuid.NullUUID
+	Action        string
+	ReportID      uuid.NullUUID
+	TargetUserID  uuid.UUID
+	TargetChirpID uuid.NullUUID
+	Reason        string
+	AppealID      uuid.NullUUID
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type MutedWord struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Phrase    string
+	ExpiresAt sql.NullTime
+}
+
+type Notification struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UserID        uuid.UUID
+	ActorID       uuid.UUID
+	Type          string
+	ChirpID       uuid.NullUUID
+	SourceChirpID uuid.NullUUID
+	ReadAt        sql.NullTime
+}
+
+type NotificationPreference struct {
+	UserID  uuid.UUID
+	Type    string
+	Enabled bool
+}
+
+type Outbox struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	EventType   string
+	Payload     string
+	Attempts    int32
+	AvailableAt time.Time
+	LastError   string
+	PublishedAt sql.NullTime
+	MaxAttempts int32
+	DeadAt      sql.NullTime
+}
+
+type Rechirp struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -28,11 +200,86 @@ type RefreshToken struct {
 	UserID    uuid.UUID
 }
 
-type User struct {
+type Report struct {
 	ID             uuid.UUID
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
-	HashedPassword string
-	Email          string
-	IsChirpyRed    bool
+	ReporterID     uuid.NullUUID
+	ReportedUserID uuid.UUID
+	ChirpID        uuid.NullUUID
+	Category       string
+	Details        string
+	Status         string
+	ClaimedBy      uuid.NullUUID
+	ClaimedAt      sql.NullTime
+	Resolution     sql.NullString
+	ResolvedAt     sql.NullTime
+}
+
+type TimelineEntry struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	AuthorID  uuid.UUID
+	CreatedAt time.Time
+}
+
+type TimelineHeavyAuthor struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type User struct {
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	HashedPassword      string
+	Email               string
+	IsChirpyRed         bool
+	IsModerator         bool
+	AccountStatus       string
+	AccountStatusUntil  sql.NullTime
+	AccountStatusReason string
+	IsProtected         bool
+	DisplayName         string
+	Bio                 string
+	Location            string
+	Website             string
+	AvatarUrl           string
+	Handle              string
+	DmsFollowersOnly    bool
+}
+
+type Webhook struct {
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	UserID              uuid.UUID
+	Url                 string
+	Secret              string
+	Events              []string
+	ConsecutiveFailures int32
+	DisabledAt          sql.NullTime
+}
+
+type WebhookAttempt struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	DeliveryID uuid.UUID
+	StatusCode sql.NullInt32
+	Error      string
+	DurationMs int32
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	WebhookID      uuid.UUID
+	Event          string
+	Payload        string
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastStatusCode sql.NullInt32
+	LastError      string
+	DeliveredAt    sql.NullTime
 }
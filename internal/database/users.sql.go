@@ -7,22 +7,24 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3
 )
-RETURNING id, created_at, updated_at, email, is_chirpy_red
+RETURNING id, created_at, updated_at, email, is_chirpy_red, handle
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         string
 }
 
 type CreateUserRow struct {
@@ -31,10 +33,11 @@ type CreateUserRow struct {
 	UpdatedAt   time.Time
 	Email       string
 	IsChirpyRed bool
+	Handle      string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i CreateUserRow
 	err := row.Scan(
 		&i.ID,
@@ -42,6 +45,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Handle,
 	)
 	return i, err
 }
@@ -56,7 +60,7 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, hashed_password, email, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, hashed_password, email, is_chirpy_red, is_moderator, account_status, account_status_until, account_status_reason, is_protected, display_name, bio, location, website, avatar_url, handle, dms_followers_only FROM users
 WHERE email = $1
 `
 
@@ -70,12 +74,86 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.HashedPassword,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.IsModerator,
+		&i.AccountStatus,
+		&i.AccountStatusUntil,
+		&i.AccountStatusReason,
+		&i.IsProtected,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.DmsFollowersOnly,
+	)
+	return i, err
+}
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT id, created_at, updated_at, hashed_password, email, is_chirpy_red, is_moderator, account_status, account_status_until, account_status_reason, is_protected, display_name, bio, location, website, avatar_url, handle, dms_followers_only FROM users
+WHERE LOWER(handle) = LOWER($1)
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.IsModerator,
+		&i.AccountStatus,
+		&i.AccountStatusUntil,
+		&i.AccountStatusReason,
+		&i.IsProtected,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.DmsFollowersOnly,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, hashed_password, email, is_chirpy_red, is_moderator, account_status, account_status_until, account_status_reason, is_protected, display_name, bio, location, website, avatar_url, handle, dms_followers_only FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.IsModerator,
+		&i.AccountStatus,
+		&i.AccountStatusUntil,
+		&i.AccountStatusReason,
+		&i.IsProtected,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.DmsFollowersOnly,
 	)
 	return i, err
 }
 
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT id, created_at, updated_at, hashed_password, email, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, hashed_password, email, is_chirpy_red, is_moderator, account_status, account_status_until, account_status_reason, is_protected, display_name, bio, location, website, avatar_url, handle, dms_followers_only FROM users
 WHERE id = (
     SELECT user_id
     FROM refresh_tokens
@@ -93,10 +171,84 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Us
 		&i.HashedPassword,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.IsModerator,
+		&i.AccountStatus,
+		&i.AccountStatusUntil,
+		&i.AccountStatusReason,
+		&i.IsProtected,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.DmsFollowersOnly,
 	)
 	return i, err
 }
 
+const listProtectedUserIDsHiddenFrom = `-- name: ListProtectedUserIDsHiddenFrom :many
+SELECT users.id FROM users
+WHERE users.is_protected
+    AND users.id <> $1
+    AND NOT EXISTS (
+        SELECT 1 FROM follows
+        WHERE follows.follower_id = $1 AND follows.followee_id = users.id
+    )
+`
+
+func (q *Queries) ListProtectedUserIDsHiddenFrom(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listProtectedUserIDsHiddenFrom, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listShadowBannedUserIDs = `-- name: ListShadowBannedUserIDs :many
+SELECT id FROM users
+WHERE account_status = 'shadow_banned'
+    AND (account_status_until IS NULL OR account_status_until > NOW())
+`
+
+func (q *Queries) ListShadowBannedUserIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listShadowBannedUserIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateToChirpyRed = `-- name: UpdateToChirpyRed :exec
 UPDATE users
 SET
@@ -109,6 +261,73 @@ func (q *Queries) UpdateToChirpyRed(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const updateUserAccountStatus = `-- name: UpdateUserAccountStatus :exec
+UPDATE users
+SET
+    account_status = $1,
+    account_status_until = $2,
+    account_status_reason = $3,
+    updated_at = NOW()
+WHERE id = $4
+`
+
+type UpdateUserAccountStatusParams struct {
+	AccountStatus       string
+	AccountStatusUntil  sql.NullTime
+	AccountStatusReason string
+	ID                  uuid.UUID
+}
+
+func (q *Queries) UpdateUserAccountStatus(ctx context.Context, arg UpdateUserAccountStatusParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserAccountStatus,
+		arg.AccountStatus,
+		arg.AccountStatusUntil,
+		arg.AccountStatusReason,
+		arg.ID,
+	)
+	return err
+}
+
+const updateUserDMSettings = `-- name: UpdateUserDMSettings :one
+UPDATE users
+SET
+    dms_followers_only = $1,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING id, created_at, updated_at, hashed_password, email, is_chirpy_red, is_moderator, account_status, account_status_until, account_status_reason, is_protected, display_name, bio, location, website, avatar_url, handle, dms_followers_only
+`
+
+type UpdateUserDMSettingsParams struct {
+	DmsFollowersOnly bool
+	ID               uuid.UUID
+}
+
+func (q *Queries) UpdateUserDMSettings(ctx context.Context, arg UpdateUserDMSettingsParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserDMSettings, arg.DmsFollowersOnly, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.IsModerator,
+		&i.AccountStatus,
+		&i.AccountStatusUntil,
+		&i.AccountStatusReason,
+		&i.IsProtected,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.DmsFollowersOnly,
+	)
+	return i, err
+}
+
 const updateUserEmailAndPassword = `-- name: UpdateUserEmailAndPassword :exec
 UPDATE users
 SET 
@@ -127,3 +346,138 @@ func (q *Queries) UpdateUserEmailAndPassword(ctx context.Context, arg UpdateUser
 	_, err := q.db.ExecContext(ctx, updateUserEmailAndPassword, arg.HashedPassword, arg.Email, arg.ID)
 	return err
 }
+
+const updateUserHandle = `-- name: UpdateUserHandle :one
+UPDATE users
+SET
+    handle = $1,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING id, created_at, updated_at, hashed_password, email, is_chirpy_red, is_moderator, account_status, account_status_until, account_status_reason, is_protected, display_name, bio, location, website, avatar_url, handle, dms_followers_only
+`
+
+type UpdateUserHandleParams struct {
+	Handle string
+	ID     uuid.UUID
+}
+
+func (q *Queries) UpdateUserHandle(ctx context.Context, arg UpdateUserHandleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserHandle, arg.Handle, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.IsModerator,
+		&i.AccountStatus,
+		&i.AccountStatusUntil,
+		&i.AccountStatusReason,
+		&i.IsProtected,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.DmsFollowersOnly,
+	)
+	return i, err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET
+    display_name = $1,
+    bio = $2,
+    location = $3,
+    website = $4,
+    avatar_url = $5,
+    updated_at = NOW()
+WHERE id = $6
+RETURNING id, created_at, updated_at, hashed_password, email, is_chirpy_red, is_moderator, account_status, account_status_until, account_status_reason, is_protected, display_name, bio, location, website, avatar_url, handle, dms_followers_only
+`
+
+type UpdateUserProfileParams struct {
+	DisplayName string
+	Bio         string
+	Location    string
+	Website     string
+	AvatarUrl   string
+	ID          uuid.UUID
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.DisplayName,
+		arg.Bio,
+		arg.Location,
+		arg.Website,
+		arg.AvatarUrl,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.IsModerator,
+		&i.AccountStatus,
+		&i.AccountStatusUntil,
+		&i.AccountStatusReason,
+		&i.IsProtected,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.DmsFollowersOnly,
+	)
+	return i, err
+}
+
+const updateUserProtected = `-- name: UpdateUserProtected :one
+UPDATE users
+SET
+    is_protected = $1,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING id, created_at, updated_at, hashed_password, email, is_chirpy_red, is_moderator, account_status, account_status_until, account_status_reason, is_protected, display_name, bio, location, website, avatar_url, handle, dms_followers_only
+`
+
+type UpdateUserProtectedParams struct {
+	IsProtected bool
+	ID          uuid.UUID
+}
+
+func (q *Queries) UpdateUserProtected(ctx context.Context, arg UpdateUserProtectedParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProtected, arg.IsProtected, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.IsModerator,
+		&i.AccountStatus,
+		&i.AccountStatusUntil,
+		&i.AccountStatusReason,
+		&i.IsProtected,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.DmsFollowersOnly,
+	)
+	return i, err
+}
@@ -0,0 +1,186 @@
+// Package eventbus carries events between chirpy instances over Postgres
+// NOTIFY and LISTEN, so in-memory state on every instance can follow what
+// happened on the others.
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	// maxPayload is just under Postgres's 8000 byte limit on NOTIFY
+	// payloads.
+	maxPayload = 7999
+
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+	// pingInterval is how often an idle listener checks its connection.
+	// pq only notices a dead connection when it next reads from it.
+	pingInterval = 90 * time.Second
+)
+
+// Event is what travels over the channel. Origin identifies the instance
+// that published it.
+type Event struct {
+	Type   string          `json:"type"`
+	Origin string          `json:"origin"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// NotifyFunc sends a payload on a Postgres channel, usually through
+// pg_notify.
+type NotifyFunc func(ctx context.Context, channel, payload string) error
+
+type Handler func(ctx context.Context, data json.RawMessage)
+
+// Bus publishes events to every instance listening on the same channel,
+// itself included. It is safe for concurrent use.
+type Bus struct {
+	channel string
+	origin  string
+	notify  NotifyFunc
+
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func New(channel string, notify NotifyFunc) *Bus {
+	return &Bus{
+		channel:  channel,
+		origin:   uuid.NewString(),
+		notify:   notify,
+		handlers: map[string][]Handler{},
+	}
+}
+
+// Subscribe calls handler for every event of the type, wherever it was
+// published. Handlers run on the publishing goroutine for local events and
+// on the listener goroutine for remote ones, so they must not block.
+func (b *Bus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Publish delivers the event to this instance's handlers straight away and
+// sends it to the other instances. Local delivery happens even if sending
+// fails.
+func (b *Bus) Publish(ctx context.Context, eventType string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	b.dispatch(ctx, eventType, raw)
+	return b.send(ctx, eventType, raw)
+}
+
+// Broadcast sends the event to the other instances only, for state this
+// instance has already updated itself.
+func (b *Bus) Broadcast(ctx context.Context, eventType string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return b.send(ctx, eventType, raw)
+}
+
+func (b *Bus) send(ctx context.Context, eventType string, raw json.RawMessage) error {
+	payload, err := json.Marshal(Event{Type: eventType, Origin: b.origin, Data: raw})
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxPayload {
+		return fmt.Errorf("%s event is %d bytes, over the %d byte limit", eventType, len(payload), maxPayload)
+	}
+	return b.notify(ctx, b.channel, string(payload))
+}
+
+func (b *Bus) dispatch(ctx context.Context, eventType string, data json.RawMessage) {
+	b.mu.RLock()
+	handlers := b.handlers[eventType]
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(ctx, data)
+	}
+}
+
+// receive handles a payload from the channel. The instance's own events
+// were already delivered when they were published.
+func (b *Bus) receive(ctx context.Context, payload string) {
+	event := Event{}
+	err := json.Unmarshal([]byte(payload), &event)
+	if err != nil {
+		log.Printf("Error decoding event: %s", err)
+		return
+	}
+	if event.Origin == b.origin {
+		return
+	}
+	b.dispatch(ctx, event.Type, event.Data)
+}
+
+// Listener is the part of pq.Listener the bus uses.
+type Listener interface {
+	Listen(channel string) error
+	NotificationChannel() <-chan *pq.Notification
+	Ping() error
+	Close() error
+}
+
+// NewListener opens a pq.Listener that reconnects on its own, backing off
+// up to a minute between attempts.
+func NewListener(dbURL string) Listener {
+	logEvent := func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			log.Printf("Event bus disconnected: %s", err)
+		case pq.ListenerEventReconnected:
+			log.Print("Event bus reconnected")
+		case pq.ListenerEventConnectionAttemptFailed:
+			log.Printf("Error connecting event bus: %s", err)
+		}
+	}
+	return pq.NewListener(dbURL, minReconnectInterval, maxReconnectInterval, logEvent)
+}
+
+// Run listens on the bus's channel and dispatches remote events until ctx
+// is done. The listener reconnects by itself; Run only notices.
+func (b *Bus) Run(ctx context.Context, listener Listener) error {
+	defer listener.Close()
+	err := listener.Listen(b.channel)
+	if err != nil {
+		return err
+	}
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification, ok := <-listener.NotificationChannel():
+			if !ok {
+				return fmt.Errorf("event bus listener closed")
+			}
+			// pq sends nil after reconnecting. Anything published while
+			// the connection was down is gone.
+			if notification == nil {
+				log.Print("Event bus listener reconnected; events sent while it was down were missed")
+				continue
+			}
+			b.receive(ctx, notification.Extra)
+		case <-ping.C:
+			err := listener.Ping()
+			if err != nil {
+				log.Printf("Error pinging event bus: %s", err)
+			}
+		}
+	}
+}
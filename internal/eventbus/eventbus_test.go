@@ -0,0 +1,117 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// cluster connects buses as if they shared a Postgres channel.
+type cluster struct {
+	buses []*Bus
+}
+
+func (c *cluster) notify(ctx context.Context, channel, payload string) error {
+	for _, bus := range c.buses {
+		if bus.channel == channel {
+			bus.receive(ctx, payload)
+		}
+	}
+	return nil
+}
+
+func TestBusPublish(t *testing.T) {
+	c := &cluster{}
+	local := New("events", c.notify)
+	remote := New("events", c.notify)
+	other := New("other_events", c.notify)
+	c.buses = []*Bus{local, remote, other}
+
+	received := map[*Bus][]string{}
+	for _, bus := range c.buses {
+		bus.Subscribe("chirp.created", func(ctx context.Context, data json.RawMessage) {
+			body := ""
+			json.Unmarshal(data, &body)
+			received[bus] = append(received[bus], body)
+		})
+	}
+
+	if err := local.Publish(context.Background(), "chirp.created", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.Broadcast(context.Background(), "chirp.created", "just the others"); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.Publish(context.Background(), "like.created", "nobody listens"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := received[local]; len(got) != 1 || got[0] != "hello" {
+		t.Errorf("Got: %v, Expected the publisher to handle its own event once", got)
+	}
+	if got := received[remote]; len(got) != 2 || got[0] != "hello" || got[1] != "just the others" {
+		t.Errorf("Got: %v, Expected the other instance to get both events", got)
+	}
+	if got := received[other]; len(got) != 0 {
+		t.Errorf("Got: %v, Expected other channels to be left alone", got)
+	}
+
+	err := local.Publish(context.Background(), "chirp.created", strings.Repeat("x", maxPayload))
+	if err == nil {
+		t.Errorf("Expected an oversized event to be refused")
+	}
+}
+
+type fakeListener struct {
+	listening []string
+	ch        chan *pq.Notification
+	closed    bool
+}
+
+func (l *fakeListener) Listen(channel string) error {
+	l.listening = append(l.listening, channel)
+	return nil
+}
+
+func (l *fakeListener) NotificationChannel() <-chan *pq.Notification { return l.ch }
+func (l *fakeListener) Ping() error                                  { return nil }
+func (l *fakeListener) Close() error {
+	l.closed = true
+	return nil
+}
+
+func TestBusRun(t *testing.T) {
+	bus := New("events", func(ctx context.Context, channel, payload string) error { return nil })
+	received := make(chan string, 1)
+	bus.Subscribe("follow.created", func(ctx context.Context, data json.RawMessage) {
+		received <- string(data)
+	})
+	listener := &fakeListener{ch: make(chan *pq.Notification, 3)}
+	listener.ch <- nil
+	listener.ch <- &pq.Notification{Channel: "events", Extra: "not json"}
+	listener.ch <- &pq.Notification{Channel: "events", Extra: `{"type":"follow.created","origin":"elsewhere","data":{"follower_id":"x"}}`}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- bus.Run(ctx, listener) }()
+
+	select {
+	case data := <-received:
+		if data != `{"follower_id":"x"}` {
+			t.Errorf("Got: %s, Expected the event data", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the remote event to be dispatched after a reconnect and a bad payload")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Got: %v, Expected Run to stop cleanly", err)
+	}
+	if len(listener.listening) != 1 || listener.listening[0] != "events" || !listener.closed {
+		t.Errorf("Expected Run to listen on the bus's channel and close the listener")
+	}
+}
@@ -0,0 +1,324 @@
+// Package jobs runs background work from a queue kept in the database.
+// Jobs have a type, a JSON payload, a time to run at and a priority. Failed
+// jobs are retried with backoff up to a limit and then dead-lettered,
+// where they wait for someone to look at them.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	mathrand "math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job statuses.
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+const (
+	DefaultMaxAttempts = 5
+
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+	// leaseMargin is how much longer than a job's timeout it stays hidden
+	// from other workers, so a job that times out is retried by the worker
+	// that ran it rather than claimed twice.
+	leaseMargin = time.Minute
+	// recordTimeout bounds writing a job's result back to the store.
+	recordTimeout = 10 * time.Second
+)
+
+// Job is a claimed job. Attempts includes the current one.
+type Job struct {
+	ID          uuid.UUID
+	Type        string
+	Payload     json.RawMessage
+	Attempts    int32
+	MaxAttempts int32
+}
+
+// New is a job ready to be written to the queue.
+type New struct {
+	Type        string
+	Payload     string
+	RunAt       time.Time
+	Priority    int32
+	MaxAttempts int32
+	UniqueKey   string
+}
+
+// Options control when and how a job runs. The zero value runs it now, at
+// priority 0, with DefaultMaxAttempts attempts.
+type Options struct {
+	// RunAt delays the job until the given time.
+	RunAt time.Time
+	// Priority orders due jobs, highest first.
+	Priority    int32
+	MaxAttempts int32
+	// UniqueKey stops the job being queued while another with the same key
+	// is pending or running.
+	UniqueKey string
+}
+
+// Prepare encodes a job for the queue, filling in defaults. Writing it is
+// left to the caller, so a job can be queued in the same transaction as
+// the change that calls for it.
+func Prepare(jobType string, payload any, opts Options) (New, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return New{}, err
+	}
+	job := New{
+		Type:        jobType,
+		Payload:     string(raw),
+		RunAt:       opts.RunAt,
+		Priority:    opts.Priority,
+		MaxAttempts: opts.MaxAttempts,
+		UniqueKey:   opts.UniqueKey,
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now().UTC()
+	}
+	if job.MaxAttempts < 1 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	return job, nil
+}
+
+// Store is the queue. Claim must lock the jobs it returns with SKIP
+// LOCKED, mark them running until leaseUntil and count the attempt.
+// Running jobs whose lease has run out are due again. Release makes a job
+// due straight away and takes back the attempt Claim counted.
+type Store interface {
+	Claim(ctx context.Context, types []string, leaseUntil time.Time, limit int32) ([]Job, error)
+	Complete(ctx context.Context, id uuid.UUID) error
+	Retry(ctx context.Context, id uuid.UUID, runAt time.Time, lastError string) error
+	Release(ctx context.Context, id uuid.UUID, lastError string) error
+	Kill(ctx context.Context, id uuid.UUID, lastError string) error
+}
+
+// Handler runs a job. A job can run more than once, if a worker dies
+// before recording the result, so handlers should be idempotent.
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error that retrying will not fix. The job is
+// dead-lettered straight away.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Backoff returns how long to wait before retrying after the given number
+// of attempts: doubling from 10 seconds up to an hour, with up to 20%
+// jitter.
+func Backoff(attempts int32) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := float64(baseBackoff) * math.Pow(2, float64(attempts-1))
+	if delay > float64(maxBackoff) {
+		delay = float64(maxBackoff)
+	}
+	return time.Duration(delay * (1 + 0.2*mathrand.Float64()))
+}
+
+type Config struct {
+	// Concurrency is how many jobs run at once.
+	Concurrency int
+	// PollInterval is how long an idle worker waits before looking for
+	// jobs again, unless woken.
+	PollInterval time.Duration
+	// Timeout bounds a single run of a job.
+	Timeout time.Duration
+	// ShutdownTimeout is how long running jobs get to finish once Run's
+	// context is done. Jobs still running after it are cancelled and
+	// retried.
+	ShutdownTimeout time.Duration
+}
+
+// Worker claims jobs of the types it has handlers for and runs them.
+type Worker struct {
+	store Store
+	cfg   Config
+	wake  chan struct{}
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+func NewWorker(store Store, cfg Config) *Worker {
+	return &Worker{
+		store:    store,
+		cfg:      cfg,
+		wake:     make(chan struct{}, cfg.Concurrency),
+		handlers: map[string]Handler{},
+	}
+}
+
+// Handle registers the handler for a job type. Register it before calling
+// Run.
+func (w *Worker) Handle(jobType string, handler Handler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers[jobType] = handler
+}
+
+// Register handles a job type whose payload decodes into T. Payloads that
+// do not decode are dead-lettered.
+func Register[T any](w *Worker, jobType string, handler func(ctx context.Context, payload T) error) {
+	w.Handle(jobType, func(ctx context.Context, raw json.RawMessage) error {
+		var payload T
+		err := json.Unmarshal(raw, &payload)
+		if err != nil {
+			return Permanent(fmt.Errorf("decoding payload: %w", err))
+		}
+		return handler(ctx, payload)
+	})
+}
+
+// Wake has an idle worker look for jobs straight away. Call it after
+// committing a transaction that queued a job.
+func (w *Worker) Wake() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run works through jobs until ctx is done, then waits for running jobs to
+// finish before returning.
+func (w *Worker) Run(ctx context.Context) {
+	// Jobs get their own context so stopping doesn't cut them short, unless
+	// they overrun ShutdownTimeout.
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-jobCtx.Done():
+			return
+		}
+		select {
+		case <-time.After(w.cfg.ShutdownTimeout):
+			cancel()
+		case <-jobCtx.Done():
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range w.cfg.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx, jobCtx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) loop(ctx, jobCtx context.Context) {
+	for ctx.Err() == nil {
+		ran, err := w.runNext(ctx, jobCtx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Error claiming job: %s", err)
+		}
+		if ran {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(w.cfg.PollInterval):
+		case <-w.wake:
+		}
+	}
+}
+
+// runNext claims and runs one job, reporting whether there was one.
+func (w *Worker) runNext(ctx, jobCtx context.Context) (bool, error) {
+	types := w.types()
+	if len(types) == 0 {
+		return false, nil
+	}
+	claimed, err := w.store.Claim(ctx, types, time.Now().UTC().Add(w.cfg.Timeout+leaseMargin), 1)
+	if err != nil || len(claimed) == 0 {
+		return false, err
+	}
+	w.run(jobCtx, claimed[0])
+	return true, nil
+}
+
+func (w *Worker) types() []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	types := make([]string, 0, len(w.handlers))
+	for jobType := range w.handlers {
+		types = append(types, jobType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// run runs a job and records the result. A job cancelled because the
+// worker is stopping is put back without counting against its limit.
+func (w *Worker) run(jobCtx context.Context, job Job) {
+	err := w.call(jobCtx, job)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(jobCtx), recordTimeout)
+	defer cancel()
+	switch {
+	case err == nil:
+		err = w.store.Complete(ctx, job.ID)
+		if err != nil {
+			// The lease runs out and the job runs again.
+			log.Printf("Error completing %s job %s: %s", job.Type, job.ID, err)
+		}
+		return
+	case jobCtx.Err() != nil:
+		log.Printf("Stopped %s job %s before it finished: %s", job.Type, job.ID, err)
+		err = w.store.Release(ctx, job.ID, "interrupted by shutdown: "+err.Error())
+	case errors.As(err, new(permanentError)) || job.Attempts >= job.MaxAttempts:
+		log.Printf("Error running %s job %s, giving up after %d attempts: %s", job.Type, job.ID, job.Attempts, err)
+		err = w.store.Kill(ctx, job.ID, err.Error())
+	default:
+		log.Printf("Error running %s job %s (attempt %d of %d): %s", job.Type, job.ID, job.Attempts, job.MaxAttempts, err)
+		err = w.store.Retry(ctx, job.ID, time.Now().UTC().Add(Backoff(job.Attempts)), err.Error())
+	}
+	if err != nil {
+		log.Printf("Error recording failed %s job %s: %s", job.Type, job.ID, err)
+	}
+}
+
+func (w *Worker) call(ctx context.Context, job Job) (err error) {
+	w.mu.RLock()
+	handler := w.handlers[job.Type]
+	w.mu.RUnlock()
+	if handler == nil {
+		return Permanent(fmt.Errorf("no handler for %s jobs", job.Type))
+	}
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.Timeout)
+	defer cancel()
+	// A panic is a bug in the handler, which running it again won't fix.
+	defer func() {
+		if r := recover(); r != nil {
+			err = Permanent(fmt.Errorf("handler panicked: %v", r))
+		}
+	}()
+	return handler(ctx, job.Payload)
+}
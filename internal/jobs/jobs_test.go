@@ -0,0 +1,334 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type row struct {
+	job         Job
+	status      string
+	priority    int32
+	runAt       time.Time
+	lockedUntil time.Time
+	lastError   string
+}
+
+// memoryStore is a queue shared by every worker using it.
+type memoryStore struct {
+	mu   sync.Mutex
+	rows []*row
+}
+
+func (s *memoryStore) add(t *testing.T, jobType string, payload any, opts Options) uuid.UUID {
+	t.Helper()
+	job, err := Prepare(jobType, payload, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := uuid.New()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows = append(s.rows, &row{
+		job:      Job{ID: id, Type: job.Type, Payload: json.RawMessage(job.Payload), MaxAttempts: job.MaxAttempts},
+		status:   StatusPending,
+		priority: job.Priority,
+		runAt:    job.RunAt,
+	})
+	return id
+}
+
+func (s *memoryStore) get(id uuid.UUID) row {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rows {
+		if r.job.ID == id {
+			return *r
+		}
+	}
+	return row{}
+}
+
+// expire makes every pending job due, as if time had passed.
+func (s *memoryStore) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rows {
+		r.runAt = time.Time{}
+	}
+}
+
+func (s *memoryStore) Claim(ctx context.Context, types []string, leaseUntil time.Time, limit int32) ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var due []*row
+	for _, r := range s.rows {
+		if !slices.Contains(types, r.job.Type) {
+			continue
+		}
+		if (r.status == StatusPending && !r.runAt.After(now)) || (r.status == StatusRunning && r.lockedUntil.Before(now)) {
+			due = append(due, r)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].priority > due[j].priority })
+	var claimed []Job
+	for _, r := range due {
+		if int32(len(claimed)) == limit {
+			break
+		}
+		r.status = StatusRunning
+		r.lockedUntil = leaseUntil
+		r.job.Attempts++
+		claimed = append(claimed, r.job)
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) set(id uuid.UUID, fn func(r *row)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rows {
+		if r.job.ID == id {
+			fn(r)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) Complete(ctx context.Context, id uuid.UUID) error {
+	return s.set(id, func(r *row) { r.status = StatusSucceeded })
+}
+
+func (s *memoryStore) Retry(ctx context.Context, id uuid.UUID, runAt time.Time, lastError string) error {
+	return s.set(id, func(r *row) {
+		r.status = StatusPending
+		r.runAt = runAt
+		r.lastError = lastError
+	})
+}
+
+func (s *memoryStore) Release(ctx context.Context, id uuid.UUID, lastError string) error {
+	return s.set(id, func(r *row) {
+		r.status = StatusPending
+		r.runAt = time.Now()
+		r.lastError = lastError
+		r.job.Attempts = max(r.job.Attempts-1, 0)
+	})
+}
+
+func (s *memoryStore) Kill(ctx context.Context, id uuid.UUID, lastError string) error {
+	return s.set(id, func(r *row) {
+		r.status = StatusDead
+		r.lastError = lastError
+	})
+}
+
+var testConfig = Config{
+	Concurrency:     1,
+	PollInterval:    time.Hour,
+	Timeout:         time.Second,
+	ShutdownTimeout: time.Second,
+}
+
+// drain runs due jobs one at a time until none are left.
+func drain(t *testing.T, w *Worker) {
+	t.Helper()
+	for {
+		ran, err := w.runNext(context.Background(), context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ran {
+			return
+		}
+	}
+}
+
+func TestWorkerPriority(t *testing.T) {
+	store := &memoryStore{}
+	w := NewWorker(store, testConfig)
+	var got []string
+	Register(w, "greet", func(ctx context.Context, name string) error {
+		got = append(got, name)
+		return nil
+	})
+	store.add(t, "greet", "low", Options{Priority: -1})
+	store.add(t, "greet", "normal", Options{})
+	store.add(t, "greet", "urgent", Options{Priority: 10})
+	later := store.add(t, "greet", "later", Options{RunAt: time.Now().Add(time.Hour), Priority: 100})
+	other := store.add(t, "unknown", "nobody handles this", Options{})
+
+	drain(t, w)
+
+	if !slices.Equal(got, []string{"urgent", "normal", "low"}) {
+		t.Errorf("Got: %v, Expected due jobs highest priority first", got)
+	}
+	if r := store.get(later); r.status != StatusPending {
+		t.Errorf("Got: %s, Expected a job scheduled for later to wait", r.status)
+	}
+	if r := store.get(other); r.status != StatusPending {
+		t.Errorf("Got: %s, Expected jobs without a handler to be left for another worker", r.status)
+	}
+}
+
+func TestWorkerRetryAndDeadLetter(t *testing.T) {
+	store := &memoryStore{}
+	w := NewWorker(store, testConfig)
+	calls := map[string]int{}
+	Register(w, "flaky", func(ctx context.Context, name string) error {
+		calls[name]++
+		if name == "recovers" && calls[name] == 2 {
+			return nil
+		}
+		if name == "panics" {
+			panic("oops")
+		}
+		return errors.New("still broken")
+	})
+	Register(w, "bad", func(ctx context.Context, n int) error {
+		calls["bad"]++
+		if n < 0 {
+			return Permanent(errors.New("negative"))
+		}
+		return nil
+	})
+	recovers := store.add(t, "flaky", "recovers", Options{MaxAttempts: 3})
+	broken := store.add(t, "flaky", "broken", Options{MaxAttempts: 3})
+	panics := store.add(t, "flaky", "panics", Options{})
+	negative := store.add(t, "bad", -1, Options{})
+	undecodable := store.add(t, "bad", "not a number", Options{})
+
+	drain(t, w)
+	if r := store.get(broken); r.status != StatusPending || r.lastError != "still broken" || !r.runAt.After(time.Now()) {
+		t.Errorf("Got: %s %q, Expected a failed job to be retried later", r.status, r.lastError)
+	}
+	for i := 0; i < 3; i++ {
+		store.expire()
+		drain(t, w)
+	}
+
+	if r := store.get(recovers); r.status != StatusSucceeded || r.job.Attempts != 2 {
+		t.Errorf("Got: %s after %d attempts, Expected success on the second", r.status, r.job.Attempts)
+	}
+	if r := store.get(broken); r.status != StatusDead || r.job.Attempts != 3 {
+		t.Errorf("Got: %s after %d attempts, Expected dead after 3", r.status, r.job.Attempts)
+	}
+	if r := store.get(panics); r.status != StatusDead || r.job.Attempts != 1 || r.lastError == "" {
+		t.Errorf("Got: %s %q after %d attempts, Expected a panicking job to be dead-lettered with the panic straight away", r.status, r.lastError, r.job.Attempts)
+	}
+	if calls["panics"] != 1 {
+		t.Errorf("Got: %d calls, Expected a panicking job not to be retried", calls["panics"])
+	}
+	for _, id := range []uuid.UUID{negative, undecodable} {
+		if r := store.get(id); r.status != StatusDead || r.job.Attempts != 1 {
+			t.Errorf("Got: %s after %d attempts, Expected a permanent failure to go straight to dead", r.status, r.job.Attempts)
+		}
+	}
+	if calls["bad"] != 1 {
+		t.Errorf("Got: %d calls, Expected the undecodable payload never to reach the handler", calls["bad"])
+	}
+}
+
+func TestWorkerLeaseExpiry(t *testing.T) {
+	store := &memoryStore{}
+	id := store.add(t, "work", nil, Options{})
+	// A worker that died after claiming the job.
+	if _, err := store.Claim(context.Background(), []string{"work"}, time.Now().Add(-time.Second), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWorker(store, testConfig)
+	w.Handle("work", func(ctx context.Context, payload json.RawMessage) error { return nil })
+	drain(t, w)
+
+	if r := store.get(id); r.status != StatusSucceeded || r.job.Attempts != 2 {
+		t.Errorf("Got: %s after %d attempts, Expected the abandoned job to be claimed again", r.status, r.job.Attempts)
+	}
+}
+
+func TestWorkerShutdown(t *testing.T) {
+	store := &memoryStore{}
+	cfg := testConfig
+	cfg.Concurrency = 2
+	cfg.PollInterval = 10 * time.Millisecond
+	cfg.ShutdownTimeout = 50 * time.Millisecond
+	w := NewWorker(store, cfg)
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	Register(w, "slow", func(ctx context.Context, name string) error {
+		started <- name
+		if name == "quick" {
+			<-release
+			return nil
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	quick := store.add(t, "slow", "quick", Options{Priority: 1})
+	stuck := store.add(t, "slow", "stuck", Options{MaxAttempts: 3})
+	// Two attempts used already, so one more counted would be its last.
+	store.set(stuck, func(r *row) { r.job.Attempts = 2 })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+	<-started
+	<-started
+	cancel()
+	close(release)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Run to return after the shutdown timeout")
+	}
+	if r := store.get(quick); r.status != StatusSucceeded {
+		t.Errorf("Got: %s, Expected the running job to finish during shutdown", r.status)
+	}
+	if r := store.get(stuck); r.status != StatusPending || r.runAt.After(time.Now()) || r.job.Attempts != 2 {
+		t.Errorf("Got: %s after %d attempts, Expected the job cut short to be put back without using an attempt", r.status, r.job.Attempts)
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	job, err := Prepare("greet", map[string]string{"name": "ada"}, Options{UniqueKey: "greet:ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Payload != `{"name":"ada"}` || job.MaxAttempts != DefaultMaxAttempts || job.RunAt.IsZero() || job.UniqueKey != "greet:ada" {
+		t.Errorf("Got: %+v, Expected the payload encoded and defaults filled in", job)
+	}
+	if _, err := Prepare("greet", make(chan int), Options{}); err == nil {
+		t.Error("Expected a payload that cannot be encoded to fail")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		attempts int32
+		min, max time.Duration
+	}{
+		{0, 10 * time.Second, 12 * time.Second},
+		{1, 10 * time.Second, 12 * time.Second},
+		{3, 40 * time.Second, 48 * time.Second},
+		{30, time.Hour, 72 * time.Minute},
+	}
+	for _, c := range cases {
+		got := Backoff(c.attempts)
+		if got < c.min || got > c.max {
+			t.Errorf("attempts %d: Got: %s, Expected between %s and %s", c.attempts, got, c.min, c.max)
+		}
+	}
+}
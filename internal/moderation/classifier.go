@@ -0,0 +1,209 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Example is a labelled chirp body used to train a Classifier.
+type Example struct {
+	Body  string
+	Toxic bool
+}
+
+// NaiveBayes is a multinomial naive Bayes text model over word unigrams and
+// bigrams. The zero value is untrained and scores everything 0.
+type NaiveBayes struct {
+	docs       [2]int
+	tokens     [2]int
+	counts     [2]map[string]int
+	vocabulary int
+}
+
+func features(txt string) []string {
+	tokens := strings.Fields(normalize(txt))
+	out := make([]string, 0, 2*len(tokens))
+	out = append(out, tokens...)
+	for i := 0; i+1 < len(tokens); i++ {
+		out = append(out, tokens[i]+" "+tokens[i+1])
+	}
+	return out
+}
+
+func label(toxic bool) int {
+	if toxic {
+		return 1
+	}
+	return 0
+}
+
+// TrainNaiveBayes fits a model to the examples.
+func TrainNaiveBayes(examples []Example) *NaiveBayes {
+	model := &NaiveBayes{
+		counts: [2]map[string]int{{}, {}},
+	}
+	vocabulary := map[string]bool{}
+	for _, example := range examples {
+		class := label(example.Toxic)
+		model.docs[class]++
+		for _, feature := range features(example.Body) {
+			model.counts[class][feature]++
+			model.tokens[class]++
+			vocabulary[feature] = true
+		}
+	}
+	model.vocabulary = len(vocabulary)
+	return model
+}
+
+// Score returns the probability in [0, 1] that txt is toxic.
+func (m *NaiveBayes) Score(txt string) float64 {
+	if m == nil || m.docs[0] == 0 || m.docs[1] == 0 {
+		return 0
+	}
+	total := float64(m.docs[0] + m.docs[1])
+	var logProb [2]float64
+	for class := 0; class < 2; class++ {
+		logProb[class] = math.Log(float64(m.docs[class]) / total)
+		denominator := float64(m.tokens[class] + m.vocabulary)
+		for _, feature := range features(txt) {
+			logProb[class] += math.Log(float64(m.counts[class][feature]+1) / denominator)
+		}
+	}
+	return 1 / (1 + math.Exp(logProb[0]-logProb[1]))
+}
+
+// ClassifierMetrics describes the current model and how it has been used.
+// Accuracy, precision and recall come from a held-out fifth of the training
+// examples.
+type ClassifierMetrics struct {
+	TrainedAt     *time.Time `json:"trained_at"`
+	Examples      int        `json:"examples"`
+	ToxicExamples int        `json:"toxic_examples"`
+	Vocabulary    int        `json:"vocabulary"`
+	Accuracy      float64    `json:"accuracy"`
+	Precision     float64    `json:"precision"`
+	Recall        float64    `json:"recall"`
+	Threshold     float64    `json:"threshold"`
+	Active        bool       `json:"active"`
+	Scored        int64      `json:"scored"`
+	Held          int64      `json:"held"`
+}
+
+// Classifier is a moderation stage that holds chirps the current model
+// scores at or above Threshold. It allows everything until it has been
+// trained on at least MinExamples of each class.
+type Classifier struct {
+	Threshold   float64
+	MinExamples int
+
+	mu      sync.RWMutex
+	model   *NaiveBayes
+	metrics ClassifierMetrics
+	scored  atomic.Int64
+	held    atomic.Int64
+}
+
+func NewClassifier(threshold float64) *Classifier {
+	return &Classifier{
+		Threshold:   threshold,
+		MinExamples: 5,
+	}
+}
+
+// Train replaces the model with one fitted to examples and returns the new
+// metrics.
+func (c *Classifier) Train(examples []Example) ClassifierMetrics {
+	train := []Example{}
+	holdout := []Example{}
+	toxic := 0
+	for i, example := range examples {
+		if example.Toxic {
+			toxic++
+		}
+		if i%5 == 4 {
+			holdout = append(holdout, example)
+		} else {
+			train = append(train, example)
+		}
+	}
+
+	metrics := evaluate(TrainNaiveBayes(train), holdout, c.Threshold)
+	model := TrainNaiveBayes(examples)
+	trainedAt := time.Now()
+	metrics.TrainedAt = &trainedAt
+	metrics.Examples = len(examples)
+	metrics.ToxicExamples = toxic
+	metrics.Vocabulary = model.vocabulary
+	metrics.Threshold = c.Threshold
+	metrics.Active = toxic >= c.MinExamples && len(examples)-toxic >= c.MinExamples
+
+	c.mu.Lock()
+	c.model = model
+	c.metrics = metrics
+	c.mu.Unlock()
+	return c.Metrics()
+}
+
+func evaluate(model *NaiveBayes, holdout []Example, threshold float64) ClassifierMetrics {
+	var truePositive, falsePositive, falseNegative, correct int
+	for _, example := range holdout {
+		predicted := model.Score(example.Body) >= threshold
+		if predicted == example.Toxic {
+			correct++
+		}
+		switch {
+		case predicted && example.Toxic:
+			truePositive++
+		case predicted && !example.Toxic:
+			falsePositive++
+		case !predicted && example.Toxic:
+			falseNegative++
+		}
+	}
+	metrics := ClassifierMetrics{}
+	if len(holdout) > 0 {
+		metrics.Accuracy = float64(correct) / float64(len(holdout))
+	}
+	if truePositive+falsePositive > 0 {
+		metrics.Precision = float64(truePositive) / float64(truePositive+falsePositive)
+	}
+	if truePositive+falseNegative > 0 {
+		metrics.Recall = float64(truePositive) / float64(truePositive+falseNegative)
+	}
+	return metrics
+}
+
+// Metrics returns a snapshot of the classifier's metrics.
+func (c *Classifier) Metrics() ClassifierMetrics {
+	c.mu.RLock()
+	metrics := c.metrics
+	c.mu.RUnlock()
+	metrics.Scored = c.scored.Load()
+	metrics.Held = c.held.Load()
+	return metrics
+}
+
+func (c *Classifier) Moderate(ctx context.Context, sub Submission) (Verdict, error) {
+	c.mu.RLock()
+	model, active := c.model, c.metrics.Active
+	c.mu.RUnlock()
+	if !active {
+		return Verdict{Action: Allow}, nil
+	}
+	c.scored.Add(1)
+	score := model.Score(sub.Body)
+	if score < c.Threshold {
+		return Verdict{Action: Allow}, nil
+	}
+	c.held.Add(1)
+	return Verdict{
+		Action: Hold,
+		Reason: fmt.Sprintf("toxicity score %.2f", score),
+	}, nil
+}
@@ -0,0 +1,83 @@
+package moderation
+
+import (
+	"context"
+	"testing"
+)
+
+func trainingExamples() []Example {
+	toxic := []string{
+		"you are an idiot and everyone hates you",
+		"shut up idiot nobody asked",
+		"what a pathetic loser",
+		"go away you stupid loser",
+		"idiot take your pathetic opinion elsewhere",
+		"everyone hates you loser",
+		"stupid idiot",
+	}
+	clean := []string{
+		"lovely weather for a walk today",
+		"just finished a great book about space",
+		"coffee with friends this morning",
+		"the sunset over the lake was beautiful",
+		"excited for the weekend hike",
+		"trying a new pasta recipe tonight",
+		"congrats on the new job",
+	}
+	examples := []Example{}
+	for i := range toxic {
+		examples = append(examples, Example{Body: toxic[i], Toxic: true})
+		examples = append(examples, Example{Body: clean[i], Toxic: false})
+	}
+	return examples
+}
+
+func TestNaiveBayesScore(t *testing.T) {
+	model := TrainNaiveBayes(trainingExamples())
+	if score := model.Score("you stupid pathetic idiot"); score < 0.9 {
+		t.Errorf("Expected insult to score high, got %.2f", score)
+	}
+	if score := model.Score("a beautiful walk by the lake"); score > 0.1 {
+		t.Errorf("Expected friendly chirp to score low, got %.2f", score)
+	}
+	var untrained *NaiveBayes
+	if score := untrained.Score("anything"); score != 0 {
+		t.Errorf("Expected untrained model to score 0, got %.2f", score)
+	}
+}
+
+func TestClassifierHoldsHighScorers(t *testing.T) {
+	classifier := NewClassifier(0.8)
+	verdict, err := classifier.Moderate(context.Background(), Submission{Body: "stupid idiot"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if verdict.Action != Allow {
+		t.Errorf("Expected untrained classifier to allow, got %s", verdict.Action)
+	}
+
+	metrics := classifier.Train(trainingExamples())
+	if !metrics.Active || metrics.Examples != 14 || metrics.ToxicExamples != 7 {
+		t.Errorf("Unexpected metrics after training: %+v", metrics)
+	}
+
+	verdict, err = classifier.Moderate(context.Background(), Submission{Body: "you pathetic stupid loser"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if verdict.Action != Hold {
+		t.Errorf("Expected toxic chirp to be held, got %s", verdict.Action)
+	}
+	verdict, err = classifier.Moderate(context.Background(), Submission{Body: "great hike this weekend"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if verdict.Action != Allow {
+		t.Errorf("Expected friendly chirp to be allowed, got %s", verdict.Action)
+	}
+
+	metrics = classifier.Metrics()
+	if metrics.Scored != 2 || metrics.Held != 1 {
+		t.Errorf("Expected 2 scored and 1 held, got %d and %d", metrics.Scored, metrics.Held)
+	}
+}
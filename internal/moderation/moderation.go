@@ -0,0 +1,135 @@
+// Package moderation runs chirp bodies through a chain of moderation stages
+// before they are published.
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Action is what a stage decided to do with a submission. Actions are
+// ordered by severity so a chain can keep the strictest one.
+type Action int
+
+const (
+	Allow Action = iota
+	Mask
+	Hold
+	Reject
+)
+
+func (a Action) String() string {
+	switch a {
+	case Allow:
+		return "allow"
+	case Mask:
+		return "mask"
+	case Hold:
+		return "hold"
+	case Reject:
+		return "reject"
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+func (a Action) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// Submission is a chirp body on its way into the database. ChirpID is the
+// zero UUID when the chirp is being created rather than edited.
+type Submission struct {
+	AuthorID uuid.UUID
+	ChirpID  uuid.UUID
+	Body     string
+}
+
+// Verdict is a single stage's decision. Body is only read when Action is
+// Mask and holds the rewritten text.
+type Verdict struct {
+	Action Action
+	Body   string
+	Reason string
+}
+
+// Moderator is one stage of the pipeline. Implement it to add custom rules
+// and hand it to Register or NewChain.
+type Moderator interface {
+	Moderate(ctx context.Context, sub Submission) (Verdict, error)
+}
+
+// ModeratorFunc lets a plain function be used as a Moderator.
+type ModeratorFunc func(ctx context.Context, sub Submission) (Verdict, error)
+
+func (f ModeratorFunc) Moderate(ctx context.Context, sub Submission) (Verdict, error) {
+	return f(ctx, sub)
+}
+
+// Outcome is the combined result of running a chain and is returned to
+// clients as-is.
+type Outcome struct {
+	Action  Action   `json:"action"`
+	Body    string   `json:"-"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+var (
+	registeredMu sync.Mutex
+	registered   []Moderator
+)
+
+// Register adds a stage to every chain built by NewChain afterwards. It is
+// meant to be called from an init function so extra rules can live in their
+// own file.
+func Register(m Moderator) {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+	registered = append(registered, m)
+}
+
+// Chain runs its stages in order. Masks rewrite the body seen by later
+// stages, and a Reject stops the chain immediately.
+type Chain []Moderator
+
+// NewChain returns the given stages followed by any registered ones.
+func NewChain(stages ...Moderator) Chain {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+	chain := Chain{}
+	chain = append(chain, stages...)
+	chain = append(chain, registered...)
+	return chain
+}
+
+func (c Chain) Run(ctx context.Context, sub Submission) (Outcome, error) {
+	outcome := Outcome{
+		Action: Allow,
+		Body:   sub.Body,
+	}
+	for _, stage := range c {
+		sub.Body = outcome.Body
+		verdict, err := stage.Moderate(ctx, sub)
+		if err != nil {
+			return Outcome{}, err
+		}
+		if verdict.Action == Allow {
+			continue
+		}
+		if verdict.Action == Mask {
+			outcome.Body = verdict.Body
+		}
+		if verdict.Action > outcome.Action {
+			outcome.Action = verdict.Action
+		}
+		if verdict.Reason != "" {
+			outcome.Reasons = append(outcome.Reasons, verdict.Reason)
+		}
+		if verdict.Action == Reject {
+			break
+		}
+	}
+	return outcome, nil
+}
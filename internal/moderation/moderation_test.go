@@ -0,0 +1,190 @@
+package moderation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestWordFilter(t *testing.T) {
+	type values struct {
+		input  string
+		output string
+	}
+	cases := []values{
+		{
+			input:  "Hello I'm bob",
+			output: "Hello I'm bob",
+		},
+		{
+			input:  "Pain",
+			output: "Pain",
+		},
+		{
+			input:  "I love a good kerfuffle",
+			output: "I love a good ****",
+		},
+		{
+			input:  "I love a good Kerfuffle",
+			output: "I love a good ****",
+		},
+		{
+			input:  "kerfuffle I barly even know her",
+			output: "**** I barly even know her",
+		},
+		{
+			input:  "sharbert I barly even know her",
+			output: "**** I barly even know her",
+		},
+		{
+			input:  "fornax I barly even know her",
+			output: "**** I barly even know her",
+		},
+		{
+			input:  "fornaxing I barly even know her",
+			output: "****ing I barly even know her",
+		},
+		{
+			input:  "kerFuffle sharBERT forNaX",
+			output: "**** **** ****",
+		},
+	}
+	filter := NewWordFilter("kerfuffle", "sharbert", "fornax")
+	for _, val := range cases {
+		cleanedTxt := filter.Clean(val.input)
+		if cleanedTxt != val.output {
+			t.Errorf("Output did not match input. \nGot:%s \nExp:%s\n", cleanedTxt, val.output)
+		}
+	}
+}
+
+func TestLinkBlocklist(t *testing.T) {
+	blocklist := NewLinkBlocklist("spam.example", "bad.test")
+	cases := map[string]Action{
+		"no links here":                         Allow,
+		"read https://good.example/post":        Allow,
+		"buy now https://spam.example/deal":     Reject,
+		"also www.cdn.spam.example is caught":   Reject,
+		"bare BAD.TEST works too":               Reject,
+		"notspam.example is a different domain": Allow,
+	}
+	for input, want := range cases {
+		verdict, err := blocklist.Moderate(context.Background(), Submission{Body: input})
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", input, err)
+			continue
+		}
+		if verdict.Action != want {
+			t.Errorf("%q: Got: %s, Expected: %s", input, verdict.Action, want)
+		}
+	}
+}
+
+func TestDuplicateDetector(t *testing.T) {
+	earlier := RecentChirp{ID: uuid.New(), Body: "Big sale today!"}
+	detector := NewDuplicateDetector(func(ctx context.Context, authorID uuid.UUID, since time.Time) ([]RecentChirp, error) {
+		return []RecentChirp{earlier}, nil
+	}, time.Hour)
+
+	verdict, err := detector.Moderate(context.Background(), Submission{Body: "big   SALE today"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if verdict.Action != Reject {
+		t.Errorf("Expected near-identical body to be rejected, got %s", verdict.Action)
+	}
+
+	earlier.Body = "Buy cheap watches online today, best prices guaranteed at example.com"
+	verdict, err = detector.Moderate(context.Background(), Submission{Body: "Buy cheap watches online today, best prices guaranteed at example.com now"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if verdict.Action != Reject {
+		t.Errorf("Expected body with one extra word to be rejected, got %s", verdict.Action)
+	}
+
+	verdict, err = detector.Moderate(context.Background(), Submission{Body: "I had a lovely walk in the park with my dog this morning"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if verdict.Action != Allow {
+		t.Errorf("Expected unrelated body to be allowed, got %s", verdict.Action)
+	}
+	earlier.Body = "Big sale today!"
+
+	verdict, err = detector.Moderate(context.Background(), Submission{ChirpID: earlier.ID, Body: "Big sale today!"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if verdict.Action != Allow {
+		t.Errorf("Expected an edit of the same chirp to be allowed, got %s", verdict.Action)
+	}
+}
+
+func TestLinkThrottle(t *testing.T) {
+	createdAt := time.Now().Add(-time.Hour)
+	throttle := NewLinkThrottle(func(ctx context.Context, authorID uuid.UUID, since time.Time) ([]RecentChirp, error) {
+		return []RecentChirp{
+			{ID: uuid.New(), Body: "see a.example and b.example"},
+			{ID: uuid.New(), Body: "and c.example"},
+		}, nil
+	}, func(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+		return createdAt, nil
+	})
+
+	verdict, err := throttle.Moderate(context.Background(), Submission{Body: "no links this time"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if verdict.Action != Allow {
+		t.Errorf("Expected chirp without links to be allowed, got %s", verdict.Action)
+	}
+
+	verdict, err = throttle.Moderate(context.Background(), Submission{Body: "one more d.example"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if verdict.Action != Hold {
+		t.Errorf("Expected fourth link from a new account to be held, got %s", verdict.Action)
+	}
+
+	createdAt = time.Now().Add(-30 * 24 * time.Hour)
+	verdict, err = throttle.Moderate(context.Background(), Submission{Body: "one more d.example"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if verdict.Action != Allow {
+		t.Errorf("Expected established account to be allowed, got %s", verdict.Action)
+	}
+}
+
+func TestChainKeepsStrictestAction(t *testing.T) {
+	hold := ModeratorFunc(func(ctx context.Context, sub Submission) (Verdict, error) {
+		return Verdict{Action: Hold, Reason: "needs a look"}, nil
+	})
+	chain := Chain{NewWordFilter("fornax"), hold}
+	outcome, err := chain.Run(context.Background(), Submission{Body: "fornax"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if outcome.Action != Hold {
+		t.Errorf("Got: %s, Expected: %s", outcome.Action, Hold)
+	}
+	if outcome.Body != "****" {
+		t.Errorf("Expected masked body to be kept, got %q", outcome.Body)
+	}
+	if len(outcome.Reasons) != 2 {
+		t.Errorf("Expected a reason from each stage, got %v", outcome.Reasons)
+	}
+
+	chain = Chain{NewLinkBlocklist("spam.example"), hold}
+	outcome, err = chain.Run(context.Background(), Submission{Body: "spam.example"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if outcome.Action != Reject || len(outcome.Reasons) != 1 {
+		t.Errorf("Expected reject to stop the chain, got %s %v", outcome.Action, outcome.Reasons)
+	}
+}
@@ -0,0 +1,44 @@
+package moderation
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+)
+
+// Simhash returns a 64-bit locality sensitive hash of txt. Bodies that differ
+// by a few words produce hashes a small Hamming distance apart.
+func Simhash(txt string) uint64 {
+	tokens := strings.Fields(normalize(txt))
+	features := make([]string, 0, 2*len(tokens))
+	features = append(features, tokens...)
+	for i := 0; i+1 < len(tokens); i++ {
+		features = append(features, tokens[i]+" "+tokens[i+1])
+	}
+
+	var weights [64]int
+	for _, feature := range features {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	var hash uint64
+	for bit := 0; bit < 64; bit++ {
+		if weights[bit] > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+// HammingDistance counts the bits that differ between two hashes.
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
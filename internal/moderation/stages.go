@@ -0,0 +1,203 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WordFilter masks any occurrence of its words, ignoring case.
+type WordFilter struct {
+	re *regexp.Regexp
+}
+
+func NewWordFilter(words ...string) *WordFilter {
+	quoted := []string{}
+	for _, word := range words {
+		if word == "" {
+			continue
+		}
+		quoted = append(quoted, regexp.QuoteMeta(word))
+	}
+	if len(quoted) == 0 {
+		return &WordFilter{}
+	}
+	return &WordFilter{
+		re: regexp.MustCompile("(?i)(" + strings.Join(quoted, "|") + ")"),
+	}
+}
+
+func (f *WordFilter) Clean(txt string) string {
+	if f.re == nil {
+		return txt
+	}
+	return f.re.ReplaceAllString(txt, "****")
+}
+
+func (f *WordFilter) Moderate(ctx context.Context, sub Submission) (Verdict, error) {
+	cleanTxt := f.Clean(sub.Body)
+	if cleanTxt == sub.Body {
+		return Verdict{Action: Allow}, nil
+	}
+	return Verdict{
+		Action: Mask,
+		Body:   cleanTxt,
+		Reason: "filtered words were masked",
+	}, nil
+}
+
+var linkRe = regexp.MustCompile(`(?i)\b(?:https?://)?(?:www\.)?((?:[a-z0-9-]+\.)+[a-z]{2,})(?:[/?#]\S*)?`)
+
+// Links returns the lower-cased host of every link in txt.
+func Links(txt string) []string {
+	hosts := []string{}
+	for _, match := range linkRe.FindAllStringSubmatch(txt, -1) {
+		hosts = append(hosts, strings.ToLower(match[1]))
+	}
+	return hosts
+}
+
+// LinkBlocklist rejects chirps linking to a blocked domain or any of its
+// subdomains.
+type LinkBlocklist struct {
+	domains map[string]bool
+}
+
+func NewLinkBlocklist(domains ...string) *LinkBlocklist {
+	blocklist := &LinkBlocklist{domains: map[string]bool{}}
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" {
+			blocklist.domains[domain] = true
+		}
+	}
+	return blocklist
+}
+
+func (b *LinkBlocklist) Moderate(ctx context.Context, sub Submission) (Verdict, error) {
+	for _, host := range Links(sub.Body) {
+		for domain := host; domain != ""; {
+			if b.domains[domain] {
+				return Verdict{
+					Action: Reject,
+					Reason: fmt.Sprintf("links to blocked domain %s", domain),
+				}, nil
+			}
+			_, parent, found := strings.Cut(domain, ".")
+			if !found {
+				break
+			}
+			domain = parent
+		}
+	}
+	return Verdict{Action: Allow}, nil
+}
+
+// RecentChirp is the part of an earlier chirp the duplicate detector needs.
+type RecentChirp struct {
+	ID   uuid.UUID
+	Body string
+}
+
+// RecentChirpsFunc returns an author's chirps created after since.
+type RecentChirpsFunc func(ctx context.Context, authorID uuid.UUID, since time.Time) ([]RecentChirp, error)
+
+// DuplicateDetector rejects a chirp whose body is a near-duplicate of one the
+// same author posted within Window. Bodies are compared by Simhash after
+// normalizing case, punctuation and spacing.
+type DuplicateDetector struct {
+	Recent      RecentChirpsFunc
+	Window      time.Duration
+	MaxDistance int
+}
+
+func NewDuplicateDetector(recent RecentChirpsFunc, window time.Duration) *DuplicateDetector {
+	return &DuplicateDetector{
+		Recent:      recent,
+		Window:      window,
+		MaxDistance: 3,
+	}
+}
+
+func (d *DuplicateDetector) Moderate(ctx context.Context, sub Submission) (Verdict, error) {
+	recent, err := d.Recent(ctx, sub.AuthorID, time.Now().Add(-d.Window))
+	if err != nil {
+		return Verdict{}, err
+	}
+	hash := Simhash(sub.Body)
+	for _, chirp := range recent {
+		if chirp.ID == sub.ChirpID {
+			continue
+		}
+		if HammingDistance(hash, Simhash(chirp.Body)) <= d.MaxDistance {
+			return Verdict{
+				Action: Reject,
+				Reason: "duplicate of a recent chirp",
+			}, nil
+		}
+	}
+	return Verdict{Action: Allow}, nil
+}
+
+// AccountCreatedFunc returns when a user signed up.
+type AccountCreatedFunc func(ctx context.Context, userID uuid.UUID) (time.Time, error)
+
+// LinkThrottle holds chirps for review once an account younger than
+// NewAccountAge has posted more than MaxLinks links within Window.
+type LinkThrottle struct {
+	Recent        RecentChirpsFunc
+	CreatedAt     AccountCreatedFunc
+	NewAccountAge time.Duration
+	Window        time.Duration
+	MaxLinks      int
+}
+
+func NewLinkThrottle(recent RecentChirpsFunc, createdAt AccountCreatedFunc) *LinkThrottle {
+	return &LinkThrottle{
+		Recent:        recent,
+		CreatedAt:     createdAt,
+		NewAccountAge: 7 * 24 * time.Hour,
+		Window:        time.Hour,
+		MaxLinks:      3,
+	}
+}
+
+func (l *LinkThrottle) Moderate(ctx context.Context, sub Submission) (Verdict, error) {
+	links := len(Links(sub.Body))
+	if links == 0 {
+		return Verdict{Action: Allow}, nil
+	}
+	createdAt, err := l.CreatedAt(ctx, sub.AuthorID)
+	if err != nil {
+		return Verdict{}, err
+	}
+	if time.Since(createdAt) > l.NewAccountAge {
+		return Verdict{Action: Allow}, nil
+	}
+	recent, err := l.Recent(ctx, sub.AuthorID, time.Now().Add(-l.Window))
+	if err != nil {
+		return Verdict{}, err
+	}
+	for _, chirp := range recent {
+		if chirp.ID != sub.ChirpID {
+			links += len(Links(chirp.Body))
+		}
+	}
+	if links > l.MaxLinks {
+		return Verdict{
+			Action: Hold,
+			Reason: "link-heavy posting from a new account",
+		}, nil
+	}
+	return Verdict{Action: Allow}, nil
+}
+
+var nonWordRe = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+func normalize(txt string) string {
+	return strings.TrimSpace(nonWordRe.ReplaceAllString(strings.ToLower(txt), " "))
+}
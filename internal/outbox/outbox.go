@@ -0,0 +1,198 @@
+// Package outbox delivers events that were written to an outbox table in
+// the same transaction as the change they describe. Events are delivered
+// at least once, so a crash between committing a change and acting on it
+// only delays the side effects. An event that keeps failing is retried
+// with backoff up to its limit and then set aside as dead.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// handlerTimeout bounds the handlers for a single event.
+	handlerTimeout = time.Minute
+	// lease is how long a claimed event is hidden from other dispatchers.
+	// The events in a batch are delivered at once, so the whole batch
+	// finishes within handlerTimeout and its lease only needs room for
+	// recording the results. An instance that dies mid-delivery leaves its
+	// events to be claimed again once it runs out.
+	lease     = handlerTimeout + time.Minute
+	batchSize = 20
+
+	minRetryDelay = time.Second
+	maxRetryDelay = 10 * time.Minute
+)
+
+// Message is an event read back from the outbox. Attempts includes the
+// current one.
+type Message struct {
+	ID          uuid.UUID
+	Type        string
+	Payload     json.RawMessage
+	Attempts    int32
+	MaxAttempts int32
+}
+
+// Store is the outbox table. Claim must lock the rows it returns with
+// SKIP LOCKED and push them out of reach until leaseUntil, so dispatchers
+// on several instances never deliver the same event at once. Dead events
+// are never claimed again.
+type Store interface {
+	Claim(ctx context.Context, leaseUntil time.Time, limit int32) ([]Message, error)
+	MarkPublished(ctx context.Context, id uuid.UUID) error
+	Retry(ctx context.Context, id uuid.UUID, availableAt time.Time, lastError string) error
+	Kill(ctx context.Context, id uuid.UUID, lastError string) error
+}
+
+// Handler acts on an event. Returning an error has the event delivered
+// again later, to every handler for its type, so handlers must be
+// idempotent.
+type Handler func(ctx context.Context, data json.RawMessage) error
+
+// Dispatcher claims events from the store and hands them to the handlers
+// subscribed to their type. It is safe for concurrent use.
+type Dispatcher struct {
+	store Store
+	wake  chan struct{}
+
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewDispatcher(store Store) *Dispatcher {
+	return &Dispatcher{
+		store:    store,
+		wake:     make(chan struct{}, 1),
+		handlers: map[string][]Handler{},
+	}
+}
+
+// Encode turns event data into an outbox payload.
+func Encode(data any) (string, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func (d *Dispatcher) Subscribe(eventType string, handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[eventType] = append(d.handlers[eventType], handler)
+}
+
+// Wake has Run poll straight away rather than at its next tick. Call it
+// after committing a transaction that wrote to the outbox.
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run polls the outbox every interval, and whenever woken, until ctx is
+// done.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := d.Poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Error polling outbox: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// Poll delivers claimed events in batches until none are due. Events in a
+// batch are delivered concurrently, so handlers must not depend on their
+// order.
+func (d *Dispatcher) Poll(ctx context.Context) error {
+	for {
+		messages, err := d.store.Claim(ctx, time.Now().UTC().Add(lease), batchSize)
+		if err != nil {
+			return err
+		}
+		var wg sync.WaitGroup
+		for _, message := range messages {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.deliver(ctx, message)
+			}()
+		}
+		wg.Wait()
+		if len(messages) < batchSize {
+			return nil
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, message Message) {
+	err := d.handle(ctx, message)
+	if err == nil {
+		err = d.store.MarkPublished(ctx, message.ID)
+		if err != nil {
+			// The lease runs out and the event is delivered again.
+			log.Printf("Error marking outbox event %s published: %s", message.ID, err)
+		}
+		return
+	}
+	if message.Attempts >= message.MaxAttempts {
+		log.Printf("Error handling %s outbox event %s, giving up after %d attempts: %s", message.Type, message.ID, message.Attempts, err)
+		err = d.store.Kill(ctx, message.ID, err.Error())
+		if err != nil {
+			log.Printf("Error marking outbox event %s dead: %s", message.ID, err)
+		}
+		return
+	}
+	log.Printf("Error handling %s outbox event %s (attempt %d of %d): %s", message.Type, message.ID, message.Attempts, message.MaxAttempts, err)
+	err = d.store.Retry(ctx, message.ID, time.Now().UTC().Add(retryDelay(message.Attempts)), err.Error())
+	if err != nil {
+		log.Printf("Error scheduling outbox event %s for retry: %s", message.ID, err)
+	}
+}
+
+func (d *Dispatcher) handle(ctx context.Context, message Message) (err error) {
+	d.mu.RLock()
+	handlers := d.handlers[message.Type]
+	d.mu.RUnlock()
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	for _, handler := range handlers {
+		err := handler(ctx, message.Payload)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retryDelay doubles from minRetryDelay with each attempt, up to
+// maxRetryDelay.
+func retryDelay(attempts int32) time.Duration {
+	delay := minRetryDelay
+	for i := int32(1); i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
@@ -0,0 +1,333 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type row struct {
+	message     Message
+	availableAt time.Time
+	published   bool
+	dead        bool
+	lastError   string
+}
+
+// memoryStore is an outbox table shared by every dispatcher using it.
+type memoryStore struct {
+	mu   sync.Mutex
+	rows []*row
+}
+
+func (s *memoryStore) add(eventType string, data any) uuid.UUID {
+	return s.addWithLimit(eventType, data, 20)
+}
+
+func (s *memoryStore) addWithLimit(eventType string, data any, maxAttempts int32) uuid.UUID {
+	payload, _ := Encode(data)
+	id := uuid.New()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows = append(s.rows, &row{message: Message{ID: id, Type: eventType, Payload: json.RawMessage(payload), MaxAttempts: maxAttempts}})
+	return id
+}
+
+func (s *memoryStore) get(id uuid.UUID) row {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rows {
+		if r.message.ID == id {
+			return *r
+		}
+	}
+	return row{}
+}
+
+// expire makes every unpublished event due again, as if time had passed.
+func (s *memoryStore) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rows {
+		r.availableAt = time.Time{}
+	}
+}
+
+func (s *memoryStore) Claim(ctx context.Context, leaseUntil time.Time, limit int32) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []Message
+	for _, r := range s.rows {
+		if int32(len(claimed)) == limit {
+			break
+		}
+		if r.published || r.dead || r.availableAt.After(time.Now()) {
+			continue
+		}
+		r.availableAt = leaseUntil
+		r.message.Attempts++
+		claimed = append(claimed, r.message)
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) MarkPublished(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rows {
+		if r.message.ID == id {
+			r.published = true
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) Retry(ctx context.Context, id uuid.UUID, availableAt time.Time, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rows {
+		if r.message.ID == id {
+			r.availableAt = availableAt
+			r.lastError = lastError
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) Kill(ctx context.Context, id uuid.UUID, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rows {
+		if r.message.ID == id {
+			r.dead = true
+			r.lastError = lastError
+		}
+	}
+	return nil
+}
+
+func TestDispatcherPoll(t *testing.T) {
+	store := &memoryStore{}
+	d := NewDispatcher(store)
+	var mu sync.Mutex
+	var got []string
+	d.Subscribe("chirp.created", func(ctx context.Context, data json.RawMessage) error {
+		body := ""
+		json.Unmarshal(data, &body)
+		mu.Lock()
+		got = append(got, body)
+		mu.Unlock()
+		return nil
+	})
+	first := store.add("chirp.created", "first")
+	second := store.add("chirp.created", "second")
+	unhandled := store.add("like.created", "nobody listens")
+
+	if err := d.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Errorf("Got: %v, Expected both chirp events", got)
+	}
+	for _, id := range []uuid.UUID{first, second, unhandled} {
+		if !store.get(id).published {
+			t.Errorf("Expected event %s to be marked published", id)
+		}
+	}
+
+	store.expire()
+	if err := d.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Errorf("Got: %v, Expected published events not to be delivered again", got)
+	}
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	store := &memoryStore{}
+	d := NewDispatcher(store)
+	calls := 0
+	d.Subscribe("follow.created", func(ctx context.Context, data json.RawMessage) error {
+		calls++
+		if calls == 1 {
+			return errors.New("database is down")
+		}
+		return nil
+	})
+	panics := true
+	d.Subscribe("like.created", func(ctx context.Context, data json.RawMessage) error {
+		if panics {
+			panic("bad payload")
+		}
+		return nil
+	})
+	follow := store.add("follow.created", nil)
+	like := store.add("like.created", nil)
+
+	if err := d.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []uuid.UUID{follow, like} {
+		r := store.get(id)
+		if r.published {
+			t.Errorf("Expected failed event %s not to be published", id)
+		}
+		if r.lastError == "" {
+			t.Errorf("Expected failed event %s to record its error", id)
+		}
+		if !r.availableAt.After(time.Now()) {
+			t.Errorf("Expected failed event %s to be retried later", id)
+		}
+	}
+
+	if err := d.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("Got: %d calls, Expected no retry before the delay", calls)
+	}
+
+	panics = false
+	store.expire()
+	if err := d.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("Got: %d calls, Expected the event to be delivered again", calls)
+	}
+	if r := store.get(follow); !r.published || r.message.Attempts != 2 {
+		t.Errorf("Got: published %v after %d attempts, Expected published after 2", r.published, r.message.Attempts)
+	}
+	if !store.get(like).published {
+		t.Error("Expected the event whose handler panicked to be published once it stops")
+	}
+}
+
+func TestDispatcherGivesUp(t *testing.T) {
+	store := &memoryStore{}
+	d := NewDispatcher(store)
+	calls := 0
+	d.Subscribe("chirp.created", func(ctx context.Context, data json.RawMessage) error {
+		calls++
+		return errors.New("poison event")
+	})
+	poison := store.addWithLimit("chirp.created", nil, 3)
+
+	for range 5 {
+		store.expire()
+		if err := d.Poll(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("Got: %d calls, Expected: 3", calls)
+	}
+	r := store.get(poison)
+	if !r.dead || r.published {
+		t.Errorf("Got: dead %v, published %v, Expected the event to be dead and unpublished", r.dead, r.published)
+	}
+	if r.lastError != "poison event" {
+		t.Errorf("Got: %q, Expected: %q", r.lastError, "poison event")
+	}
+}
+
+func TestDispatcherSharedStore(t *testing.T) {
+	store := &memoryStore{}
+	var mu sync.Mutex
+	delivered := map[uuid.UUID]int{}
+	var dispatchers []*Dispatcher
+	for range 4 {
+		d := NewDispatcher(store)
+		d.Subscribe("chirp.created", func(ctx context.Context, data json.RawMessage) error {
+			id := uuid.UUID{}
+			json.Unmarshal(data, &id)
+			mu.Lock()
+			delivered[id]++
+			mu.Unlock()
+			return nil
+		})
+		dispatchers = append(dispatchers, d)
+	}
+	for range 250 {
+		id := uuid.New()
+		store.add("chirp.created", id)
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range dispatchers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := d.Poll(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(delivered) != 250 {
+		t.Errorf("Got: %d events, Expected all 250 delivered", len(delivered))
+	}
+	for id, n := range delivered {
+		if n != 1 {
+			t.Errorf("Got: %d deliveries of %s, Expected 1 while leases hold", n, id)
+		}
+	}
+}
+
+func TestDispatcherBatchWithinLease(t *testing.T) {
+	store := &memoryStore{}
+	d := NewDispatcher(store)
+	// Every handler waits for the whole batch to start, which only happens
+	// if they run at once rather than one after another.
+	var started sync.WaitGroup
+	started.Add(batchSize)
+	d.Subscribe("chirp.created", func(ctx context.Context, data json.RawMessage) error {
+		started.Done()
+		started.Wait()
+		return nil
+	})
+	for range batchSize {
+		store.add("chirp.created", nil)
+	}
+
+	done := make(chan error)
+	go func() { done <- d.Poll(context.Background()) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the events in a batch to be delivered concurrently")
+	}
+	if lease < handlerTimeout {
+		t.Errorf("Got: lease %s, Expected it to outlast a batch taking %s", lease, handlerTimeout)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	cases := []struct {
+		attempts int32
+		expected time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{20, maxRetryDelay},
+		{1000, maxRetryDelay},
+	}
+	for _, c := range cases {
+		if got := retryDelay(c.attempts); got != c.expected {
+			t.Errorf("attempts %d: Got: %s, Expected: %s", c.attempts, got, c.expected)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package pubsub fans values out to in-process subscribers without letting a
+// slow subscriber hold up the publisher.
+package pubsub
+
+import "sync"
+
+// Hub delivers every published value to every current subscriber. It is
+// safe for concurrent use.
+type Hub[T any] struct {
+	mu   sync.Mutex
+	subs map[*Subscription[T]]struct{}
+}
+
+// Subscription receives values on C until it is closed, either by the
+// subscriber or by the hub when the subscriber falls behind.
+type Subscription[T any] struct {
+	C <-chan T
+
+	ch  chan T
+	hub *Hub[T]
+}
+
+func NewHub[T any]() *Hub[T] {
+	return &Hub[T]{subs: map[*Subscription[T]]struct{}{}}
+}
+
+// Subscribe registers a subscriber that can fall up to buffer values behind
+// before it is dropped.
+func (h *Hub[T]) Subscribe(buffer int) *Subscription[T] {
+	ch := make(chan T, buffer)
+	sub := &Subscription[T]{C: ch, ch: ch, hub: h}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// Publish hands v to every subscriber without blocking. Subscribers whose
+// buffer is full are dropped and their channel closed, so they can tell
+// they missed values and catch up some other way.
+func (h *Hub[T]) Publish(v T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		select {
+		case sub.ch <- v:
+		default:
+			delete(h.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Len returns the number of current subscribers.
+func (h *Hub[T]) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// Close unsubscribes. It is safe to call more than once and after the hub
+// has dropped the subscription.
+func (s *Subscription[T]) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if _, ok := s.hub.subs[s]; !ok {
+		return
+	}
+	delete(s.hub.subs, s)
+	close(s.ch)
+}
@@ -0,0 +1,39 @@
+package pubsub
+
+import "testing"
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub[int]()
+	fast := hub.Subscribe(4)
+	slow := hub.Subscribe(1)
+
+	hub.Publish(1)
+	hub.Publish(2)
+
+	if got := <-fast.C; got != 1 {
+		t.Errorf("Got: %d, Expected: 1", got)
+	}
+	if got := <-fast.C; got != 2 {
+		t.Errorf("Got: %d, Expected: 2", got)
+	}
+	if got := <-slow.C; got != 1 {
+		t.Errorf("Got: %d, Expected: 1", got)
+	}
+	if _, ok := <-slow.C; ok {
+		t.Errorf("Expected the slow subscriber to be dropped once its buffer was full")
+	}
+	if hub.Len() != 1 {
+		t.Errorf("Got %d subscribers, Expected 1", hub.Len())
+	}
+
+	slow.Close()
+	fast.Close()
+	fast.Close()
+	if _, ok := <-fast.C; ok {
+		t.Errorf("Expected a closed subscription's channel to be closed")
+	}
+	if hub.Len() != 0 {
+		t.Errorf("Got %d subscribers, Expected 0", hub.Len())
+	}
+	hub.Publish(3)
+}
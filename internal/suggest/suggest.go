@@ -0,0 +1,193 @@
+// Package suggest ranks accounts a user might want to follow and keeps the
+// rankings cached between requests.
+package suggest
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	mutualWeight   = 3.0
+	hashtagWeight  = 2.0
+	activityWeight = 1.0
+	// activityHalfLife is how long it takes a candidate's activity score to
+	// halve after their last chirp.
+	activityHalfLife = 72 * time.Hour
+)
+
+// Candidate holds the signals gathered for one account that could be
+// suggested.
+type Candidate struct {
+	UserID         uuid.UUID
+	MutualFollows  int64
+	SharedHashtags int
+	RecentChirps   int
+	LastChirpAt    time.Time
+}
+
+type Suggestion struct {
+	UserID         uuid.UUID `json:"user_id"`
+	Score          float64   `json:"score"`
+	MutualFollows  int64     `json:"mutual_follows"`
+	SharedHashtags int       `json:"shared_hashtags"`
+}
+
+// Score combines the signals with diminishing returns on each, so a single
+// very active account cannot drown out friends-of-friends overlap.
+func (c Candidate) Score(now time.Time) float64 {
+	score := mutualWeight*math.Log1p(float64(c.MutualFollows)) +
+		hashtagWeight*math.Log1p(float64(c.SharedHashtags))
+	if c.RecentChirps > 0 && !c.LastChirpAt.IsZero() {
+		age := now.Sub(c.LastChirpAt)
+		if age < 0 {
+			age = 0
+		}
+		decay := math.Exp2(-float64(age) / float64(activityHalfLife))
+		score += activityWeight * math.Log1p(float64(c.RecentChirps)) * decay
+	}
+	return score
+}
+
+// Rank scores the candidates and returns at most limit suggestions, best
+// first. Candidates with no signal at all are dropped.
+func Rank(candidates []Candidate, now time.Time, limit int) []Suggestion {
+	suggestions := []Suggestion{}
+	for _, candidate := range candidates {
+		score := candidate.Score(now)
+		if score <= 0 {
+			continue
+		}
+		suggestions = append(suggestions, Suggestion{
+			UserID:         candidate.UserID,
+			Score:          score,
+			MutualFollows:  candidate.MutualFollows,
+			SharedHashtags: candidate.SharedHashtags,
+		})
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].UserID.String() < suggestions[j].UserID.String()
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// ComputeFunc computes fresh suggestions for a user.
+type ComputeFunc func(ctx context.Context, userID uuid.UUID) ([]Suggestion, error)
+
+type entry struct {
+	suggestions []Suggestion
+	computedAt  time.Time
+	readAt      time.Time
+}
+
+// Cache serves per-user suggestions from memory. A miss computes them
+// synchronously; Run keeps entries that are still being read fresh in the
+// background and forgets the rest.
+type Cache struct {
+	compute ComputeFunc
+	// TTL is how old an entry may get before Run recomputes it.
+	TTL time.Duration
+	// Idle is how long an entry may go unread before Run evicts it.
+	Idle time.Duration
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]entry
+	now     func() time.Time
+}
+
+func NewCache(compute ComputeFunc, ttl, idle time.Duration) *Cache {
+	return &Cache{
+		compute: compute,
+		TTL:     ttl,
+		Idle:    idle,
+		entries: map[uuid.UUID]entry{},
+		now:     time.Now,
+	}
+}
+
+// Get returns the cached suggestions for the user, computing them on a miss.
+func (c *Cache) Get(ctx context.Context, userID uuid.UUID) ([]Suggestion, error) {
+	c.mu.Lock()
+	cached, ok := c.entries[userID]
+	if ok {
+		cached.readAt = c.now()
+		c.entries[userID] = cached
+	}
+	c.mu.Unlock()
+	if ok {
+		return cached.suggestions, nil
+	}
+	suggestions, err := c.compute(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := c.now()
+	c.mu.Lock()
+	c.entries[userID] = entry{suggestions: suggestions, computedAt: now, readAt: now}
+	c.mu.Unlock()
+	return suggestions, nil
+}
+
+// Invalidate drops the user's entry so the next Get recomputes it.
+func (c *Cache) Invalidate(userID uuid.UUID) {
+	c.mu.Lock()
+	delete(c.entries, userID)
+	c.mu.Unlock()
+}
+
+// Refresh evicts idle entries and recomputes stale ones. Failed refreshes
+// keep serving the previous suggestions.
+func (c *Cache) Refresh(ctx context.Context) {
+	now := c.now()
+	stale := []uuid.UUID{}
+	c.mu.Lock()
+	for userID, cached := range c.entries {
+		switch {
+		case now.Sub(cached.readAt) > c.Idle:
+			delete(c.entries, userID)
+		case now.Sub(cached.computedAt) > c.TTL:
+			stale = append(stale, userID)
+		}
+	}
+	c.mu.Unlock()
+	for _, userID := range stale {
+		suggestions, err := c.compute(ctx, userID)
+		if err != nil {
+			log.Printf("Error refreshing suggestions for %s: %s", userID, err)
+			continue
+		}
+		c.mu.Lock()
+		if cached, ok := c.entries[userID]; ok {
+			cached.suggestions = suggestions
+			cached.computedAt = c.now()
+			c.entries[userID] = cached
+		}
+		c.mu.Unlock()
+	}
+}
+
+// Run calls Refresh every interval until ctx is done.
+func (c *Cache) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Refresh(ctx)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package suggest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRank(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	friendOfFriends := uuid.New()
+	sharesTags := uuid.New()
+	activeStranger := uuid.New()
+	staleStranger := uuid.New()
+	nobody := uuid.New()
+	candidates := []Candidate{
+		{UserID: nobody},
+		{UserID: staleStranger, RecentChirps: 5, LastChirpAt: now.Add(-30 * 24 * time.Hour)},
+		{UserID: activeStranger, RecentChirps: 5, LastChirpAt: now.Add(-time.Hour)},
+		{UserID: sharesTags, SharedHashtags: 3},
+		{UserID: friendOfFriends, MutualFollows: 4},
+	}
+
+	ranked := Rank(candidates, now, 10)
+	want := []uuid.UUID{friendOfFriends, sharesTags, activeStranger, staleStranger}
+	if len(ranked) != len(want) {
+		t.Fatalf("Got %d suggestions, Expected %d: %v", len(ranked), len(want), ranked)
+	}
+	for i, userID := range want {
+		if ranked[i].UserID != userID {
+			t.Errorf("Case %d: Got: %s, Expected: %s", i, ranked[i].UserID, userID)
+		}
+	}
+
+	if limited := Rank(candidates, now, 2); len(limited) != 2 {
+		t.Errorf("Got %d suggestions, Expected the limit of 2", len(limited))
+	}
+}
+
+func TestCacheRefresh(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	cache := NewCache(func(ctx context.Context, userID uuid.UUID) ([]Suggestion, error) {
+		calls++
+		return []Suggestion{{UserID: userID, Score: float64(calls)}}, nil
+	}, time.Hour, 24*time.Hour)
+	cache.now = func() time.Time { return now }
+	userID := uuid.New()
+
+	first, _ := cache.Get(context.Background(), userID)
+	second, _ := cache.Get(context.Background(), userID)
+	if calls != 1 || first[0].Score != second[0].Score {
+		t.Fatalf("Expected the second Get to be served from cache, got %d computations", calls)
+	}
+
+	now = now.Add(2 * time.Hour)
+	cache.Refresh(context.Background())
+	refreshed, _ := cache.Get(context.Background(), userID)
+	if calls != 2 || refreshed[0].Score != 2 {
+		t.Errorf("Expected a stale entry to be recomputed, got %d computations", calls)
+	}
+
+	now = now.Add(48 * time.Hour)
+	cache.Refresh(context.Background())
+	if calls != 2 {
+		t.Errorf("Expected an idle entry to be evicted without recomputing, got %d computations", calls)
+	}
+	cache.Get(context.Background(), userID)
+	if calls != 3 {
+		t.Errorf("Expected a Get after eviction to recompute, got %d computations", calls)
+	}
+}
@@ -0,0 +1,39 @@
+// Package tags pulls hashtags and @mentions out of chirp bodies.
+package tags
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	hashtagRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&])#([\p{L}\p{N}_]+)`)
+	mentionRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])@([A-Za-z0-9_]+)`)
+)
+
+// Hashtags returns the distinct hashtags in txt, lower-cased and without the
+// leading '#', in the order they first appear.
+func Hashtags(txt string) []string {
+	return distinctMatches(hashtagRe, txt)
+}
+
+// Mentions returns the distinct @handles mentioned in txt, lower-cased and
+// without the leading '@', in the order they first appear. Email addresses
+// are not mentions.
+func Mentions(txt string) []string {
+	return distinctMatches(mentionRe, txt)
+}
+
+func distinctMatches(re *regexp.Regexp, txt string) []string {
+	seen := map[string]bool{}
+	matches := []string{}
+	for _, match := range re.FindAllStringSubmatch(txt, -1) {
+		value := strings.ToLower(match[1])
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		matches = append(matches, value)
+	}
+	return matches
+}
@@ -0,0 +1,43 @@
+package tags
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	cases := map[string][]string{
+		"no tags here":                   {},
+		"#Go is fun":                     {"go"},
+		"loving #golang and #GoLang":     {"golang"},
+		"mid#word and a&#38; entity":     {},
+		"(#first) #second_tag, #3rd!":    {"first", "second_tag", "3rd"},
+		"unicode #café works":            {"café"},
+		"trailing hash # is not a tag":   {},
+		"email me at bob@example.com #x": {"x"},
+	}
+	for input, want := range cases {
+		got := Hashtags(input)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: Got: %v, Expected: %v", input, got, want)
+		}
+	}
+}
+
+func TestMentions(t *testing.T) {
+	cases := map[string][]string{
+		"no mentions here":            {},
+		"hey @Alice":                  {"alice"},
+		"@bob and @BOB again":         {"bob"},
+		"(@first), @second_one!":      {"first", "second_one"},
+		"email me at bob@example.com": {},
+		"a lone @ sign":               {},
+		"cc @carol #tag":              {"carol"},
+	}
+	for input, want := range cases {
+		got := Mentions(input)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: Got: %v, Expected: %v", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Package trends scores hashtags by how far their recent usage rises above
+// their usual level and keeps a ranked snapshot up to date in the background.
+package trends
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/tags"
+	"github.com/google/uuid"
+)
+
+// Config tunes how tags are scored.
+type Config struct {
+	// HalfLife is how long it takes a mention to count half as much towards
+	// a tag's recent rate.
+	HalfLife time.Duration
+	// BaselineHalfLife is the same for the tag's baseline rate. It should be
+	// much longer than HalfLife so that the baseline reflects the tag's
+	// usual level rather than the current spike.
+	BaselineHalfLife time.Duration
+	// Window is how far back mentions count as recent. Only tags mentioned
+	// within it are ranked.
+	Window time.Duration
+	// MinAuthors is how many distinct accounts must have used a tag within
+	// Window before it can trend, so one account cannot push a tag alone.
+	MinAuthors int
+	// Prior is added to every baseline, in mentions per hour, so that tags
+	// with almost no history don't get unbounded scores.
+	Prior float64
+}
+
+type Trend struct {
+	Tag     string  `json:"tag"`
+	Score   float64 `json:"score"`
+	Authors int     `json:"authors"`
+}
+
+// counter is an exponentially decayed mention count as of updatedAt.
+type counter struct {
+	value     float64
+	updatedAt time.Time
+}
+
+func (c counter) at(now time.Time, halfLife time.Duration) float64 {
+	age := now.Sub(c.updatedAt)
+	if age <= 0 {
+		return c.value
+	}
+	return c.value * math.Exp2(-float64(age)/float64(halfLife))
+}
+
+func (c *counter) add(at time.Time, halfLife time.Duration) {
+	if at.After(c.updatedAt) {
+		c.value = c.at(at, halfLife)
+		c.updatedAt = at
+	}
+	c.value += math.Exp2(-float64(c.updatedAt.Sub(at)) / float64(halfLife))
+}
+
+// rate converts a decayed count into mentions per hour. A tag mentioned at
+// a steady r per hour settles at a decayed count of r*halfLife/ln2.
+func rate(count float64, halfLife time.Duration) float64 {
+	return count * math.Ln2 / halfLife.Hours()
+}
+
+type tagStats struct {
+	recent   counter
+	baseline counter
+	// authors maps each account that used the tag to when they last did.
+	authors map[uuid.UUID]time.Time
+}
+
+// Tracker accumulates hashtag mentions. It is safe for concurrent use.
+type Tracker struct {
+	cfg Config
+
+	mu   sync.Mutex
+	tags map[string]*tagStats
+}
+
+func NewTracker(cfg Config) *Tracker {
+	return &Tracker{cfg: cfg, tags: map[string]*tagStats{}}
+}
+
+// Add records a mention of tag by authorID at the given time.
+func (t *Tracker) Add(tag string, authorID uuid.UUID, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats, ok := t.tags[tag]
+	if !ok {
+		stats = &tagStats{authors: map[uuid.UUID]time.Time{}}
+		t.tags[tag] = stats
+	}
+	stats.recent.add(at, t.cfg.HalfLife)
+	stats.baseline.add(at, t.cfg.BaselineHalfLife)
+	if at.After(stats.authors[authorID]) {
+		stats.authors[authorID] = at
+	}
+}
+
+// Top scores every tag mentioned within the window as its recent rate over
+// its baseline rate plus the prior, and returns at most limit of them, best
+// first. A tag used at a steady pace scores close to 1 however popular it
+// is; a tag that is suddenly being used more scores higher.
+func (t *Tracker) Top(now time.Time, limit int) []Trend {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	trends := []Trend{}
+	for tag, stats := range t.tags {
+		authors := 0
+		for _, lastUsed := range stats.authors {
+			if now.Sub(lastUsed) <= t.cfg.Window {
+				authors++
+			}
+		}
+		if authors == 0 || authors < t.cfg.MinAuthors {
+			continue
+		}
+		recent := rate(stats.recent.at(now, t.cfg.HalfLife), t.cfg.HalfLife)
+		baseline := rate(stats.baseline.at(now, t.cfg.BaselineHalfLife), t.cfg.BaselineHalfLife)
+		trends = append(trends, Trend{
+			Tag:     tag,
+			Score:   recent / (baseline + t.cfg.Prior),
+			Authors: authors,
+		})
+	}
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Score != trends[j].Score {
+			return trends[i].Score > trends[j].Score
+		}
+		return trends[i].Tag < trends[j].Tag
+	})
+	if len(trends) > limit {
+		trends = trends[:limit]
+	}
+	return trends
+}
+
+// Prune forgets authors who haven't used a tag within the window and tags
+// whose baseline has decayed to almost nothing, so memory stays bounded by
+// what is still relevant.
+func (t *Tracker) Prune(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for tag, stats := range t.tags {
+		for authorID, lastUsed := range stats.authors {
+			if now.Sub(lastUsed) > t.cfg.Window {
+				delete(stats.authors, authorID)
+			}
+		}
+		if len(stats.authors) == 0 && stats.baseline.at(now, t.cfg.BaselineHalfLife) < 0.01 {
+			delete(t.tags, tag)
+		}
+	}
+}
+
+// Post is a published chirp as seen by the aggregator.
+type Post struct {
+	ID        uuid.UUID
+	AuthorID  uuid.UUID
+	Body      string
+	CreatedAt time.Time
+}
+
+// FetchFunc returns up to limit posts created after (afterTime, afterID) and
+// before until, oldest first.
+type FetchFunc func(ctx context.Context, afterTime time.Time, afterID uuid.UUID, until time.Time, limit int32) ([]Post, error)
+
+const (
+	fetchBatchSize = 500
+	// settleDelay keeps the aggregator behind the newest chirps so that a
+	// chirp whose transaction commits late is not skipped past.
+	settleDelay  = 5 * time.Second
+	snapshotSize = 50
+)
+
+// Aggregator feeds new chirps into a Tracker and serves the latest ranking.
+// Each poll only reads chirps created since the previous one.
+type Aggregator struct {
+	fetch   FetchFunc
+	tracker *Tracker
+
+	afterTime time.Time
+	afterID   uuid.UUID
+
+	mu       sync.RWMutex
+	snapshot []Trend
+	now      func() time.Time
+}
+
+// NewAggregator starts reading from warmUp ago, so the first poll rebuilds
+// the baselines that were lost when the process last stopped.
+func NewAggregator(fetch FetchFunc, cfg Config, warmUp time.Duration) *Aggregator {
+	return &Aggregator{
+		fetch:     fetch,
+		tracker:   NewTracker(cfg),
+		afterTime: time.Now().UTC().Add(-warmUp),
+		snapshot:  []Trend{},
+		now:       time.Now,
+	}
+}
+
+// Poll reads every chirp created since the last poll, then recomputes the
+// ranking. Poll must not be called concurrently with itself.
+func (a *Aggregator) Poll(ctx context.Context) error {
+	until := a.now().UTC().Add(-settleDelay)
+	for {
+		posts, err := a.fetch(ctx, a.afterTime, a.afterID, until, fetchBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, post := range posts {
+			for _, tag := range tags.Hashtags(post.Body) {
+				a.tracker.Add(tag, post.AuthorID, post.CreatedAt)
+			}
+			a.afterTime = post.CreatedAt
+			a.afterID = post.ID
+		}
+		if len(posts) < fetchBatchSize {
+			break
+		}
+	}
+	now := a.now()
+	a.tracker.Prune(now)
+	snapshot := a.tracker.Top(now, snapshotSize)
+	a.mu.Lock()
+	a.snapshot = snapshot
+	a.mu.Unlock()
+	return nil
+}
+
+// Trends returns at most limit tags from the latest snapshot.
+func (a *Aggregator) Trends(limit int) []Trend {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if len(a.snapshot) < limit {
+		limit = len(a.snapshot)
+	}
+	return a.snapshot[:limit]
+}
+
+// Run polls immediately and then every interval until ctx is done.
+func (a *Aggregator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := a.Poll(ctx)
+		if err != nil {
+			log.Printf("Error aggregating trends: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package trends
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var testConfig = Config{
+	HalfLife:         2 * time.Hour,
+	BaselineHalfLife: 3 * 24 * time.Hour,
+	Window:           24 * time.Hour,
+	MinAuthors:       3,
+	Prior:            1,
+}
+
+func TestTrackerTop(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	tracker := NewTracker(testConfig)
+	authors := make([]uuid.UUID, 20)
+	for i := range authors {
+		authors[i] = uuid.New()
+	}
+
+	// #news is mentioned 20 times an hour, every hour, for a week.
+	for h := 7 * 24; h > 0; h-- {
+		for i := 0; i < 20; i++ {
+			tracker.Add("news", authors[i], now.Add(-time.Duration(h)*time.Hour+time.Duration(i)*time.Minute))
+		}
+	}
+	// #eclipse has never been used and gets 15 mentions in the last hour.
+	for i := 0; i < 15; i++ {
+		tracker.Add("eclipse", authors[i], now.Add(-time.Duration(i)*time.Minute))
+	}
+	// #mine is pushed by a single account.
+	for i := 0; i < 50; i++ {
+		tracker.Add("mine", authors[0], now.Add(-time.Duration(i)*time.Minute))
+	}
+	// #yesterday spiked two days ago.
+	for i := 0; i < 15; i++ {
+		tracker.Add("yesterday", authors[i], now.Add(-48*time.Hour))
+	}
+
+	top := tracker.Top(now, 10)
+	if len(top) != 2 {
+		t.Fatalf("Got %d trends, Expected 2: %v", len(top), top)
+	}
+	if top[0].Tag != "eclipse" || top[1].Tag != "news" {
+		t.Errorf("Got: %v, Expected eclipse ahead of news", top)
+	}
+	if top[1].Score < 0.5 || top[1].Score > 1.5 {
+		t.Errorf("Expected a steady tag to score about 1, got %f", top[1].Score)
+	}
+	if limited := tracker.Top(now, 1); len(limited) != 1 {
+		t.Errorf("Got %d trends, Expected the limit of 1", len(limited))
+	}
+
+	tracker.Prune(now.Add(60 * 24 * time.Hour))
+	if len(tracker.tags) != 0 {
+		t.Errorf("Expected prune to forget stale tags, %d left", len(tracker.tags))
+	}
+}
+
+func TestAggregatorPoll(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	posts := []Post{}
+	for i := 0; i < fetchBatchSize+10; i++ {
+		posts = append(posts, Post{
+			ID:        uuid.New(),
+			AuthorID:  uuid.New(),
+			Body:      fmt.Sprintf("post %d about #launch", i),
+			CreatedAt: now.Add(-time.Hour + time.Duration(i)*time.Second),
+		})
+	}
+	fetched := 0
+	fetch := func(ctx context.Context, afterTime time.Time, afterID uuid.UUID, until time.Time, limit int32) ([]Post, error) {
+		batch := []Post{}
+		for _, post := range posts {
+			if post.CreatedAt.After(afterTime) && post.CreatedAt.Before(until) && len(batch) < int(limit) {
+				batch = append(batch, post)
+			}
+		}
+		fetched += len(batch)
+		return batch, nil
+	}
+	aggregator := NewAggregator(fetch, testConfig, 0)
+	aggregator.afterTime = now.Add(-2 * time.Hour)
+	aggregator.now = func() time.Time { return now }
+
+	if err := aggregator.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fetched != len(posts) {
+		t.Errorf("Got %d posts fetched, Expected %d", fetched, len(posts))
+	}
+	trends := aggregator.Trends(10)
+	if len(trends) != 1 || trends[0].Tag != "launch" {
+		t.Fatalf("Got: %v, Expected #launch to trend", trends)
+	}
+
+	if err := aggregator.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fetched != len(posts) {
+		t.Errorf("Expected the second poll to read nothing new, fetched %d in total", fetched)
+	}
+}
@@ -0,0 +1,115 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	body := []byte(`{"event":"reply"}`)
+	header := Sign("secret", now, body)
+
+	if err := Verify("secret", header, body, now.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Errorf("Expected a fresh signature to verify, got %s", err)
+	}
+	if err := Verify("other", header, body, now, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Got: %v, Expected the wrong secret to fail", err)
+	}
+	if err := Verify("secret", header, []byte(`{"event":"like"}`), now, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Got: %v, Expected a tampered body to fail", err)
+	}
+	if err := Verify("secret", header, body, now.Add(time.Hour), 5*time.Minute); !errors.Is(err, ErrSignatureExpired) {
+		t.Errorf("Got: %v, Expected a replayed signature to fail", err)
+	}
+	if err := Verify("secret", "garbage", body, now, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Got: %v, Expected a malformed header to fail", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		4:  4 * time.Minute,
+		20: 6 * time.Hour,
+	}
+	for attempts, base := range cases {
+		got := Backoff(attempts)
+		if got < base || got > base+base/5 {
+			t.Errorf("Attempt %d: Got: %s, Expected between %s and %s", attempts, got, base, base+base/5)
+		}
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"192.168.0.10":    false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"::1":             false,
+		"::ffff:10.0.0.1": false,
+		"0.0.0.0":         false,
+	}
+	for addr, want := range cases {
+		if got := isPublic(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("%s: Got: %v, Expected: %v", addr, got, want)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	var got *http.Request
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(503)
+		case "/redirect":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		default:
+			w.WriteHeader(204)
+		}
+	}))
+	defer server.Close()
+	sender := NewSender(5*time.Second, true)
+	deliveryID := uuid.New()
+	body := []byte(`{"event":"follow"}`)
+
+	result := sender.Send(context.Background(), server.URL+"/ok", "secret", deliveryID, "follow", body)
+	if !result.OK() || result.StatusCode != 204 {
+		t.Fatalf("Got: %+v, Expected a successful delivery", result)
+	}
+	if got.Header.Get(EventHeader) != "follow" || got.Header.Get(DeliveryHeader) != deliveryID.String() {
+		t.Errorf("Got headers %v, Expected the event and delivery ID", got.Header)
+	}
+	if err := Verify("secret", got.Header.Get(SignatureHeader), gotBody, time.Now(), time.Minute); err != nil {
+		t.Errorf("Expected the receiver to verify the signature, got %s", err)
+	}
+
+	if result := sender.Send(context.Background(), server.URL+"/fail", "secret", deliveryID, "follow", body); result.OK() || result.StatusCode != 503 {
+		t.Errorf("Got: %+v, Expected a 503 to count as a failure", result)
+	}
+	if result := sender.Send(context.Background(), server.URL+"/redirect", "secret", deliveryID, "follow", body); result.OK() || result.StatusCode != 302 {
+		t.Errorf("Got: %+v, Expected redirects not to be followed", result)
+	}
+
+	strict := NewSender(5*time.Second, false)
+	if result := strict.Send(context.Background(), server.URL+"/ok", "secret", deliveryID, "follow", body); !errors.Is(result.Err, errPrivateAddress) {
+		t.Errorf("Got: %+v, Expected loopback to be refused", result)
+	}
+}
@@ -0,0 +1,500 @@
+// Package websocket implements the server and client sides of RFC 6455
+// that the gateway needs: the opening handshake, framing with masking and
+// fragmentation, and the ping, pong and close control frames. Extensions
+// and subprotocols are not supported.
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Message types, which are the frame opcodes.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// Close codes from RFC 6455 section 7.4.1.
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+	CloseTryAgainLater   = 1013
+)
+
+const (
+	acceptGUID         = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	maxControlPayload  = 125
+	defaultReadLimit   = 64 * 1024
+	closeFrameDeadline = time.Second
+)
+
+var (
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+	ErrBadOrigin    = errors.New("websocket: request origin not allowed")
+	ErrCloseSent    = errors.New("websocket: close frame already sent")
+)
+
+// CloseError is returned by ReadMessage once the connection is closed,
+// either by the peer or because the peer broke the protocol.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHasToken reports whether a comma-separated header contains token,
+// ignoring case.
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameOrigin reports whether a browser made the request from a page served
+// by this host. Browsers always send Origin on WebSocket requests, and
+// other clients are not exposed to cross-site requests, so a request
+// without one is allowed.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// Upgrade completes the opening handshake and takes over the request's
+// connection. On failure it has already answered the request. Requests
+// from pages on other origins are refused, since the same-origin policy
+// does not apply to WebSockets.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet ||
+		!headerHasToken(r.Header, "Connection", "upgrade") ||
+		!headerHasToken(r.Header, "Upgrade", "websocket") {
+		w.WriteHeader(400)
+		w.Write([]byte("Expected a WebSocket upgrade"))
+		return nil, ErrBadHandshake
+	}
+	if !sameOrigin(r) {
+		w.WriteHeader(403)
+		w.Write([]byte("Cross-origin WebSocket requests are not allowed"))
+		return nil, ErrBadOrigin
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		w.WriteHeader(426)
+		w.Write([]byte("Unsupported WebSocket version"))
+		return nil, ErrBadHandshake
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		w.WriteHeader(400)
+		w.Write([]byte("Invalid Sec-WebSocket-Key"))
+		return nil, ErrBadHandshake
+	}
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to upgrade connection"))
+		return nil, err
+	}
+	// The server's own deadlines were meant for the HTTP exchange.
+	netConn.SetDeadline(time.Time{})
+	_, err = fmt.Fprintf(brw.Writer, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err == nil {
+		err = brw.Writer.Flush()
+	}
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return newConn(netConn, brw.Reader, true), nil
+}
+
+// Dial opens a client connection to a ws:// or wss:// URL. If the server
+// refuses the upgrade, the error is ErrBadHandshake and the response is
+// returned so the caller can see why.
+func Dial(ctx context.Context, rawURL string, header http.Header) (*Conn, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	host := u.Host
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+	case "wss":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+	default:
+		return nil, nil, fmt.Errorf("websocket: unsupported scheme %q", u.Scheme)
+	}
+	var netConn net.Conn
+	if u.Scheme == "wss" {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname()}}
+		netConn, err = dialer.DialContext(ctx, "tcp", host)
+	} else {
+		netConn, err = (&net.Dialer{}).DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		netConn.SetDeadline(deadline)
+	}
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Host:       u.Host,
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	err = req.Write(netConn)
+	if err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!headerHasToken(resp.Header, "Upgrade", "websocket") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		netConn.Close()
+		return nil, resp, ErrBadHandshake
+	}
+	netConn.SetDeadline(time.Time{})
+	return newConn(netConn, br, false), resp, nil
+}
+
+// Conn is a WebSocket connection. One goroutine may read while others
+// write; writes are serialized.
+type Conn struct {
+	conn   net.Conn
+	br     *bufio.Reader
+	server bool
+
+	readLimit   int64
+	pongHandler func(data string)
+
+	wmu          sync.Mutex
+	writeTimeout time.Duration
+	closeSent    bool
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, server bool) *Conn {
+	return &Conn{
+		conn:      conn,
+		br:        br,
+		server:    server,
+		readLimit: defaultReadLimit,
+	}
+}
+
+// SetReadLimit caps the size of a message, across all of its fragments.
+// Larger messages close the connection with CloseMessageTooBig.
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteTimeout bounds every write, so a peer that stops reading cannot
+// block its writers forever.
+func (c *Conn) SetWriteTimeout(timeout time.Duration) {
+	c.wmu.Lock()
+	c.writeTimeout = timeout
+	c.wmu.Unlock()
+}
+
+// SetPongHandler is called from ReadMessage for every pong received.
+func (c *Conn) SetPongHandler(handler func(data string)) {
+	c.pongHandler = handler
+}
+
+// Close closes the underlying connection without a closing handshake.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// WriteMessage sends data as a single unfragmented frame.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case PingMessage, PongMessage:
+		if len(data) > maxControlPayload {
+			return fmt.Errorf("websocket: control frame payload over %d bytes", maxControlPayload)
+		}
+	default:
+		return fmt.Errorf("websocket: unsupported message type %d", messageType)
+	}
+	return c.writeFrame(true, messageType, data)
+}
+
+// WriteClose starts the closing handshake. Nothing but control frames
+// already queued can follow it.
+func (c *Conn) WriteClose(code int, text string) error {
+	payload := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, text...)
+	if len(payload) > maxControlPayload {
+		payload = payload[:maxControlPayload]
+	}
+	return c.writeFrame(true, CloseMessage, payload)
+}
+
+func (c *Conn) writeFrame(fin bool, opcode int, data []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+
+	header := make([]byte, 2, 14)
+	header[0] = byte(opcode)
+	if fin {
+		header[0] |= 0x80
+	}
+	switch {
+	case len(data) <= 125:
+		header[1] = byte(len(data))
+	case len(data) <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(len(data)))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(len(data)))
+	}
+	payload := data
+	if !c.server {
+		// Clients must mask every frame (section 5.3).
+		header[1] |= 0x80
+		key := make([]byte, 4)
+		rand.Read(key)
+		header = append(header, key...)
+		payload = make([]byte, len(data))
+		for i := range data {
+			payload[i] = data[i] ^ key[i%4]
+		}
+	}
+
+	deadline := time.Time{}
+	if opcode == CloseMessage {
+		deadline = time.Now().Add(closeFrameDeadline)
+	} else if c.writeTimeout > 0 {
+		deadline = time.Now().Add(c.writeTimeout)
+	}
+	c.conn.SetWriteDeadline(deadline)
+	_, err := c.conn.Write(append(header, payload...))
+	return err
+}
+
+// fail closes the connection because the peer broke the protocol and
+// returns the error ReadMessage reports.
+func (c *Conn) fail(code int, text string) error {
+	c.WriteClose(code, text)
+	return &CloseError{Code: code, Text: text}
+}
+
+// ReadMessage returns the next text or binary message, reassembling
+// fragments. Pings are answered and pongs passed to the pong handler along
+// the way. Once the peer closes, or breaks the protocol, the error is a
+// *CloseError.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	messageType := 0
+	var message []byte
+	for {
+		var head [2]byte
+		_, err := io.ReadFull(c.br, head[:])
+		if err != nil {
+			return 0, nil, err
+		}
+		fin := head[0]&0x80 != 0
+		opcode := int(head[0] & 0x0f)
+		masked := head[1]&0x80 != 0
+		if head[0]&0x70 != 0 {
+			return 0, nil, c.fail(CloseProtocolError, "reserved bits set")
+		}
+		if masked != c.server {
+			return 0, nil, c.fail(CloseProtocolError, "wrong masking")
+		}
+
+		length := int64(head[1] & 0x7f)
+		switch length {
+		case 126:
+			var ext [2]byte
+			_, err = io.ReadFull(c.br, ext[:])
+			length = int64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			_, err = io.ReadFull(c.br, ext[:])
+			n := binary.BigEndian.Uint64(ext[:])
+			if n>>63 != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "invalid length")
+			}
+			length = int64(n)
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+
+		control := opcode >= CloseMessage
+		switch {
+		case control && (!fin || length > maxControlPayload):
+			return 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+		case opcode == continuationFrame && messageType == 0:
+			return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+		case (opcode == TextMessage || opcode == BinaryMessage) && messageType != 0:
+			return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+		case opcode > BinaryMessage && !control, opcode > PongMessage:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		case !control && int64(len(message))+length > c.readLimit:
+			return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+
+		var key [4]byte
+		if masked {
+			_, err = io.ReadFull(c.br, key[:])
+			if err != nil {
+				return 0, nil, err
+			}
+		}
+		payload := make([]byte, length)
+		_, err = io.ReadFull(c.br, payload)
+		if err != nil {
+			return 0, nil, err
+		}
+		if masked {
+			for i := range payload {
+				payload[i] ^= key[i%4]
+			}
+		}
+
+		switch opcode {
+		case PingMessage:
+			err = c.writeFrame(true, PongMessage, payload)
+			if err != nil && !errors.Is(err, ErrCloseSent) {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if c.pongHandler != nil {
+				c.pongHandler(string(payload))
+			}
+			continue
+		case CloseMessage:
+			closeErr := &CloseError{Code: CloseNoStatus}
+			switch {
+			case len(payload) == 1:
+				return 0, nil, c.fail(CloseProtocolError, "invalid close payload")
+			case len(payload) >= 2:
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Text = string(payload[2:])
+				if !validCloseCode(closeErr.Code) {
+					return 0, nil, c.fail(CloseProtocolError, "invalid close code")
+				}
+				if !utf8.ValidString(closeErr.Text) {
+					return 0, nil, c.fail(CloseInvalidPayload, "invalid UTF-8")
+				}
+			}
+			// Echo the close unless we started the handshake.
+			if closeErr.Code == CloseNoStatus {
+				c.writeFrame(true, CloseMessage, nil)
+			} else {
+				c.WriteClose(closeErr.Code, "")
+			}
+			return 0, nil, closeErr
+		case TextMessage, BinaryMessage:
+			messageType = opcode
+		}
+		message = append(message, payload...)
+		if !fin {
+			continue
+		}
+		if messageType == TextMessage && !utf8.Valid(message) {
+			return 0, nil, c.fail(CloseInvalidPayload, "invalid UTF-8")
+		}
+		return messageType, message, nil
+	}
+}
+
+// validCloseCode reports whether a peer may send code in a close frame
+// (section 7.4). 1005, 1006 and 1015 are only for reporting locally, and
+// the rest of 1000-2999 is unassigned.
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code >= CloseNormal && code <= 1014:
+		return code != 1004 && code != CloseNoStatus && code != 1006
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoServer echoes every message back until the client closes.
+func echoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetReadLimit(1024)
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if conn.WriteMessage(messageType, data) != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func dial(t *testing.T, server *httptest.Server) *Conn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestEcho(t *testing.T) {
+	conn := dial(t, echoServer(t))
+	for _, body := range []string{"hello", strings.Repeat("x", 300), ""} {
+		if err := conn.WriteMessage(TextMessage, []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if messageType != TextMessage || string(data) != body {
+			t.Errorf("Got: %d %q, Expected: %d %q", messageType, data, TextMessage, body)
+		}
+	}
+}
+
+func TestFragmentsAndPing(t *testing.T) {
+	conn := dial(t, echoServer(t))
+	pongs := []string{}
+	conn.SetPongHandler(func(data string) { pongs = append(pongs, data) })
+
+	// A ping may arrive between the fragments of a message.
+	conn.writeFrame(false, TextMessage, []byte("frag"))
+	conn.WriteMessage(PingMessage, []byte("are you there"))
+	conn.writeFrame(true, continuationFrame, []byte("mented"))
+
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "fragmented" {
+		t.Errorf("Got: %q, Expected: %q", data, "fragmented")
+	}
+	if len(pongs) != 1 || pongs[0] != "are you there" {
+		t.Errorf("Got pongs %v, Expected the ping payload echoed once", pongs)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	cases := map[string]struct {
+		send func(conn *Conn)
+		code int
+	}{
+		"too big": {
+			send: func(conn *Conn) { conn.WriteMessage(BinaryMessage, make([]byte, 2048)) },
+			code: CloseMessageTooBig,
+		},
+		"invalid utf-8": {
+			send: func(conn *Conn) { conn.WriteMessage(TextMessage, []byte{0xff, 0xfe}) },
+			code: CloseInvalidPayload,
+		},
+		"stray continuation": {
+			send: func(conn *Conn) { conn.writeFrame(true, continuationFrame, []byte("x")) },
+			code: CloseProtocolError,
+		},
+		"too big across fragments": {
+			send: func(conn *Conn) {
+				conn.writeFrame(false, BinaryMessage, make([]byte, 600))
+				conn.writeFrame(true, continuationFrame, make([]byte, 600))
+			},
+			code: CloseMessageTooBig,
+		},
+		"huge length header": {
+			// Refused from the header alone, before any payload is read.
+			send: func(conn *Conn) {
+				conn.conn.Write([]byte{0x82, 0xff, 0, 0, 1, 0, 0, 0, 0, 0, 1, 2, 3, 4})
+			},
+			code: CloseMessageTooBig,
+		},
+		"invalid length": {
+			send: func(conn *Conn) {
+				conn.conn.Write([]byte{0x82, 0xff, 0x80, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4})
+			},
+			code: CloseProtocolError,
+		},
+		"control frame too big": {
+			send: func(conn *Conn) { conn.writeFrame(true, PingMessage, make([]byte, maxControlPayload+1)) },
+			code: CloseProtocolError,
+		},
+		"unmasked": {
+			send: func(conn *Conn) { conn.conn.Write([]byte{0x81, 0x01, 'x'}) },
+			code: CloseProtocolError,
+		},
+		"unmasked continuation": {
+			send: func(conn *Conn) {
+				conn.writeFrame(false, TextMessage, []byte("x"))
+				conn.conn.Write([]byte{0x80, 0x01, 'y'})
+			},
+			code: CloseProtocolError,
+		},
+		"fragmented ping": {
+			send: func(conn *Conn) {
+				conn.writeFrame(false, PingMessage, []byte("are"))
+				conn.writeFrame(true, continuationFrame, []byte("you there"))
+			},
+			code: CloseProtocolError,
+		},
+		"fragmented close": {
+			send: func(conn *Conn) { conn.writeFrame(false, CloseMessage, []byte{0x03, 0xe8}) },
+			code: CloseProtocolError,
+		},
+		"reserved bits": {
+			send: func(conn *Conn) { conn.writeFrame(true, TextMessage|0x40, []byte("x")) },
+			code: CloseProtocolError,
+		},
+		"unknown opcode": {
+			send: func(conn *Conn) { conn.writeFrame(true, 3, []byte("x")) },
+			code: CloseProtocolError,
+		},
+	}
+	server := echoServer(t)
+	for name, c := range cases {
+		conn := dial(t, server)
+		c.send(conn)
+		_, _, err := conn.ReadMessage()
+		var closeErr *CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != c.code {
+			t.Errorf("%s: Got: %v, Expected close code %d", name, err, c.code)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	conn := dial(t, echoServer(t))
+	if err := conn.WriteClose(CloseNormal, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := conn.ReadMessage()
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseNormal {
+		t.Errorf("Got: %v, Expected the server to echo the close", err)
+	}
+	if err := conn.WriteMessage(TextMessage, []byte("late")); !errors.Is(err, ErrCloseSent) {
+		t.Errorf("Got: %v, Expected writes after close to fail", err)
+	}
+}
+
+func TestBadCloseFrames(t *testing.T) {
+	cases := map[string]struct {
+		payload []byte
+		code    int
+	}{
+		"one byte payload": {[]byte{0x03}, CloseProtocolError},
+		"below range":      {[]byte{0x03, 0xe7}, CloseProtocolError},
+		"reserved 1004":    {[]byte{0x03, 0xec}, CloseProtocolError},
+		"no status sent":   {[]byte{0x03, 0xed}, CloseProtocolError},
+		"abnormal sent":    {[]byte{0x03, 0xee}, CloseProtocolError},
+		"tls failure sent": {[]byte{0x03, 0xf7}, CloseProtocolError},
+		"unassigned":       {[]byte{0x07, 0xd0}, CloseProtocolError},
+		"above range":      {[]byte{0x13, 0x88}, CloseProtocolError},
+		"invalid utf-8":    {[]byte{0x03, 0xe8, 0xff}, CloseInvalidPayload},
+		"application code": {[]byte{0x0f, 0xa0}, 4000},
+		"registered code":  {[]byte{0x03, 0xf5}, CloseTryAgainLater},
+		"empty payload":    {nil, CloseNoStatus},
+	}
+	server := echoServer(t)
+	for name, c := range cases {
+		conn := dial(t, server)
+		conn.writeFrame(true, CloseMessage, c.payload)
+		_, _, err := conn.ReadMessage()
+		var closeErr *CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != c.code {
+			t.Errorf("%s: Got: %v, Expected close code %d", name, err, c.code)
+		}
+	}
+}
+
+func TestBadHandshake(t *testing.T) {
+	server := echoServer(t)
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 400 {
+		t.Errorf("Got: %d, Expected a plain GET to be refused with 400", resp.StatusCode)
+	}
+	if key := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); key != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Got: %s, Expected the accept key from RFC 6455", key)
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	server := echoServer(t)
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	cases := map[string]struct {
+		origin  string
+		allowed bool
+	}{
+		"no origin":    {"", true},
+		"same origin":  {server.URL, true},
+		"other origin": {"https://evil.example", false},
+		"other port":   {"http://127.0.0.1:1", false},
+		"malformed":    {"::not a url", false},
+	}
+	for name, c := range cases {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		header := http.Header{}
+		if c.origin != "" {
+			header.Set("Origin", c.origin)
+		}
+		conn, resp, err := Dial(ctx, wsURL, header)
+		cancel()
+		if c.allowed {
+			if err != nil {
+				t.Errorf("%s: Got: %s, Expected the upgrade to succeed", name, err)
+				continue
+			}
+			conn.Close()
+			continue
+		}
+		if !errors.Is(err, ErrBadHandshake) || resp == nil || resp.StatusCode != 403 {
+			t.Errorf("%s: Got: %v, Expected the upgrade to be refused with 403", name, err)
+		}
+	}
+}
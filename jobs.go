@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/David-Bosnic/chirpy/internal/jobs"
+	"github.com/google/uuid"
+)
+
+var jobsConfig = jobs.Config{
+	Concurrency:     4,
+	PollInterval:    5 * time.Second,
+	Timeout:         5 * time.Minute,
+	ShutdownTimeout: 30 * time.Second,
+}
+
+const (
+	// maintenanceInterval is how often the cleanup jobs are queued. Each
+	// instance queues them, and their unique keys keep one of each waiting.
+	maintenanceInterval = time.Hour
+	// jobRetention is how long succeeded jobs are kept. Dead jobs are kept
+	// until they are retried.
+	jobRetention = 7 * 24 * time.Hour
+	// revokedTokenRetention is how long revoked refresh tokens are kept
+	// before cleanup deletes them. Expired tokens go straight away.
+	revokedTokenRetention = 24 * time.Hour
+)
+
+// Job types.
+const (
+	jobFanOutChirp   = "timeline.fan_out"
+	jobNotifyChirp   = "notifications.chirp"
+	jobCleanupTokens = "tokens.cleanup"
+	jobPruneJobs     = "jobs.prune"
+)
+
+// jobPriorityMaintenance puts cleanup behind work users are waiting on.
+const jobPriorityMaintenance = -10
+
+type Job struct {
+	ID          uuid.UUID       `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Priority    int32           `json:"priority"`
+	Status      string          `json:"status"`
+	Attempts    int32           `json:"attempts"`
+	MaxAttempts int32           `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LastError   string          `json:"last_error"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+	UniqueKey   string          `json:"unique_key,omitempty"`
+}
+
+type JobCount struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+}
+
+type fanOutJob struct {
+	ChirpID uuid.UUID `json:"chirp_id"`
+}
+
+type notifyChirpJob struct {
+	ChirpID uuid.UUID `json:"chirp_id"`
+}
+
+func formatJob(job database.Job) Job {
+	return Job{
+		ID:          job.ID,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+		Type:        job.Type,
+		Payload:     json.RawMessage(job.Payload),
+		Priority:    job.Priority,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt,
+		LastError:   job.LastError,
+		FinishedAt:  nullTimePtr(job.FinishedAt),
+		UniqueKey:   job.UniqueKey.String,
+	}
+}
+
+// jobStore reads and updates the jobs table for the worker.
+type jobStore struct {
+	queries *database.Queries
+}
+
+func (s jobStore) Claim(ctx context.Context, types []string, leaseUntil time.Time, limit int32) ([]jobs.Job, error) {
+	rows, err := s.queries.ClaimJobs(ctx, database.ClaimJobsParams{
+		LeaseUntil: leaseUntil,
+		Types:      types,
+		BatchSize:  limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	claimed := make([]jobs.Job, 0, len(rows))
+	for _, row := range rows {
+		claimed = append(claimed, jobs.Job{
+			ID:          row.ID,
+			Type:        row.Type,
+			Payload:     json.RawMessage(row.Payload),
+			Attempts:    row.Attempts,
+			MaxAttempts: row.MaxAttempts,
+		})
+	}
+	return claimed, nil
+}
+
+func (s jobStore) Complete(ctx context.Context, id uuid.UUID) error {
+	return s.queries.CompleteJob(ctx, id)
+}
+
+func (s jobStore) Retry(ctx context.Context, id uuid.UUID, runAt time.Time, lastError string) error {
+	return s.queries.RetryJob(ctx, database.RetryJobParams{
+		ID:        id,
+		RunAt:     runAt,
+		LastError: lastError,
+	})
+}
+
+func (s jobStore) Release(ctx context.Context, id uuid.UUID, lastError string) error {
+	return s.queries.ReleaseJob(ctx, database.ReleaseJobParams{
+		ID:        id,
+		LastError: lastError,
+	})
+}
+
+func (s jobStore) Kill(ctx context.Context, id uuid.UUID, lastError string) error {
+	return s.queries.KillJob(ctx, database.KillJobParams{
+		ID:        id,
+		LastError: lastError,
+	})
+}
+
+// enqueueJob queues a job. q may be bound to a transaction, so the job is
+// only queued if the transaction commits.
+func enqueueJob(ctx context.Context, q *database.Queries, jobType string, payload any, opts jobs.Options) error {
+	job, err := jobs.Prepare(jobType, payload, opts)
+	if err != nil {
+		return err
+	}
+	return q.CreateJob(ctx, database.CreateJobParams{
+		Type:        job.Type,
+		Payload:     job.Payload,
+		Priority:    job.Priority,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt,
+		UniqueKey:   sql.NullString{String: job.UniqueKey, Valid: job.UniqueKey != ""},
+	})
+}
+
+func (cfg *apiConfig) registerJobs() {
+	jobs.Register(cfg.jobs, jobFanOutChirp, func(ctx context.Context, payload fanOutJob) error {
+		chirp, err := cfg.queries.GetChirp(ctx, payload.ChirpID)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Deleted before it was fanned out.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return cfg.fanOutChirp(ctx, chirp)
+	})
+	jobs.Register(cfg.jobs, jobNotifyChirp, func(ctx context.Context, payload notifyChirpJob) error {
+		chirp, err := cfg.queries.GetChirp(ctx, payload.ChirpID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if chirp.Status != chirpStatusPublished {
+			return nil
+		}
+		return cfg.notifyChirp(ctx, chirp)
+	})
+	jobs.Register(cfg.jobs, jobCleanupTokens, func(ctx context.Context, payload struct{}) error {
+		return cfg.queries.DeleteStaleRefreshTokens(ctx, sql.NullTime{Time: time.Now().UTC().Add(-revokedTokenRetention), Valid: true})
+	})
+	jobs.Register(cfg.jobs, jobPruneJobs, func(ctx context.Context, payload struct{}) error {
+		return cfg.queries.DeleteSucceededJobs(ctx, sql.NullTime{Time: time.Now().UTC().Add(-jobRetention), Valid: true})
+	})
+}
+
+// scheduleMaintenance queues the cleanup jobs now and every
+// maintenanceInterval until ctx is done.
+func (cfg *apiConfig) scheduleMaintenance(ctx context.Context) {
+	ticker := time.NewTicker(maintenanceInterval)
+	defer ticker.Stop()
+	for {
+		for _, jobType := range []string{jobCleanupTokens, jobPruneJobs} {
+			err := enqueueJob(ctx, cfg.queries, jobType, struct{}{}, jobs.Options{
+				Priority:  jobPriorityMaintenance,
+				UniqueKey: jobType,
+			})
+			if err != nil {
+				log.Printf("Error queueing %s job: %s", jobType, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) handlerAdminJobsList(w http.ResponseWriter, r *http.Request) {
+	_, err := cfg.authenticateModerator(r)
+	if err != nil {
+		log.Printf("Error failed to authenticate moderator: %s\n", err)
+		w.WriteHeader(403)
+		w.Write([]byte("Moderator access required"))
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = jobs.StatusDead
+	}
+	if status != jobs.StatusPending && status != jobs.StatusRunning && status != jobs.StatusSucceeded && status != jobs.StatusDead {
+		w.WriteHeader(400)
+		w.Write([]byte("Unknown job status"))
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	rows, err := cfg.queries.ListJobs(r.Context(), database.ListJobsParams{
+		Status:     status,
+		Type:       r.URL.Query().Get("type"),
+		BeforeTime: cursor.CreatedAt,
+		BeforeID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		log.Printf("Error listing jobs: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list jobs"))
+		return
+	}
+	page := Page[Job]{Items: []Job{}}
+	for _, row := range rows {
+		page.Items = append(page.Items, formatJob(row))
+	}
+	if len(rows) > 0 {
+		last := rows[len(rows)-1]
+		page.NextCursor = nextCursor(len(rows), limit, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	dat, err := json.Marshal(page)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+// handlerAdminJobsStats counts jobs by type and status.
+func (cfg *apiConfig) handlerAdminJobsStats(w http.ResponseWriter, r *http.Request) {
+	_, err := cfg.authenticateModerator(r)
+	if err != nil {
+		log.Printf("Error failed to authenticate moderator: %s\n", err)
+		w.WriteHeader(403)
+		w.Write([]byte("Moderator access required"))
+		return
+	}
+	rows, err := cfg.queries.CountJobsByStatus(r.Context())
+	if err != nil {
+		log.Printf("Error counting jobs: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to count jobs"))
+		return
+	}
+	counts := []JobCount{}
+	for _, row := range rows {
+		counts = append(counts, JobCount{Type: row.Type, Status: row.Status, Count: row.Count})
+	}
+	dat, err := json.Marshal(counts)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerAdminJobsGet(w http.ResponseWriter, r *http.Request) {
+	_, err := cfg.authenticateModerator(r)
+	if err != nil {
+		log.Printf("Error failed to authenticate moderator: %s\n", err)
+		w.WriteHeader(403)
+		w.Write([]byte("Moderator access required"))
+		return
+	}
+	jobID, err := uuid.Parse(r.PathValue("jobID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing jobID"))
+		return
+	}
+	job, err := cfg.queries.GetJob(r.Context(), jobID)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("Job does not exist"))
+		return
+	}
+	writeJob(w, 200, job)
+}
+
+// handlerAdminJobsRetry puts a dead job back in the queue with its
+// attempts reset.
+func (cfg *apiConfig) handlerAdminJobsRetry(w http.ResponseWriter, r *http.Request) {
+	_, err := cfg.authenticateModerator(r)
+	if err != nil {
+		log.Printf("Error failed to authenticate moderator: %s\n", err)
+		w.WriteHeader(403)
+		w.Write([]byte("Moderator access required"))
+		return
+	}
+	jobID, err := uuid.Parse(r.PathValue("jobID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing jobID"))
+		return
+	}
+	job, err := cfg.queries.GetJob(r.Context(), jobID)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("Job does not exist"))
+		return
+	}
+	if job.Status != jobs.StatusDead {
+		w.WriteHeader(409)
+		w.Write([]byte("Only dead jobs can be retried"))
+		return
+	}
+	job, err = cfg.queries.RequeueDeadJob(r.Context(), jobID)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(409)
+		w.Write([]byte("A job with the same unique key is already queued"))
+		return
+	}
+	if err != nil {
+		log.Printf("Error requeueing job: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to retry job"))
+		return
+	}
+	cfg.jobs.Wake()
+	writeJob(w, 200, job)
+}
+
+func writeJob(w http.ResponseWriter, status int, job database.Job) {
+	dat, err := json.Marshal(formatJob(job))
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(dat)
+}
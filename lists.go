@@ -0,0 +1,527 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxListNameLength        = 25
+	maxListDescriptionLength = 100
+	maxListMembers           = 5000
+)
+
+type List struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	OwnerID     uuid.UUID `json:"owner_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IsPrivate   bool      `json:"is_private"`
+}
+
+func formatList(list database.List) List {
+	return List{
+		ID:          list.ID,
+		CreatedAt:   list.CreatedAt,
+		UpdatedAt:   list.UpdatedAt,
+		OwnerID:     list.OwnerID,
+		Name:        list.Name,
+		Description: list.Description,
+		IsPrivate:   list.IsPrivate,
+	}
+}
+
+func formatLists(lists []database.List) []List {
+	formatted := []List{}
+	for _, list := range lists {
+		formatted = append(formatted, formatList(list))
+	}
+	return formatted
+}
+
+// validateListFields trims the name and description in place and returns a
+// client-facing message when either is unacceptable.
+func validateListFields(name, description *string) string {
+	*name = strings.TrimSpace(*name)
+	*description = strings.TrimSpace(*description)
+	if *name == "" {
+		return "List name is required"
+	}
+	if utf8.RuneCountInString(*name) > maxListNameLength {
+		return "List name is too long"
+	}
+	if utf8.RuneCountInString(*description) > maxListDescriptionLength {
+		return "List description is too long"
+	}
+	return ""
+}
+
+// canViewList reports whether the viewer may read the list, its members and
+// its timeline, or subscribe to it. Private lists are for their owner only.
+func canViewList(list database.List, viewerID uuid.UUID) bool {
+	return !list.IsPrivate || list.OwnerID == viewerID
+}
+
+// visibleList resolves the {listID} path value to a list the viewer may
+// read. Private lists look missing to everyone but their owner.
+func (cfg *apiConfig) visibleList(w http.ResponseWriter, r *http.Request, viewerID uuid.UUID) (database.List, bool) {
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing listID"))
+		return database.List{}, false
+	}
+	list, err := cfg.queries.GetList(r.Context(), listID)
+	if err != nil || !canViewList(list, viewerID) {
+		w.WriteHeader(404)
+		w.Write([]byte("List does not exist"))
+		return database.List{}, false
+	}
+	return list, true
+}
+
+// ownedList authenticates the caller and resolves {listID} to one of their
+// own lists.
+func (cfg *apiConfig) ownedList(w http.ResponseWriter, r *http.Request) (database.List, bool) {
+	userID, err := cfg.authenticateWriter(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return database.List{}, false
+	}
+	list, ok := cfg.visibleList(w, r, userID)
+	if !ok {
+		return database.List{}, false
+	}
+	if list.OwnerID != userID {
+		w.WriteHeader(403)
+		w.Write([]byte("Only the list owner can do that"))
+		return database.List{}, false
+	}
+	return list, true
+}
+
+func writeList(w http.ResponseWriter, code int, list database.List) {
+	dat, err := json.Marshal(formatList(list))
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(code)
+	w.Write(dat)
+}
+
+func writeLists(w http.ResponseWriter, lists []database.List) {
+	dat, err := json.Marshal(formatLists(lists))
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerListsCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticateWriter(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	type parameters struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		IsPrivate   bool   `json:"is_private"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error decoding parameters"))
+		return
+	}
+	if msg := validateListFields(&params.Name, &params.Description); msg != "" {
+		w.WriteHeader(400)
+		w.Write([]byte(msg))
+		return
+	}
+	list, err := cfg.queries.CreateList(r.Context(), database.CreateListParams{
+		OwnerID:     userID,
+		Name:        params.Name,
+		Description: params.Description,
+		IsPrivate:   params.IsPrivate,
+	})
+	if err != nil {
+		log.Printf("Error creating list: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to create list"))
+		return
+	}
+	writeList(w, 201, list)
+}
+
+func (cfg *apiConfig) handlerListsGet(w http.ResponseWriter, r *http.Request) {
+	viewer, err := cfg.viewerFilterFromRequest(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	list, ok := cfg.visibleList(w, r, viewer.viewerID)
+	if !ok {
+		return
+	}
+	writeList(w, 200, list)
+}
+
+func (cfg *apiConfig) handlerListsUpdate(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+	type parameters struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		IsPrivate   *bool   `json:"is_private"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error decoding parameters"))
+		return
+	}
+	update := database.UpdateListParams{
+		Name:        list.Name,
+		Description: list.Description,
+		IsPrivate:   list.IsPrivate,
+		ID:          list.ID,
+	}
+	if params.Name != nil {
+		update.Name = *params.Name
+	}
+	if params.Description != nil {
+		update.Description = *params.Description
+	}
+	if params.IsPrivate != nil {
+		update.IsPrivate = *params.IsPrivate
+	}
+	if msg := validateListFields(&update.Name, &update.Description); msg != "" {
+		w.WriteHeader(400)
+		w.Write([]byte(msg))
+		return
+	}
+	list, err = cfg.queries.UpdateList(r.Context(), update)
+	if err != nil {
+		log.Printf("Error updating list: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to update list"))
+		return
+	}
+	writeList(w, 200, list)
+}
+
+func (cfg *apiConfig) handlerListsDelete(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.queries.DeleteList(r.Context(), list.ID)
+	if err != nil {
+		log.Printf("Error deleting list: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to delete list"))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerUserListsList(w http.ResponseWriter, r *http.Request) {
+	viewer, err := cfg.viewerFilterFromRequest(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	ownerID, ok := cfg.userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	if !viewer.canSeeProfile(ownerID) {
+		w.WriteHeader(404)
+		w.Write([]byte("User does not exist"))
+		return
+	}
+	lists, err := cfg.queries.ListListsByOwner(r.Context(), database.ListListsByOwnerParams{
+		OwnerID:        ownerID,
+		IncludePrivate: ownerID == viewer.viewerID,
+	})
+	if err != nil {
+		log.Printf("Error listing lists: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list lists"))
+		return
+	}
+	writeLists(w, lists)
+}
+
+func (cfg *apiConfig) handlerSubscribedListsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	lists, err := cfg.queries.ListSubscribedLists(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error listing subscribed lists: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list lists"))
+		return
+	}
+	writeLists(w, lists)
+}
+
+func (cfg *apiConfig) handlerListMembersList(w http.ResponseWriter, r *http.Request) {
+	viewer, err := cfg.viewerFilterFromRequest(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	list, ok := cfg.visibleList(w, r, viewer.viewerID)
+	if !ok {
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	members, err := cfg.queries.ListListMembers(r.Context(), database.ListListMembersParams{
+		ListID:     list.ID,
+		BeforeTime: cursor.CreatedAt,
+		BeforeID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		log.Printf("Error listing list members: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list members"))
+		return
+	}
+	page := Page[Relationship]{Items: visibleListMembers(viewer, members)}
+	if len(members) > 0 {
+		last := members[len(members)-1]
+		page.NextCursor = nextCursor(len(members), limit, pageCursor{CreatedAt: last.CreatedAt, ID: last.UserID})
+	}
+	dat, err := json.Marshal(page)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+// visibleListMembers drops members the viewer may not see. The cursor still
+// comes from the last member read, so hidden members never end a page early.
+func visibleListMembers(viewer *viewerFilter, members []database.ListMember) []Relationship {
+	relationships := []Relationship{}
+	for _, member := range members {
+		if !viewer.canSeeProfile(member.UserID) {
+			continue
+		}
+		relationships = append(relationships, Relationship{
+			UserID:    member.UserID,
+			CreatedAt: member.CreatedAt,
+		})
+	}
+	return relationships
+}
+
+func (cfg *apiConfig) handlerListMembersAdd(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+	memberID, ok := cfg.userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	_, err := cfg.queries.GetUserByID(r.Context(), memberID)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("User does not exist"))
+		return
+	}
+	blocked, err := cfg.queries.IsBlockedEitherWay(r.Context(), database.IsBlockedEitherWayParams{
+		BlockerID: list.OwnerID,
+		BlockedID: memberID,
+	})
+	if err != nil {
+		log.Printf("Error checking blocks: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to add list member"))
+		return
+	}
+	if blocked {
+		w.WriteHeader(403)
+		w.Write([]byte("Cannot add this user"))
+		return
+	}
+	count, err := cfg.queries.CountListMembers(r.Context(), list.ID)
+	if err != nil {
+		log.Printf("Error counting list members: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to add list member"))
+		return
+	}
+	if count >= maxListMembers {
+		w.WriteHeader(400)
+		w.Write([]byte("List is full"))
+		return
+	}
+	err = cfg.queries.AddListMember(r.Context(), database.AddListMemberParams{
+		ListID: list.ID,
+		UserID: memberID,
+	})
+	if err != nil {
+		log.Printf("Error adding list member: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to add list member"))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerListMembersRemove(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+	memberID, ok := cfg.userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	removed, err := cfg.queries.RemoveListMember(r.Context(), database.RemoveListMemberParams{
+		ListID: list.ID,
+		UserID: memberID,
+	})
+	if err != nil {
+		log.Printf("Error removing list member: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to remove list member"))
+		return
+	}
+	if removed == 0 {
+		w.WriteHeader(404)
+		w.Write([]byte("User is not a member of this list"))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerListSubscriptionsCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	list, ok := cfg.visibleList(w, r, userID)
+	if !ok {
+		return
+	}
+	if list.OwnerID == userID {
+		w.WriteHeader(400)
+		w.Write([]byte("Cannot subscribe to your own list"))
+		return
+	}
+	blocked, err := cfg.queries.IsBlockedEitherWay(r.Context(), database.IsBlockedEitherWayParams{
+		BlockerID: userID,
+		BlockedID: list.OwnerID,
+	})
+	if err != nil {
+		log.Printf("Error checking blocks: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to subscribe to list"))
+		return
+	}
+	if blocked {
+		w.WriteHeader(403)
+		w.Write([]byte("Cannot subscribe to this list"))
+		return
+	}
+	err = cfg.queries.SubscribeToList(r.Context(), database.SubscribeToListParams{
+		ListID: list.ID,
+		UserID: userID,
+	})
+	if err != nil {
+		log.Printf("Error subscribing to list: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to subscribe to list"))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerListSubscriptionsDelete(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing listID"))
+		return
+	}
+	// Unsubscribing works even if the list has since gone private.
+	err = cfg.queries.UnsubscribeFromList(r.Context(), database.UnsubscribeFromListParams{
+		ListID: listID,
+		UserID: userID,
+	})
+	if err != nil {
+		log.Printf("Error unsubscribing from list: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to unsubscribe from list"))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerListTimeline(w http.ResponseWriter, r *http.Request) {
+	viewer, err := cfg.viewerFilterFromRequest(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	list, ok := cfg.visibleList(w, r, viewer.viewerID)
+	if !ok {
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	chirps, err := cfg.queries.ListListTimeline(r.Context(), database.ListListTimelineParams{
+		ListID:     list.ID,
+		BeforeTime: cursor.CreatedAt,
+		BeforeID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		log.Printf("Error listing list timeline: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to get list timeline"))
+		return
+	}
+	writeChirpPage(w, r, viewer, chirps, limit)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestCanViewList(t *testing.T) {
+	ownerID := uuid.New()
+	subscriberID := uuid.New()
+	public := database.List{ID: uuid.New(), OwnerID: ownerID}
+	private := database.List{ID: uuid.New(), OwnerID: ownerID, IsPrivate: true}
+	cases := []struct {
+		name     string
+		list     database.List
+		viewerID uuid.UUID
+		want     bool
+	}{
+		{"public to owner", public, ownerID, true},
+		{"public to subscriber", public, subscriberID, true},
+		{"public to anonymous", public, uuid.Nil, true},
+		{"private to owner", private, ownerID, true},
+		// A subscription made while the list was public doesn't keep it
+		// readable once it goes private.
+		{"private to subscriber", private, subscriberID, false},
+		{"private to anonymous", private, uuid.Nil, false},
+	}
+	for _, c := range cases {
+		if got := canViewList(c.list, c.viewerID); got != c.want {
+			t.Errorf("%s: Got: %v, Expected: %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestVisibleListMembers(t *testing.T) {
+	viewerID := uuid.New()
+	friendID := uuid.New()
+	blockedID := uuid.New()
+	shadowBannedID := uuid.New()
+	mutedID := uuid.New()
+	protectedID := uuid.New()
+	filter := &viewerFilter{
+		viewerID:     viewerID,
+		blocked:      map[uuid.UUID]bool{blockedID: true},
+		muted:        map[uuid.UUID]bool{mutedID: true},
+		shadowBanned: map[uuid.UUID]bool{shadowBannedID: true},
+		protected:    map[uuid.UUID]bool{protectedID: true},
+	}
+	now := time.Now()
+	members := []database.ListMember{}
+	for i, id := range []uuid.UUID{friendID, blockedID, shadowBannedID, mutedID, protectedID, viewerID} {
+		members = append(members, database.ListMember{UserID: id, CreatedAt: now.Add(-time.Duration(i) * time.Minute)})
+	}
+
+	// Muted and protected members are still listed; only their chirps are
+	// kept out of the list timeline.
+	want := []uuid.UUID{friendID, mutedID, protectedID, viewerID}
+	got := visibleListMembers(filter, members)
+	if len(got) != len(want) {
+		t.Fatalf("Got %d members, Expected %d: %v", len(got), len(want), got)
+	}
+	for i, id := range want {
+		if got[i].UserID != id {
+			t.Errorf("Case %d: Got: %s, Expected: %s", i, got[i].UserID, id)
+		}
+	}
+	if n := len(visibleListMembers(nil, members)); n != len(members) {
+		t.Errorf("Got: %d members, Expected all %d without a filter", n, len(members))
+	}
+
+	chirps := []database.Chirp{}
+	for _, member := range members {
+		chirps = append(chirps, database.Chirp{ID: uuid.New(), UserID: member.UserID})
+	}
+	timeline := filter.present(chirps, false)
+	wantAuthors := []uuid.UUID{friendID, viewerID}
+	if len(timeline) != len(wantAuthors) {
+		t.Fatalf("Got %d chirps, Expected %d: %v", len(timeline), len(wantAuthors), timeline)
+	}
+	for i, id := range wantAuthors {
+		if timeline[i].UserID != id {
+			t.Errorf("Timeline case %d: Got: %s, Expected: %s", i, timeline[i].UserID, id)
+		}
+	}
+}
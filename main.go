@@ -1,37 +1,82 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"regexp"
+	"os/signal"
 	"sort"
+	"strings"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/David-Bosnic/chirpy/internal/auth"
 	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/David-Bosnic/chirpy/internal/eventbus"
+	"github.com/David-Bosnic/chirpy/internal/jobs"
+	"github.com/David-Bosnic/chirpy/internal/moderation"
+	"github.com/David-Bosnic/chirpy/internal/outbox"
+	"github.com/David-Bosnic/chirpy/internal/pubsub"
+	"github.com/David-Bosnic/chirpy/internal/suggest"
+	"github.com/David-Bosnic/chirpy/internal/trends"
+	"github.com/David-Bosnic/chirpy/internal/webhooks"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
+// accessTokenTTL is how long an access token from login or refresh lasts.
+const accessTokenTTL = time.Hour
+
+// shutdownTimeout is how long open requests get to finish on shutdown.
+const shutdownTimeout = 10 * time.Second
+
 type apiConfig struct {
 	fileserverHit atomic.Int32
-	queries       *database.Queries
-	platform      string
-	JWTSecret     string
+	// fileserverHitUnsent counts hits not yet shared with other instances.
+	fileserverHitUnsent atomic.Int32
+	db                  *sql.DB
+	queries             *database.Queries
+	platform            string
+	JWTSecret           string
+	moderator           moderation.Chain
+	messageModerator    moderation.Chain
+	classifier          *moderation.Classifier
+	suggestions         *suggest.Cache
+	trends              *trends.Aggregator
+	chirpStream         *pubsub.Hub[database.Chirp]
+	gateway             *gateway
+	bus                 *eventbus.Bus
+	webhookSender       *webhooks.Sender
+	outbox              *outbox.Dispatcher
+	jobs                *jobs.Worker
+	// stopStreams is closed once the server starts shutting down. Shutdown
+	// does not cancel the contexts of open requests, so streams watch it.
+	stopStreams chan struct{}
 }
 
 type User struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	ID               uuid.UUID `json:"id"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	Email            string    `json:"email,omitempty"`
+	Handle           string    `json:"handle"`
+	IsChirpyRed      bool      `json:"is_chirpy_red"`
+	IsProtected      bool      `json:"is_protected"`
+	DisplayName      string    `json:"display_name"`
+	Bio              string    `json:"bio"`
+	Location         string    `json:"location"`
+	Website          string    `json:"website"`
+	AvatarURL        string    `json:"avatar_url"`
+	DMsFollowersOnly bool      `json:"dms_followers_only"`
+	FollowerCount    int64     `json:"follower_count"`
+	FollowingCount   int64     `json:"following_count"`
 }
 
 type UserWithJWT struct {
@@ -41,15 +86,22 @@ type UserWithJWT struct {
 }
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID          uuid.UUID           `json:"id"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	Body        string              `json:"body"`
+	UserID      uuid.UUID           `json:"user_id"`
+	Status      string              `json:"status"`
+	ReplyToID   *uuid.UUID          `json:"reply_to_id,omitempty"`
+	Moderation  *moderation.Outcome `json:"moderation,omitempty"`
+	Collapsed   bool                `json:"collapsed,omitempty"`
+	MutedPhrase string              `json:"muted_phrase,omitempty"`
 }
 
 func main() {
 	godotenv.Load()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	dbURL := os.Getenv("DB_URL")
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
@@ -57,9 +109,67 @@ func main() {
 	}
 	dbQueries := database.New(db)
 	var apiConf apiConfig
+	apiConf.db = db
 	apiConf.queries = dbQueries
 	apiConf.platform = os.Getenv("PLATFORM")
 	apiConf.JWTSecret = os.Getenv("SECRET")
+	apiConf.classifier = moderation.NewClassifier(toxicityThreshold)
+	go func() {
+		_, err := apiConf.retrainClassifier(ctx)
+		if err != nil {
+			log.Printf("Error training classifier: %s", err)
+		}
+	}()
+	wordFilter := moderation.NewWordFilter("kerfuffle", "sharbert", "fornax")
+	linkBlocklist := moderation.NewLinkBlocklist(strings.Split(os.Getenv("BLOCKED_LINK_DOMAINS"), ",")...)
+	apiConf.moderator = moderation.NewChain(
+		wordFilter,
+		linkBlocklist,
+		moderation.NewDuplicateDetector(apiConf.recentChirps, 24*time.Hour),
+		moderation.NewLinkThrottle(apiConf.recentChirps, apiConf.accountCreatedAt),
+		apiConf.classifier,
+	)
+	// Messages get the content filters but not the stages that look at an
+	// author's public posting history.
+	apiConf.messageModerator = moderation.NewChain(
+		wordFilter,
+		linkBlocklist,
+		apiConf.classifier,
+	)
+	apiConf.suggestions = suggest.NewCache(apiConf.computeSuggestions, suggestionsTTL, suggestionsIdle)
+	go apiConf.suggestions.Run(ctx, suggestionsRefreshInterval)
+	apiConf.chirpStream = pubsub.NewHub[database.Chirp]()
+	apiConf.stopStreams = make(chan struct{})
+	apiConf.gateway = newGateway()
+	apiConf.bus = eventbus.New(eventBusChannel, func(ctx context.Context, channel, payload string) error {
+		return dbQueries.NotifyEvent(ctx, database.NotifyEventParams{Channel: channel, Payload: payload})
+	})
+	apiConf.subscribeEvents()
+	go func() {
+		err := apiConf.bus.Run(ctx, eventbus.NewListener(dbURL))
+		if err != nil {
+			log.Printf("Error running event bus: %s", err)
+		}
+	}()
+	go apiConf.flushMetrics(ctx)
+	apiConf.outbox = outbox.NewDispatcher(outboxStore{queries: dbQueries})
+	apiConf.subscribeOutbox()
+	go apiConf.outbox.Run(ctx, outboxPollInterval)
+	go apiConf.pruneOutbox(ctx)
+	apiConf.jobs = jobs.NewWorker(jobStore{queries: dbQueries}, jobsConfig)
+	apiConf.registerJobs()
+	go apiConf.scheduleMaintenance(ctx)
+	jobsDone := make(chan struct{})
+	go func() {
+		apiConf.jobs.Run(ctx)
+		close(jobsDone)
+	}()
+	// Local development points webhooks at localhost, which is refused
+	// everywhere else.
+	apiConf.webhookSender = webhooks.NewSender(webhookTimeout, apiConf.platform == "dev")
+	go apiConf.dispatchWebhooks(ctx)
+	apiConf.trends = trends.NewAggregator(apiConf.fetchTrendPosts, trendsConfig, trendsWarmUp)
+	go apiConf.trends.Run(ctx, trendsRefreshInterval)
 	mux := http.NewServeMux()
 	mux.Handle("/app/", http.StripPrefix("/app/", apiConf.middlewareMetricsInc(http.FileServer(http.Dir(".")))))
 
@@ -84,7 +194,12 @@ func main() {
 	})
 	mux.HandleFunc("POST /admin/reset", func(w http.ResponseWriter, r *http.Request) {
 		apiConf.fileserverHit.Store(0)
-		err := dbQueries.DeleteAllUsers(r.Context())
+		apiConf.fileserverHitUnsent.Store(0)
+		err := apiConf.bus.Broadcast(r.Context(), eventMetricsReset, nil)
+		if err != nil {
+			log.Printf("Error resetting metrics on other instances: %s", err)
+		}
+		err = dbQueries.DeleteAllUsers(r.Context())
 		if err != nil {
 			log.Printf("Error deleting all users: %s", err)
 			return
@@ -112,10 +227,12 @@ func main() {
 			log.Printf("Error getting all chirps: %s", err)
 			return
 		}
-		taggedChirps := []Chirp{}
-		for _, untaggedChirp := range untaggedChirps {
-			taggedChirps = append(taggedChirps, addTagsToChirp(untaggedChirp))
+		viewer, err := apiConf.viewerFilterFromRequest(r)
+		if err != nil {
+			writeAuthError(w, err)
+			return
 		}
+		taggedChirps := viewer.present(untaggedChirps, r.URL.Query().Get("collapse_muted") == "true")
 
 		sortType := r.URL.Query().Get("sort")
 		if sortType == "asc" {
@@ -146,6 +263,17 @@ func main() {
 			w.Write([]byte("Failed to get chirp"))
 			return
 		}
+		viewer, err := apiConf.viewerFilterFromRequest(r)
+		if err != nil {
+			writeAuthError(w, err)
+			return
+		}
+		isAuthor := viewer != nil && viewer.viewerID == chirp.UserID
+		if (chirp.Status != chirpStatusPublished && !isAuthor) || !viewer.canSee(chirp.UserID) {
+			w.WriteHeader(404)
+			w.Write([]byte("Failed to get chirp"))
+			return
+		}
 
 		dat, err := json.Marshal(addTagsToChirp(chirp))
 		if err != nil {
@@ -161,21 +289,12 @@ func main() {
 	})
 	mux.HandleFunc("POST /api/chirps", func(w http.ResponseWriter, r *http.Request) {
 		type parameters struct {
-			Body string `json:"body"`
+			Body      string     `json:"body"`
+			ReplyToID *uuid.UUID `json:"reply_to_id"`
 		}
-		token, err := auth.GetBearerToken(r.Header)
+		validatedUUID, err := apiConf.authenticateWriter(r)
 		if err != nil {
-			log.Printf("Error failed to get bearer token %s\n", err)
-			w.WriteHeader(400)
-			w.Write([]byte("Failed to get bearer token"))
-			return
-		}
-
-		validatedUUID, err := auth.ValidateJWT(token, apiConf.JWTSecret)
-		if err != nil {
-			log.Printf("Error failed to validate jwt %s\n", err)
-			w.WriteHeader(401)
-			w.Write([]byte("Failed to validate jwt token"))
+			writeAuthError(w, err)
 			return
 		}
 		decoder := json.NewDecoder(r.Body)
@@ -225,27 +344,57 @@ func main() {
 			w.Write(dat)
 			return
 		}
-		// cleanUUID, err := uuid.Parse(validatedUUID)
-		// if err != nil {
-		// 	log.Printf("Error parsing uuid for chirps: %s", err)
-		// 	return
-		// }
+		replyToID := uuid.NullUUID{}
+		if params.ReplyToID != nil {
+			parent, ok := apiConf.visibleChirp(w, r, validatedUUID, *params.ReplyToID)
+			if !ok {
+				return
+			}
+			replyToID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		}
+		outcome, err := apiConf.moderator.Run(r.Context(), moderation.Submission{
+			AuthorID: validatedUUID,
+			Body:     params.Body,
+		})
+		if err != nil {
+			log.Printf("Error moderating chirp: %s", err)
+			w.WriteHeader(500)
+			w.Write([]byte("Failed to moderate chirp"))
+			return
+		}
+		if outcome.Action == moderation.Reject {
+			writeRejectedChirp(w, outcome)
+			return
+		}
 
 		cleanChirp := database.CreateChirpParams{
-			Body:   cleanBody(params.Body),
-			UserID: validatedUUID,
-		}
-		chirp, err := dbQueries.CreateChirp(r.Context(), cleanChirp)
+			Body:      outcome.Body,
+			UserID:    validatedUUID,
+			Status:    chirpStatusFor(outcome),
+			ReplyToID: replyToID,
+		}
+		var chirp database.Chirp
+		err = apiConf.inTx(r.Context(), func(q *database.Queries) error {
+			chirp, err = q.CreateChirp(r.Context(), cleanChirp)
+			if err != nil {
+				return err
+			}
+			if chirp.Status == chirpStatusHeld {
+				err = apiConf.queueHeldChirp(r.Context(), q, chirp, outcome)
+				if err != nil {
+					return err
+				}
+			}
+			return enqueueChirpCreated(r.Context(), q, chirp)
+		})
 		if err != nil {
 			log.Printf("Error creating chirp: %s", err)
+			w.WriteHeader(500)
+			w.Write([]byte("Failed to create chirp"))
+			return
 		}
-		formattedChirp := Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		}
+		formattedChirp := addTagsToChirp(chirp)
+		formattedChirp.Moderation = &outcome
 
 		dat, err := json.Marshal(formattedChirp)
 		if err != nil {
@@ -261,6 +410,7 @@ func main() {
 		type parameters struct {
 			Email    string `json:"email"`
 			Password string `json:"password"`
+			Handle   string `json:"handle"`
 		}
 		decoder := json.NewDecoder(r.Body)
 		params := parameters{}
@@ -284,12 +434,29 @@ func main() {
 			log.Printf("Error hashing new user password: %s", err)
 			return
 		}
+		if params.Handle == "" {
+			params.Handle = defaultHandle()
+		} else {
+			err = validateHandle(params.Handle)
+			if err == nil {
+				err = apiConf.checkHandleAvailable(r, params.Handle, uuid.Nil)
+			}
+			if err != nil {
+				writeHandleError(w, err)
+				return
+			}
+		}
 		formatedParams := database.CreateUserParams{
 			Email:          params.Email,
 			HashedPassword: hashedPass,
+			Handle:         params.Handle,
 		}
 
 		user, err := dbQueries.CreateUser(r.Context(), formatedParams)
+		if isHandleTaken(err) {
+			writeHandleError(w, errHandleUnavailable)
+			return
+		}
 		if err != nil {
 			log.Printf("Error Creating User: %s", err)
 			w.WriteHeader(500)
@@ -300,6 +467,7 @@ func main() {
 			CreatedAt:   user.CreatedAt,
 			UpdatedAt:   user.UpdatedAt,
 			Email:       user.Email,
+			Handle:      user.Handle,
 			IsChirpyRed: user.IsChirpyRed,
 		}
 		dat, err := json.Marshal(formatedUser)
@@ -344,7 +512,11 @@ func main() {
 			w.Write([]byte("incorrect email or password"))
 			return
 		}
-		jwtToken, err := auth.MakeJWT(user.ID, apiConf.JWTSecret)
+		if effectiveAccountStatus(user) == accountStatusSuspended {
+			apiConf.writeSuspendedLogin(w, r, user)
+			return
+		}
+		jwtToken, err := auth.MakeJWT(user.ID, apiConf.JWTSecret, accessTokenTTL)
 		if err != nil {
 			w.WriteHeader(500)
 			w.Write([]byte("Failed to make JWT token"))
@@ -363,16 +535,14 @@ func main() {
 
 		dbQueries.CreateRefreshToken(r.Context(), tokenparams)
 		formatedUser := UserWithJWT{
-			User: User{
-				ID:          user.ID,
-				CreatedAt:   user.CreatedAt,
-				UpdatedAt:   user.UpdatedAt,
-				Email:       user.Email,
-				IsChirpyRed: user.IsChirpyRed,
-			},
+			User:         formatUser(user),
 			Token:        jwtToken,
 			RefreshToken: refreshToken,
 		}
+		err = apiConf.followCounts(r.Context(), &formatedUser.User)
+		if err != nil {
+			log.Printf("Error counting follows: %s", err)
+		}
 
 		dat, err := json.Marshal(formatedUser)
 		if err != nil {
@@ -403,7 +573,12 @@ func main() {
 			w.Write([]byte("Failed to find user from refresh token"))
 			return
 		}
-		jwtToken, err := auth.MakeJWT(user.ID, apiConf.JWTSecret)
+		if effectiveAccountStatus(user) == accountStatusSuspended {
+			w.WriteHeader(403)
+			w.Write([]byte("Account is suspended"))
+			return
+		}
+		jwtToken, err := auth.MakeJWT(user.ID, apiConf.JWTSecret, accessTokenTTL)
 		if err != nil {
 			w.WriteHeader(500)
 			w.Write([]byte("Failed to make JWT token"))
@@ -443,18 +618,9 @@ func main() {
 			Password string `json:"password"`
 		}
 
-		bearerToken, err := auth.GetBearerToken(r.Header)
-		if err != nil {
-			log.Printf("Error failed to get bearer token %s\n", err)
-			w.WriteHeader(401)
-			w.Write([]byte("Failed to get bearer token"))
-			return
-		}
-		userId, err := auth.ValidateJWT(bearerToken, apiConf.JWTSecret)
+		userId, err := apiConf.authenticate(r)
 		if err != nil {
-			log.Printf("Error Failed to validate JWT %s\n", err)
-			w.WriteHeader(401)
-			w.Write([]byte("Failed to validate JWT"))
+			writeAuthError(w, err)
 			return
 		}
 		decoder := json.NewDecoder(r.Body)
@@ -498,12 +664,10 @@ func main() {
 			log.Printf("Error getting user: %s", err)
 			return
 		}
-		resp := User{
-			ID:          user.ID,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-			Email:       user.Email,
-			IsChirpyRed: user.IsChirpyRed,
+		resp := formatUser(user)
+		err = apiConf.followCounts(r.Context(), &resp)
+		if err != nil {
+			log.Printf("Error counting follows: %s", err)
 		}
 
 		dat, err := json.Marshal(resp)
@@ -515,6 +679,7 @@ func main() {
 		w.Write(dat)
 		return
 	})
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", apiConf.handlerChirpsUpdate)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
 		id := r.PathValue("chirpID")
 		parsedID, err := uuid.Parse(id)
@@ -522,18 +687,9 @@ func main() {
 			log.Printf("Error parsing GET chirps id: %s", err)
 			return
 		}
-		bearerToken, err := auth.GetBearerToken(r.Header)
+		userId, err := apiConf.authenticate(r)
 		if err != nil {
-			log.Printf("Error failed to get bearer token %s\n", err)
-			w.WriteHeader(401)
-			w.Write([]byte("Failed to get bearer token"))
-			return
-		}
-		userId, err := auth.ValidateJWT(bearerToken, apiConf.JWTSecret)
-		if err != nil {
-			log.Printf("Error Failed to validate JWT %s\n", err)
-			w.WriteHeader(403)
-			w.Write([]byte("Failed to validate JWT"))
+			writeAuthError(w, err)
 			return
 		}
 		currentChirp, err := dbQueries.GetChirp(r.Context(), parsedID)
@@ -556,6 +712,90 @@ func main() {
 		}
 		w.WriteHeader(204)
 	})
+	mux.HandleFunc("POST /api/chirps/{chirpID}/reports", apiConf.handlerChirpReportsCreate)
+	mux.HandleFunc("POST /api/users/{id}/reports", apiConf.handlerUserReportsCreate)
+	mux.HandleFunc("POST /api/users/{id}/block", apiConf.handlerBlocksCreate)
+	mux.HandleFunc("DELETE /api/users/{id}/block", apiConf.handlerBlocksDelete)
+	mux.HandleFunc("GET /api/users/me/blocks", apiConf.handlerBlocksList)
+	mux.HandleFunc("POST /api/users/{id}/mute", apiConf.handlerMutesCreate)
+	mux.HandleFunc("DELETE /api/users/{id}/mute", apiConf.handlerMutesDelete)
+	mux.HandleFunc("GET /api/users/me/mutes", apiConf.handlerMutesList)
+	mux.HandleFunc("GET /api/users/me/muted_words", apiConf.handlerMutedWordsList)
+	mux.HandleFunc("POST /api/users/me/muted_words", apiConf.handlerMutedWordsCreate)
+	mux.HandleFunc("DELETE /api/users/me/muted_words/{mutedWordID}", apiConf.handlerMutedWordsDelete)
+	mux.HandleFunc("POST /api/users/{id}/follow", apiConf.handlerFollowsCreate)
+	mux.HandleFunc("DELETE /api/users/{id}/follow", apiConf.handlerFollowsDelete)
+	mux.HandleFunc("GET /api/users/{id}/followers", apiConf.handlerFollowersList)
+	mux.HandleFunc("GET /api/users/{id}/following", apiConf.handlerFollowingList)
+	mux.HandleFunc("GET /api/users/{id}", apiConf.handlerUsersGet)
+	mux.HandleFunc("PATCH /api/users/me", apiConf.handlerUsersUpdateProfile)
+	mux.HandleFunc("PUT /api/users/me/handle", apiConf.handlerUsersUpdateHandle)
+	mux.HandleFunc("PUT /api/users/me/protected", apiConf.handlerUsersProtect)
+	mux.HandleFunc("GET /api/users/me/follow_requests", apiConf.handlerFollowRequestsList)
+	mux.HandleFunc("POST /api/users/me/follow_requests/{id}/approve", apiConf.handlerFollowRequestsApprove)
+	mux.HandleFunc("POST /api/users/me/follow_requests/{id}/deny", apiConf.handlerFollowRequestsDeny)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", apiConf.handlerLikesCreate)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiConf.handlerLikesDelete)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", apiConf.handlerRechirpsCreate)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", apiConf.handlerRechirpsDelete)
+	mux.HandleFunc("GET /api/timeline", apiConf.handlerTimeline)
+	mux.HandleFunc("POST /api/conversations", apiConf.handlerConversationsCreate)
+	mux.HandleFunc("GET /api/conversations", apiConf.handlerConversationsList)
+	mux.HandleFunc("GET /api/conversations/{conversationID}", apiConf.handlerConversationsGet)
+	mux.HandleFunc("GET /api/conversations/{conversationID}/messages", apiConf.handlerMessagesList)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/messages", apiConf.handlerMessagesCreate)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/read", apiConf.handlerConversationsMarkRead)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/leave", apiConf.handlerConversationsLeave)
+	mux.HandleFunc("PUT /api/users/me/dm_settings", apiConf.handlerUsersDMSettings)
+	mux.HandleFunc("GET /api/notifications", apiConf.handlerNotificationsList)
+	mux.HandleFunc("POST /api/notifications/read", apiConf.handlerNotificationsMarkRead)
+	mux.HandleFunc("GET /api/notifications/preferences", apiConf.handlerNotificationPreferencesGet)
+	mux.HandleFunc("PUT /api/notifications/preferences", apiConf.handlerNotificationPreferencesUpdate)
+	mux.HandleFunc("POST /api/lists", apiConf.handlerListsCreate)
+	mux.HandleFunc("GET /api/lists/{listID}", apiConf.handlerListsGet)
+	mux.HandleFunc("PATCH /api/lists/{listID}", apiConf.handlerListsUpdate)
+	mux.HandleFunc("DELETE /api/lists/{listID}", apiConf.handlerListsDelete)
+	mux.HandleFunc("GET /api/lists/{listID}/members", apiConf.handlerListMembersList)
+	mux.HandleFunc("POST /api/lists/{listID}/members/{id}", apiConf.handlerListMembersAdd)
+	mux.HandleFunc("DELETE /api/lists/{listID}/members/{id}", apiConf.handlerListMembersRemove)
+	mux.HandleFunc("POST /api/lists/{listID}/subscription", apiConf.handlerListSubscriptionsCreate)
+	mux.HandleFunc("DELETE /api/lists/{listID}/subscription", apiConf.handlerListSubscriptionsDelete)
+	mux.HandleFunc("GET /api/lists/{listID}/timeline", apiConf.handlerListTimeline)
+	mux.HandleFunc("GET /api/users/{id}/lists", apiConf.handlerUserListsList)
+	mux.HandleFunc("GET /api/users/me/subscribed_lists", apiConf.handlerSubscribedListsList)
+	mux.HandleFunc("GET /api/users/me/suggestions", apiConf.handlerSuggestionsList)
+	mux.HandleFunc("GET /api/trends", apiConf.handlerTrendsList)
+	mux.HandleFunc("GET /api/stream", apiConf.handlerStream)
+	mux.HandleFunc("GET /api/ws", apiConf.handlerGateway)
+	mux.HandleFunc("POST /api/webhooks", apiConf.handlerWebhooksCreate)
+	mux.HandleFunc("GET /api/webhooks", apiConf.handlerWebhooksList)
+	mux.HandleFunc("GET /api/webhooks/{webhookID}", apiConf.handlerWebhooksGet)
+	mux.HandleFunc("PATCH /api/webhooks/{webhookID}", apiConf.handlerWebhooksUpdate)
+	mux.HandleFunc("DELETE /api/webhooks/{webhookID}", apiConf.handlerWebhooksDelete)
+	mux.HandleFunc("POST /api/webhooks/{webhookID}/secret", apiConf.handlerWebhooksRotateSecret)
+	mux.HandleFunc("GET /api/webhooks/{webhookID}/deliveries", apiConf.handlerWebhookDeliveriesList)
+	mux.HandleFunc("GET /api/webhooks/{webhookID}/deliveries/{deliveryID}", apiConf.handlerWebhookDeliveriesGet)
+	mux.HandleFunc("GET /admin/reports", apiConf.handlerAdminReportsList)
+	mux.HandleFunc("GET /admin/reports/{reportID}", apiConf.handlerAdminReportsGet)
+	mux.HandleFunc("POST /admin/reports/{reportID}/claim", apiConf.handlerAdminReportsClaim)
+	mux.HandleFunc("POST /admin/reports/{reportID}/resolve", apiConf.handlerAdminReportsResolve)
+	mux.HandleFunc("POST /admin/users/{id}/suspend", apiConf.handlerAdminUsersSuspend)
+	mux.HandleFunc("POST /admin/users/{id}/read_only", apiConf.handlerAdminUsersReadOnly)
+	mux.HandleFunc("POST /admin/users/{id}/shadow_ban", apiConf.handlerAdminUsersShadowBan)
+	mux.HandleFunc("POST /admin/users/{id}/restore", apiConf.handlerAdminUsersRestore)
+	mux.HandleFunc("GET /api/users/me/moderation_actions", apiConf.handlerModerationActionsListMine)
+	mux.HandleFunc("POST /api/appeals", apiConf.handlerAppealsCreate)
+	mux.HandleFunc("GET /api/appeals", apiConf.handlerAppealsListMine)
+	mux.HandleFunc("GET /admin/appeals", apiConf.handlerAdminAppealsList)
+	mux.HandleFunc("GET /admin/appeals/{appealID}", apiConf.handlerAdminAppealsGet)
+	mux.HandleFunc("POST /admin/appeals/{appealID}/uphold", apiConf.handlerAdminAppealsUphold)
+	mux.HandleFunc("POST /admin/appeals/{appealID}/reverse", apiConf.handlerAdminAppealsReverse)
+	mux.HandleFunc("GET /admin/classifier", apiConf.handlerAdminClassifierMetrics)
+	mux.HandleFunc("GET /admin/jobs", apiConf.handlerAdminJobsList)
+	mux.HandleFunc("GET /admin/jobs/stats", apiConf.handlerAdminJobsStats)
+	mux.HandleFunc("GET /admin/jobs/{jobID}", apiConf.handlerAdminJobsGet)
+	mux.HandleFunc("POST /admin/jobs/{jobID}/retry", apiConf.handlerAdminJobsRetry)
+	mux.HandleFunc("POST /admin/classifier/retrain", apiConf.handlerAdminClassifierRetrain)
 	mux.HandleFunc("POST /api/polka/webhooks", func(w http.ResponseWriter, r *http.Request) {
 		type parameters struct {
 			Event string `json:"event"`
@@ -619,20 +859,38 @@ func main() {
 	ServerMux := http.Server{}
 	ServerMux.Handler = mux
 	ServerMux.Addr = ":8080"
+	ServerMux.RegisterOnShutdown(func() { close(apiConf.stopStreams) })
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		// Shutdown leaves hijacked connections alone, so the gateway closes
+		// its WebSockets alongside it.
+		gatewayDone := make(chan struct{})
+		go func() {
+			apiConf.gateway.shutdown()
+			close(gatewayDone)
+		}()
+		err := ServerMux.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Printf("Error shutting down server: %s", err)
+		}
+		<-gatewayDone
+	}()
 
 	fmt.Println("Running Server")
 	err = ServerMux.ListenAndServe()
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal(err)
-	} else {
-		fmt.Println("Spinning up server")
 	}
-}
-
-func cleanBody(txt string) string {
-	re := regexp.MustCompile("(?i)(kerfuffle|sharbert|fornax)")
-	cleanTxt := re.ReplaceAllString(txt, "****")
-	return cleanTxt
+	// ListenAndServe returns as soon as shutdown starts. Wait for open
+	// requests, then let running jobs finish rather than leave them for the
+	// lease to expire.
+	<-serverDone
+	<-jobsDone
 }
 
 func addTagsToChirp(noTagChirp database.Chirp) Chirp {
@@ -642,12 +900,29 @@ func addTagsToChirp(noTagChirp database.Chirp) Chirp {
 		UpdatedAt: noTagChirp.UpdatedAt,
 		Body:      noTagChirp.Body,
 		UserID:    noTagChirp.UserID,
+		Status:    noTagChirp.Status,
+		ReplyToID: nullUUIDPtr(noTagChirp.ReplyToID),
+	}
+}
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
 	}
+	return &t.Time
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cfg.fileserverHit.Add(1)
+		cfg.fileserverHitUnsent.Add(1)
 		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/David-Bosnic/chirpy/internal/tags"
+	"github.com/google/uuid"
+)
+
+const maxMutedPhraseLength = 100
+
+type MutedWord struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Phrase    string     `json:"phrase"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func formatMutedWord(mutedWord database.MutedWord) MutedWord {
+	return MutedWord{
+		ID:        mutedWord.ID,
+		CreatedAt: mutedWord.CreatedAt,
+		Phrase:    mutedWord.Phrase,
+		ExpiresAt: nullTimePtr(mutedWord.ExpiresAt),
+	}
+}
+
+func (cfg *apiConfig) handlerMutedWordsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	mutedWords, err := cfg.queries.ListActiveMutedWords(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error listing muted words: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list muted words"))
+		return
+	}
+	formattedMutedWords := []MutedWord{}
+	for _, mutedWord := range mutedWords {
+		formattedMutedWords = append(formattedMutedWords, formatMutedWord(mutedWord))
+	}
+	dat, err := json.Marshal(formattedMutedWords)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerMutedWordsCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Phrase    string     `json:"phrase"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding muted word JSON: %s", err)
+		w.WriteHeader(400)
+		w.Write([]byte("Failed to decode muted word"))
+		return
+	}
+	phrase := strings.ToLower(strings.TrimSpace(params.Phrase))
+	if phrase == "" || len(phrase) > maxMutedPhraseLength {
+		w.WriteHeader(400)
+		w.Write([]byte("Muted phrase must be between 1 and 100 characters"))
+		return
+	}
+	if strings.HasPrefix(phrase, "#") {
+		hashtags := tags.Hashtags(phrase)
+		if len(hashtags) != 1 || "#"+hashtags[0] != phrase {
+			w.WriteHeader(400)
+			w.Write([]byte("Muted hashtag is not a valid hashtag"))
+			return
+		}
+	}
+	expiresAt := sql.NullTime{}
+	if params.ExpiresAt != nil {
+		if !params.ExpiresAt.After(time.Now()) {
+			w.WriteHeader(400)
+			w.Write([]byte("expires_at must be in the future"))
+			return
+		}
+		expiresAt = sql.NullTime{Time: params.ExpiresAt.UTC(), Valid: true}
+	}
+
+	mutedWord, err := cfg.queries.CreateMutedWord(r.Context(), database.CreateMutedWordParams{
+		UserID:    userID,
+		Phrase:    phrase,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		log.Printf("Error creating muted word: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to create muted word"))
+		return
+	}
+	dat, err := json.Marshal(formatMutedWord(mutedWord))
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(201)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerMutedWordsDelete(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	mutedWordID, err := uuid.Parse(r.PathValue("mutedWordID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing mutedWordID"))
+		return
+	}
+	deleted, err := cfg.queries.DeleteMutedWord(r.Context(), database.DeleteMutedWordParams{
+		ID:     mutedWordID,
+		UserID: userID,
+	})
+	if err != nil {
+		log.Printf("Error deleting muted word: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to delete muted word"))
+		return
+	}
+	if deleted == 0 {
+		w.WriteHeader(404)
+		w.Write([]byte("Muted word does not exist"))
+		return
+	}
+	w.WriteHeader(204)
+}
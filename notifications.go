@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/David-Bosnic/chirpy/internal/tags"
+	"github.com/google/uuid"
+)
+
+const (
+	notificationLike          = "like"
+	notificationReply         = "reply"
+	notificationMention       = "mention"
+	notificationFollow        = "follow"
+	notificationFollowRequest = "follow_request"
+	notificationRechirp       = "rechirp"
+
+	// maxMentionNotifications caps how many users one chirp can notify by
+	// mentioning them.
+	maxMentionNotifications = 10
+)
+
+// notificationTypes lists every type users can turn off, and whether
+// notifications of that type are grouped by the chirp they are about.
+var notificationTypes = map[string]bool{
+	notificationLike:          true,
+	notificationReply:         false,
+	notificationMention:       false,
+	notificationFollow:        true,
+	notificationFollowRequest: true,
+	notificationRechirp:       true,
+}
+
+// NotificationGroup is one entry in the inbox. Likes, rechirps and follows
+// about the same chirp collapse into a single group listing every actor;
+// replies and mentions are always their own group.
+type NotificationGroup struct {
+	Type            string      `json:"type"`
+	ChirpID         *uuid.UUID  `json:"chirp_id,omitempty"`
+	SourceChirpID   *uuid.UUID  `json:"source_chirp_id,omitempty"`
+	ActorIDs        []uuid.UUID `json:"actor_ids"`
+	NotificationIDs []uuid.UUID `json:"notification_ids"`
+	Read            bool        `json:"read"`
+	CreatedAt       time.Time   `json:"created_at"`
+}
+
+type NotificationsPage struct {
+	Page[NotificationGroup]
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// notify records a notification for an action a request just took. Failures
+// are logged rather than failing the action that caused them.
+func (cfg *apiConfig) notify(ctx context.Context, params database.CreateNotificationParams) {
+	err := cfg.createNotification(ctx, params)
+	if err != nil {
+		log.Printf("Error creating %s notification: %s", params.Type, err)
+	}
+}
+
+// createNotification records a notification and queues it for the user's
+// webhooks in one transaction, then pushes it to connected clients. The
+// query itself skips self-notifications, disabled types, blocked or muted
+// actors, shadow-banned actors and notifications that already exist, so
+// repeating it is safe.
+func (cfg *apiConfig) createNotification(ctx context.Context, params database.CreateNotificationParams) error {
+	var notification database.Notification
+	err := cfg.inTx(ctx, func(q *database.Queries) error {
+		var err error
+		notification, err = q.CreateNotification(ctx, params)
+		if err != nil {
+			return err
+		}
+		return enqueueWebhooks(ctx, q, notification)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cfg.deliver(ctx, notification.UserID, gatewayEvent{
+		Type: gatewayEventNotification,
+		Data: groupNotifications([]database.Notification{notification})[0],
+	})
+	return nil
+}
+
+// unnotify removes the notification for an action that has been undone.
+func (cfg *apiConfig) unnotify(ctx context.Context, userID, actorID uuid.UUID, notificationType string, chirpID uuid.NullUUID) {
+	err := cfg.queries.DeleteNotification(ctx, database.DeleteNotificationParams{
+		UserID:  userID,
+		ActorID: actorID,
+		Type:    notificationType,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		log.Printf("Error deleting %s notification: %s", notificationType, err)
+	}
+}
+
+// notifyChirp notifies the author of the chirp being replied to and the
+// users the chirp mentions. It runs as a job, so an error has it run again;
+// notifications already created are not repeated.
+func (cfg *apiConfig) notifyChirp(ctx context.Context, chirp database.Chirp) error {
+	notified := map[uuid.UUID]bool{}
+	if chirp.ReplyToID.Valid {
+		parent, err := cfg.queries.GetChirp(ctx, chirp.ReplyToID.UUID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil {
+			err = cfg.createNotification(ctx, database.CreateNotificationParams{
+				UserID:        parent.UserID,
+				ActorID:       chirp.UserID,
+				Type:          notificationReply,
+				ChirpID:       uuid.NullUUID{UUID: parent.ID, Valid: true},
+				SourceChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+			notified[parent.UserID] = true
+		}
+	}
+	handles := tags.Mentions(chirp.Body)
+	if len(handles) == 0 {
+		return nil
+	}
+	author, err := cfg.queries.GetUserByID(ctx, chirp.UserID)
+	if err != nil {
+		return err
+	}
+	if len(handles) > maxMentionNotifications {
+		handles = handles[:maxMentionNotifications]
+	}
+	for _, handle := range handles {
+		user, err := cfg.queries.GetUserByHandle(ctx, handle)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if notified[user.ID] {
+			continue
+		}
+		// A protected author's mentions only reach users who can read them.
+		if author.IsProtected {
+			following, err := cfg.queries.IsFollowing(ctx, database.IsFollowingParams{
+				FollowerID: user.ID,
+				FolloweeID: author.ID,
+			})
+			if err != nil {
+				return err
+			}
+			if !following {
+				continue
+			}
+		}
+		err = cfg.createNotification(ctx, database.CreateNotificationParams{
+			UserID:  user.ID,
+			ActorID: chirp.UserID,
+			Type:    notificationMention,
+			ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		notified[user.ID] = true
+	}
+	return nil
+}
+
+// groupNotifications collapses groupable notifications of the same type
+// about the same chirp, keeping the position of the newest one. The input is
+// newest first, as are the actors of each group. A group is read only once
+// all of its notifications are.
+func groupNotifications(notifications []database.Notification) []NotificationGroup {
+	type groupKey struct {
+		notificationType string
+		chirpID          uuid.NullUUID
+	}
+	groups := []NotificationGroup{}
+	index := map[groupKey]int{}
+	for _, notification := range notifications {
+		key := groupKey{notification.Type, notification.ChirpID}
+		i, seen := index[key]
+		if !seen || !notificationTypes[notification.Type] {
+			if notificationTypes[notification.Type] {
+				index[key] = len(groups)
+			}
+			groups = append(groups, NotificationGroup{
+				Type:            notification.Type,
+				ChirpID:         nullUUIDPtr(notification.ChirpID),
+				SourceChirpID:   nullUUIDPtr(notification.SourceChirpID),
+				ActorIDs:        []uuid.UUID{notification.ActorID},
+				NotificationIDs: []uuid.UUID{notification.ID},
+				Read:            notification.ReadAt.Valid,
+				CreatedAt:       notification.CreatedAt,
+			})
+			continue
+		}
+		group := &groups[i]
+		group.ActorIDs = append(group.ActorIDs, notification.ActorID)
+		group.NotificationIDs = append(group.NotificationIDs, notification.ID)
+		group.Read = group.Read && notification.ReadAt.Valid
+	}
+	return groups
+}
+
+func (cfg *apiConfig) handlerNotificationsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	notifications, err := cfg.queries.ListNotifications(r.Context(), database.ListNotificationsParams{
+		UserID:     userID,
+		BeforeTime: cursor.CreatedAt,
+		BeforeID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		log.Printf("Error listing notifications: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list notifications"))
+		return
+	}
+	unread, err := cfg.queries.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error counting unread notifications: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list notifications"))
+		return
+	}
+	page := NotificationsPage{
+		Page:        Page[NotificationGroup]{Items: groupNotifications(notifications)},
+		UnreadCount: unread,
+	}
+	if len(notifications) > 0 {
+		last := notifications[len(notifications)-1]
+		page.NextCursor = nextCursor(len(notifications), limit, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	dat, err := json.Marshal(page)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerNotificationsMarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	// With no notification_ids every unread notification is marked read.
+	type parameters struct {
+		NotificationIDs []uuid.UUID `json:"notification_ids"`
+	}
+	params := parameters{}
+	if r.ContentLength != 0 {
+		err = json.NewDecoder(r.Body).Decode(&params)
+		if err != nil {
+			w.WriteHeader(400)
+			w.Write([]byte("Error decoding parameters"))
+			return
+		}
+	}
+	if params.NotificationIDs == nil {
+		params.NotificationIDs = []uuid.UUID{}
+	}
+	_, err = cfg.queries.MarkNotificationsRead(r.Context(), database.MarkNotificationsReadParams{
+		UserID: userID,
+		Ids:    params.NotificationIDs,
+	})
+	if err != nil {
+		log.Printf("Error marking notifications read: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to mark notifications read"))
+		return
+	}
+	unread, err := cfg.queries.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error counting unread notifications: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to mark notifications read"))
+		return
+	}
+	dat, err := json.Marshal(struct {
+		UnreadCount int64 `json:"unread_count"`
+	}{unread})
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+// notificationPreferences returns every notification type mapped to whether
+// the user receives it. Types without a stored preference are enabled.
+func (cfg *apiConfig) notificationPreferences(ctx context.Context, userID uuid.UUID) (map[string]bool, error) {
+	stored, err := cfg.queries.ListNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	preferences := map[string]bool{}
+	for notificationType := range notificationTypes {
+		preferences[notificationType] = true
+	}
+	for _, preference := range stored {
+		preferences[preference.Type] = preference.Enabled
+	}
+	return preferences, nil
+}
+
+func (cfg *apiConfig) handlerNotificationPreferencesGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	preferences, err := cfg.notificationPreferences(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error listing notification preferences: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to get notification preferences"))
+		return
+	}
+	dat, err := json.Marshal(preferences)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerNotificationPreferencesUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	params := map[string]bool{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error decoding parameters"))
+		return
+	}
+	for notificationType := range params {
+		if _, ok := notificationTypes[notificationType]; !ok {
+			w.WriteHeader(400)
+			w.Write([]byte("Unknown notification type: " + notificationType))
+			return
+		}
+	}
+	for notificationType, enabled := range params {
+		err = cfg.queries.UpsertNotificationPreference(r.Context(), database.UpsertNotificationPreferenceParams{
+			UserID:  userID,
+			Type:    notificationType,
+			Enabled: enabled,
+		})
+		if err != nil {
+			log.Printf("Error updating notification preference: %s", err)
+			w.WriteHeader(500)
+			w.Write([]byte("Failed to update notification preferences"))
+			return
+		}
+	}
+	preferences, err := cfg.notificationPreferences(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error listing notification preferences: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to update notification preferences"))
+		return
+	}
+	dat, err := json.Marshal(preferences)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
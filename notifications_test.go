@@ -0,0 +1,68 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestGroupNotifications(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	chirpA := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	chirpB := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	read := sql.NullTime{Time: now, Valid: true}
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+	notification := func(minutesAgo int, notificationType string, actor uuid.UUID, chirp uuid.NullUUID, readAt sql.NullTime) database.Notification {
+		return database.Notification{
+			ID:        uuid.New(),
+			CreatedAt: now.Add(-time.Duration(minutesAgo) * time.Minute),
+			ActorID:   actor,
+			Type:      notificationType,
+			ChirpID:   chirp,
+			ReadAt:    readAt,
+		}
+	}
+	notifications := []database.Notification{
+		notification(1, notificationLike, alice, chirpA, sql.NullTime{}),
+		notification(2, notificationReply, bob, chirpA, sql.NullTime{}),
+		notification(3, notificationLike, bob, chirpA, read),
+		notification(4, notificationLike, carol, chirpB, read),
+		notification(5, notificationReply, carol, chirpA, read),
+		notification(6, notificationFollow, alice, uuid.NullUUID{}, read),
+		notification(7, notificationFollow, carol, uuid.NullUUID{}, read),
+	}
+
+	groups := groupNotifications(notifications)
+	want := []struct {
+		notificationType string
+		actors           []uuid.UUID
+		read             bool
+	}{
+		{notificationLike, []uuid.UUID{alice, bob}, false},
+		{notificationReply, []uuid.UUID{bob}, false},
+		{notificationLike, []uuid.UUID{carol}, true},
+		{notificationReply, []uuid.UUID{carol}, true},
+		{notificationFollow, []uuid.UUID{alice, carol}, true},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("Got %d groups, Expected %d: %+v", len(groups), len(want), groups)
+	}
+	for i, w := range want {
+		group := groups[i]
+		if group.Type != w.notificationType || group.Read != w.read || len(group.ActorIDs) != len(w.actors) {
+			t.Errorf("Case %d: Got: %+v, Expected: %+v", i, group, w)
+			continue
+		}
+		for j, actor := range w.actors {
+			if group.ActorIDs[j] != actor {
+				t.Errorf("Case %d: actor %d Got: %s, Expected: %s", i, j, group.ActorIDs[j], actor)
+			}
+		}
+	}
+	if !groups[0].CreatedAt.Equal(now.Add(-time.Minute)) {
+		t.Errorf("Expected a group to take the time of its newest notification, got %s", groups[0].CreatedAt)
+	}
+}
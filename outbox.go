@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/David-Bosnic/chirpy/internal/outbox"
+	"github.com/google/uuid"
+)
+
+const (
+	// outboxPollInterval is how often the outbox is checked for events
+	// written by other instances or due for a retry. Events written here
+	// wake the dispatcher straight away.
+	outboxPollInterval  = 5 * time.Second
+	outboxPruneInterval = time.Hour
+	// outboxRetention is how long published events are kept for debugging.
+	outboxRetention = 24 * time.Hour
+)
+
+// Events written to the outbox alongside the change that caused them.
+const (
+	outboxChirpCreated = "chirp.created"
+)
+
+// outboxStore reads the outbox table for the dispatcher.
+type outboxStore struct {
+	queries *database.Queries
+}
+
+func (s outboxStore) Claim(ctx context.Context, leaseUntil time.Time, limit int32) ([]outbox.Message, error) {
+	rows, err := s.queries.ClaimOutboxEvents(ctx, database.ClaimOutboxEventsParams{
+		LeaseUntil: leaseUntil,
+		BatchSize:  limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]outbox.Message, 0, len(rows))
+	for _, row := range rows {
+		messages = append(messages, outbox.Message{
+			ID:          row.ID,
+			Type:        row.EventType,
+			Payload:     json.RawMessage(row.Payload),
+			Attempts:    row.Attempts,
+			MaxAttempts: row.MaxAttempts,
+		})
+	}
+	return messages, nil
+}
+
+func (s outboxStore) MarkPublished(ctx context.Context, id uuid.UUID) error {
+	return s.queries.MarkOutboxEventPublished(ctx, id)
+}
+
+func (s outboxStore) Retry(ctx context.Context, id uuid.UUID, availableAt time.Time, lastError string) error {
+	return s.queries.RetryOutboxEvent(ctx, database.RetryOutboxEventParams{
+		ID:          id,
+		AvailableAt: availableAt,
+		LastError:   lastError,
+	})
+}
+
+func (s outboxStore) Kill(ctx context.Context, id uuid.UUID, lastError string) error {
+	return s.queries.KillOutboxEvent(ctx, database.KillOutboxEventParams{
+		ID:        id,
+		LastError: lastError,
+	})
+}
+
+// inTx runs fn with queries bound to a transaction, committing if fn
+// succeeds. Outbox events and jobs fn writes are picked up once it commits.
+func (cfg *apiConfig) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = fn(cfg.queries.WithTx(tx))
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	cfg.outbox.Wake()
+	cfg.jobs.Wake()
+	return nil
+}
+
+// enqueue writes an event to the outbox. q should be bound to the
+// transaction making the change the event describes.
+func enqueue(ctx context.Context, q *database.Queries, eventType string, data any) error {
+	payload, err := outbox.Encode(data)
+	if err != nil {
+		return err
+	}
+	return q.CreateOutboxEvent(ctx, database.CreateOutboxEventParams{
+		EventType: eventType,
+		Payload:   payload,
+	})
+}
+
+// subscribeOutbox registers the side effects of outbox events. They run at
+// least once, so each must tolerate repeating.
+func (cfg *apiConfig) subscribeOutbox() {
+	cfg.outbox.Subscribe(outboxChirpCreated, func(ctx context.Context, data json.RawMessage) error {
+		chirp := database.Chirp{}
+		err := json.Unmarshal(data, &chirp)
+		if err != nil {
+			// Retrying will not fix a payload that cannot be read.
+			log.Printf("Error decoding %s outbox event: %s", outboxChirpCreated, err)
+			return nil
+		}
+		// Notifications are a job of their own, so nothing here can fail
+		// and have the chirp streamed again.
+		if chirp.Status == chirpStatusPublished {
+			cfg.publish(ctx, eventChirpCreated, chirp)
+		}
+		return nil
+	})
+}
+
+// pruneOutbox deletes old published events every outboxPruneInterval
+// until ctx is done.
+func (cfg *apiConfig) pruneOutbox(ctx context.Context) {
+	ticker := time.NewTicker(outboxPruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := cfg.queries.DeletePublishedOutboxEvents(ctx, time.Now().UTC().Add(-outboxRetention))
+		if err != nil {
+			log.Printf("Error pruning outbox: %s", err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Page is one page of a cursor-paginated listing. Pass NextCursor back as
+// the cursor query parameter to get the following page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// pageCursor points just past the last item of a page, ordered by creation
+// time and then ID, both descending.
+type pageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// firstPage sorts after every real row.
+var firstPage = pageCursor{
+	CreatedAt: time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC),
+	ID:        uuid.Max,
+}
+
+func (c pageCursor) String() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parsePageCursor(s string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, err
+	}
+	createdAt, id, found := strings.Cut(string(raw), "|")
+	if !found {
+		return pageCursor{}, fmt.Errorf("cursor is missing its ID")
+	}
+	cursor := pageCursor{}
+	cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return pageCursor{}, err
+	}
+	cursor.ID, err = uuid.Parse(id)
+	if err != nil {
+		return pageCursor{}, err
+	}
+	return cursor, nil
+}
+
+// parsePage reads the cursor and limit query parameters.
+func parsePage(r *http.Request) (pageCursor, int32, error) {
+	cursor := firstPage
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		var err error
+		cursor, err = parsePageCursor(s)
+		if err != nil {
+			return pageCursor{}, 0, fmt.Errorf("invalid cursor: %w", err)
+		}
+	}
+	limit := defaultPageSize
+	if s := r.URL.Query().Get("limit"); s != "" {
+		var err error
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return pageCursor{}, 0, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+	}
+	return cursor, int32(limit), nil
+}
+
+// nextCursor is the cursor for the page after one that ended with last, or
+// empty when the page came back short and there is nothing more.
+func nextCursor(returned int, limit int32, last pageCursor) string {
+	if returned < int(limit) {
+		return ""
+	}
+	return last.String()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPageCursorRoundTrip(t *testing.T) {
+	cursor := pageCursor{
+		CreatedAt: time.Date(2025, 3, 4, 5, 6, 7, 891011, time.UTC),
+		ID:        uuid.New(),
+	}
+	parsed, err := parsePageCursor(cursor.String())
+	if err != nil {
+		t.Fatalf("Error parsing cursor: %s", err)
+	}
+	if !parsed.CreatedAt.Equal(cursor.CreatedAt) || parsed.ID != cursor.ID {
+		t.Errorf("Got: %+v, Expected: %+v", parsed, cursor)
+	}
+}
+
+func TestParsePage(t *testing.T) {
+	cursor, limit, err := parsePage(httptest.NewRequest("GET", "/api/users/me/followers", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cursor != firstPage || limit != defaultPageSize {
+		t.Errorf("Expected first page defaults, got %+v and %d", cursor, limit)
+	}
+	for _, query := range []string{"?limit=0", "?limit=101", "?limit=abc", "?cursor=not-a-cursor"} {
+		_, _, err := parsePage(httptest.NewRequest("GET", "/api/users/me/followers"+query, nil))
+		if err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type Relationship struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// relationshipTarget authenticates the caller and resolves the {id} path
+// value to an existing user other than the caller. It writes the error
+// response itself and returns ok=false when the request should stop.
+func (cfg *apiConfig) relationshipTarget(w http.ResponseWriter, r *http.Request) (userID, targetID uuid.UUID, ok bool) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	targetID, ok = cfg.userIDFromPath(w, r)
+	if !ok {
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	if targetID == userID {
+		w.WriteHeader(400)
+		w.Write([]byte("Cannot do that to yourself"))
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	_, err = cfg.queries.GetUserByID(r.Context(), targetID)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("User does not exist"))
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	return userID, targetID, true
+}
+
+func (cfg *apiConfig) handlerBlocksCreate(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationshipTarget(w, r)
+	if !ok {
+		return
+	}
+	// Blocking removes everything connecting the two users, all or nothing,
+	// so a failure never leaves a block with the follow it should have cut.
+	err := cfg.inTx(r.Context(), func(q *database.Queries) error {
+		err := q.CreateBlock(r.Context(), database.CreateBlockParams{
+			BlockerID: userID,
+			BlockedID: targetID,
+		})
+		if err != nil {
+			return fmt.Errorf("creating block: %w", err)
+		}
+		err = q.DeleteFollowsBetween(r.Context(), database.DeleteFollowsBetweenParams{
+			FollowerID: userID,
+			FolloweeID: targetID,
+		})
+		if err != nil {
+			return fmt.Errorf("removing follows: %w", err)
+		}
+		err = q.DeleteFollowRequestsBetween(r.Context(), database.DeleteFollowRequestsBetweenParams{
+			RequesterID: userID,
+			TargetID:    targetID,
+		})
+		if err != nil {
+			return fmt.Errorf("removing follow requests: %w", err)
+		}
+		err = q.DeleteListMembershipsBetween(r.Context(), database.DeleteListMembershipsBetweenParams{
+			OwnerID: userID,
+			UserID:  targetID,
+		})
+		if err != nil {
+			return fmt.Errorf("removing list memberships: %w", err)
+		}
+		err = q.DeleteNotificationsBetween(r.Context(), database.DeleteNotificationsBetweenParams{
+			UserID:  userID,
+			ActorID: targetID,
+		})
+		if err != nil {
+			return fmt.Errorf("removing notifications: %w", err)
+		}
+		err = q.DeleteTimelineEntriesBetween(r.Context(), database.DeleteTimelineEntriesBetweenParams{
+			UserID:   userID,
+			AuthorID: targetID,
+		})
+		if err != nil {
+			return fmt.Errorf("removing timeline entries: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error blocking user: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to block user"))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerBlocksDelete(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationshipTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.queries.DeleteBlock(r.Context(), database.DeleteBlockParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+	})
+	if err != nil {
+		log.Printf("Error deleting block: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to unblock user"))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerBlocksList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	blocks, err := cfg.queries.ListBlocks(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error listing blocks: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list blocks"))
+		return
+	}
+	relationships := []Relationship{}
+	for _, block := range blocks {
+		relationships = append(relationships, Relationship{
+			UserID:    block.BlockedID,
+			CreatedAt: block.CreatedAt,
+		})
+	}
+	dat, err := json.Marshal(relationships)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerMutesCreate(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationshipTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.queries.CreateMute(r.Context(), database.CreateMuteParams{
+		MuterID: userID,
+		MutedID: targetID,
+	})
+	if err != nil {
+		log.Printf("Error creating mute: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to mute user"))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerMutesDelete(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationshipTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.queries.DeleteMute(r.Context(), database.DeleteMuteParams{
+		MuterID: userID,
+		MutedID: targetID,
+	})
+	if err != nil {
+		log.Printf("Error deleting mute: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to unmute user"))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlerMutesList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	mutes, err := cfg.queries.ListMutes(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error listing mutes: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list mutes"))
+		return
+	}
+	relationships := []Relationship{}
+	for _, mute := range mutes {
+		relationships = append(relationships, Relationship{
+			UserID:    mute.MutedID,
+			CreatedAt: mute.CreatedAt,
+		})
+	}
+	dat, err := json.Marshal(relationships)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/David-Bosnic/chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+const (
+	reportStatusOpen     = "open"
+	reportStatusClaimed  = "claimed"
+	reportStatusResolved = "resolved"
+
+	reportCategoryAutomated = "automated"
+
+	resolutionDismiss       = "dismiss"
+	resolutionHideChirp     = "hide_chirp"
+	resolutionSuspendAuthor = "suspend_author"
+
+	actionClaimReport = "claim_report"
+	actionDismiss     = "dismiss_report"
+	actionHoldChirp   = "hold_chirp"
+	actionHideChirp   = "hide_chirp"
+	actionSuspendUser = "suspend_user"
+
+	chirpStatusHidden = "hidden"
+)
+
+var reportCategories = map[string]bool{
+	"spam":           true,
+	"harassment":     true,
+	"hate":           true,
+	"violence":       true,
+	"sexual_content": true,
+	"misinformation": true,
+	"impersonation":  true,
+	"other":          true,
+}
+
+type Report struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	ReporterID     *uuid.UUID `json:"reporter_id"`
+	ReportedUserID uuid.UUID  `json:"reported_user_id"`
+	ChirpID        *uuid.UUID `json:"chirp_id,omitempty"`
+	Category       string     `json:"category"`
+	Details        string     `json:"details"`
+	Status         string     `json:"status"`
+	ClaimedBy      *uuid.UUID `json:"claimed_by,omitempty"`
+	ClaimedAt      *time.Time `json:"claimed_at,omitempty"`
+	Resolution     string     `json:"resolution,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+}
+
+type ModerationAction struct {
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ModeratorID   *uuid.UUID `json:"moderator_id"`
+	Action        string     `json:"action"`
+	ReportID      *uuid.UUID `json:"report_id,omitempty"`
+	TargetUserID  uuid.UUID  `json:"target_user_id"`
+	TargetChirpID *uuid.UUID `json:"target_chirp_id,omitempty"`
+	Reason        string     `json:"reason"`
+	AppealID      *uuid.UUID `json:"appeal_id,omitempty"`
+}
+
+func formatReport(report database.Report) Report {
+	return Report{
+		ID:             report.ID,
+		CreatedAt:      report.CreatedAt,
+		UpdatedAt:      report.UpdatedAt,
+		ReporterID:     nullUUIDPtr(report.ReporterID),
+		ReportedUserID: report.ReportedUserID,
+		ChirpID:        nullUUIDPtr(report.ChirpID),
+		Category:       report.Category,
+		Details:        report.Details,
+		Status:         report.Status,
+		ClaimedBy:      nullUUIDPtr(report.ClaimedBy),
+		ClaimedAt:      nullTimePtr(report.ClaimedAt),
+		Resolution:     report.Resolution.String,
+		ResolvedAt:     nullTimePtr(report.ResolvedAt),
+	}
+}
+
+func formatModerationAction(action database.ModerationAction) ModerationAction {
+	return ModerationAction{
+		ID:            action.ID,
+		CreatedAt:     action.CreatedAt,
+		ModeratorID:   nullUUIDPtr(action.ModeratorID),
+		Action:        action.Action,
+		ReportID:      nullUUIDPtr(action.ReportID),
+		TargetUserID:  action.TargetUserID,
+		TargetChirpID: nullUUIDPtr(action.TargetChirpID),
+		Reason:        action.Reason,
+		AppealID:      nullUUIDPtr(action.AppealID),
+	}
+}
+
+// authenticateModerator is authenticate for the moderation endpoints under
+// /admin: the bearer JWT must belong to a user flagged as a moderator.
+func (cfg *apiConfig) authenticateModerator(r *http.Request) (database.User, error) {
+	user, err := cfg.authenticateUser(r)
+	if err != nil {
+		return database.User{}, err
+	}
+	if !user.IsModerator {
+		return database.User{}, fmt.Errorf("user %s is not a moderator", user.ID)
+	}
+	return user, nil
+}
+
+// queueHeldChirp puts a chirp the moderation pipeline held into the review
+// queue as a report with no reporter.
+func (cfg *apiConfig) queueHeldChirp(ctx context.Context, q *database.Queries, chirp database.Chirp, outcome moderation.Outcome) error {
+	reason := strings.Join(outcome.Reasons, "; ")
+	report, err := q.CreateReport(ctx, database.CreateReportParams{
+		ReportedUserID: chirp.UserID,
+		ChirpID:        uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		Category:       reportCategoryAutomated,
+		Details:        reason,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = q.CreateModerationAction(ctx, database.CreateModerationActionParams{
+		Action:        actionHoldChirp,
+		ReportID:      uuid.NullUUID{UUID: report.ID, Valid: true},
+		TargetUserID:  chirp.UserID,
+		TargetChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		Reason:        reason,
+	})
+	return err
+}
+
+func (cfg *apiConfig) handlerChirpReportsCreate(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing chirpID"))
+		return
+	}
+	chirp, err := cfg.queries.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("Chirp does not exist"))
+		return
+	}
+	cfg.createReport(w, r, chirp.UserID, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+}
+
+func (cfg *apiConfig) handlerUserReportsCreate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	user, err := cfg.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("User does not exist"))
+		return
+	}
+	cfg.createReport(w, r, user.ID, uuid.NullUUID{})
+}
+
+func (cfg *apiConfig) createReport(w http.ResponseWriter, r *http.Request, reportedUserID uuid.UUID, chirpID uuid.NullUUID) {
+	type parameters struct {
+		Category string `json:"category"`
+		Details  string `json:"details"`
+	}
+	reporterID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding report JSON: %s", err)
+		w.WriteHeader(400)
+		w.Write([]byte("Failed to decode report"))
+		return
+	}
+	if !reportCategories[params.Category] {
+		w.WriteHeader(400)
+		w.Write([]byte("Unknown report category"))
+		return
+	}
+	if reporterID == reportedUserID {
+		w.WriteHeader(400)
+		w.Write([]byte("Cannot report yourself"))
+		return
+	}
+
+	reporter := uuid.NullUUID{UUID: reporterID, Valid: true}
+	report, err := cfg.queries.CreateReport(r.Context(), database.CreateReportParams{
+		ReporterID:     reporter,
+		ReportedUserID: reportedUserID,
+		ChirpID:        chirpID,
+		Category:       params.Category,
+		Details:        params.Details,
+	})
+	status := 201
+	if errors.Is(err, sql.ErrNoRows) {
+		// The reporter already filed this report, so hand back the original.
+		status = 200
+		if chirpID.Valid {
+			report, err = cfg.queries.GetReportByReporterAndChirp(r.Context(), database.GetReportByReporterAndChirpParams{
+				ReporterID: reporter,
+				ChirpID:    chirpID,
+			})
+		} else {
+			report, err = cfg.queries.GetReportByReporterAndUser(r.Context(), database.GetReportByReporterAndUserParams{
+				ReporterID:     reporter,
+				ReportedUserID: reportedUserID,
+			})
+		}
+	}
+	if err != nil {
+		log.Printf("Error creating report: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to create report"))
+		return
+	}
+
+	dat, err := json.Marshal(formatReport(report))
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerAdminReportsList(w http.ResponseWriter, r *http.Request) {
+	_, err := cfg.authenticateModerator(r)
+	if err != nil {
+		log.Printf("Error failed to authenticate moderator: %s\n", err)
+		w.WriteHeader(403)
+		w.Write([]byte("Moderator access required"))
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = reportStatusOpen
+	}
+	if status != reportStatusOpen && status != reportStatusClaimed && status != reportStatusResolved {
+		w.WriteHeader(400)
+		w.Write([]byte("Unknown report status"))
+		return
+	}
+	reports, err := cfg.queries.ListReportsByStatus(r.Context(), status)
+	if err != nil {
+		log.Printf("Error listing reports: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list reports"))
+		return
+	}
+	formattedReports := []Report{}
+	for _, report := range reports {
+		formattedReports = append(formattedReports, formatReport(report))
+	}
+	dat, err := json.Marshal(formattedReports)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerAdminReportsGet(w http.ResponseWriter, r *http.Request) {
+	_, err := cfg.authenticateModerator(r)
+	if err != nil {
+		log.Printf("Error failed to authenticate moderator: %s\n", err)
+		w.WriteHeader(403)
+		w.Write([]byte("Moderator access required"))
+		return
+	}
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing reportID"))
+		return
+	}
+	report, err := cfg.queries.GetReport(r.Context(), reportID)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("Report does not exist"))
+		return
+	}
+	actions, err := cfg.queries.ListModerationActionsForReport(r.Context(), uuid.NullUUID{UUID: report.ID, Valid: true})
+	if err != nil {
+		log.Printf("Error listing moderation actions: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list moderation actions"))
+		return
+	}
+	resp := struct {
+		Report
+		Actions []ModerationAction `json:"actions"`
+	}{
+		Report:  formatReport(report),
+		Actions: []ModerationAction{},
+	}
+	for _, action := range actions {
+		resp.Actions = append(resp.Actions, formatModerationAction(action))
+	}
+	dat, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerAdminReportsClaim(w http.ResponseWriter, r *http.Request) {
+	moderator, err := cfg.authenticateModerator(r)
+	if err != nil {
+		log.Printf("Error failed to authenticate moderator: %s\n", err)
+		w.WriteHeader(403)
+		w.Write([]byte("Moderator access required"))
+		return
+	}
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing reportID"))
+		return
+	}
+	moderatorID := uuid.NullUUID{UUID: moderator.ID, Valid: true}
+	var report database.Report
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		report, err = q.ClaimReport(r.Context(), database.ClaimReportParams{
+			ID:        reportID,
+			ClaimedBy: moderatorID,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = q.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			ModeratorID:   moderatorID,
+			Action:        actionClaimReport,
+			ReportID:      uuid.NullUUID{UUID: report.ID, Valid: true},
+			TargetUserID:  report.ReportedUserID,
+			TargetChirpID: report.ChirpID,
+		})
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(409)
+		w.Write([]byte("Report does not exist or is not open"))
+		return
+	}
+	if err != nil {
+		log.Printf("Error claiming report: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to claim report"))
+		return
+	}
+
+	dat, err := json.Marshal(formatReport(report))
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+// applyResolution changes the chirp or account a resolved report is about.
+// Dismissing a report on a held chirp approves the chirp.
+func (cfg *apiConfig) applyResolution(ctx context.Context, q *database.Queries, report database.Report, resolution, reason string) error {
+	switch resolution {
+	case resolutionDismiss:
+		if !report.ChirpID.Valid {
+			return nil
+		}
+		chirp, err := q.GetChirp(ctx, report.ChirpID.UUID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if chirp.Status != chirpStatusHeld {
+			return nil
+		}
+		return publishHeldChirp(ctx, q, chirp)
+	case resolutionHideChirp:
+		return q.UpdateChirpStatus(ctx, database.UpdateChirpStatusParams{
+			Status: chirpStatusHidden,
+			ID:     report.ChirpID.UUID,
+		})
+	case resolutionSuspendAuthor:
+		return cfg.setAccountStatus(ctx, q, report.ReportedUserID, accountStatusSuspended, sql.NullTime{}, reason)
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerAdminReportsResolve(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Resolution string `json:"resolution"`
+		Reason     string `json:"reason"`
+	}
+	moderator, err := cfg.authenticateModerator(r)
+	if err != nil {
+		log.Printf("Error failed to authenticate moderator: %s\n", err)
+		w.WriteHeader(403)
+		w.Write([]byte("Moderator access required"))
+		return
+	}
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing reportID"))
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding resolution JSON: %s", err)
+		w.WriteHeader(400)
+		w.Write([]byte("Failed to decode resolution"))
+		return
+	}
+	report, err := cfg.queries.GetReport(r.Context(), reportID)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("Report does not exist"))
+		return
+	}
+
+	var action string
+	switch params.Resolution {
+	case resolutionDismiss:
+		action = actionDismiss
+	case resolutionHideChirp:
+		if !report.ChirpID.Valid {
+			w.WriteHeader(400)
+			w.Write([]byte("Report is not about a chirp"))
+			return
+		}
+		action = actionHideChirp
+	case resolutionSuspendAuthor:
+		action = actionSuspendUser
+	default:
+		w.WriteHeader(400)
+		w.Write([]byte("Unknown resolution"))
+		return
+	}
+
+	moderatorID := uuid.NullUUID{UUID: moderator.ID, Valid: true}
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		report, err = q.ResolveReport(r.Context(), database.ResolveReportParams{
+			ID:         report.ID,
+			Resolution: sql.NullString{String: params.Resolution, Valid: true},
+			ClaimedBy:  moderatorID,
+		})
+		if err != nil {
+			return err
+		}
+		err = cfg.applyResolution(r.Context(), q, report, params.Resolution, params.Reason)
+		if err != nil {
+			return err
+		}
+		_, err = q.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			ModeratorID:   moderatorID,
+			Action:        action,
+			ReportID:      uuid.NullUUID{UUID: report.ID, Valid: true},
+			TargetUserID:  report.ReportedUserID,
+			TargetChirpID: report.ChirpID,
+			Reason:        params.Reason,
+		})
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(409)
+		w.Write([]byte("Report must be claimed by you before it is resolved"))
+		return
+	}
+	if err != nil {
+		log.Printf("Error resolving report: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to resolve report"))
+		return
+	}
+
+	dat, err := json.Marshal(formatReport(report))
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
@@ -0,0 +1,32 @@
+-- name: CreateAppeal :one
+INSERT INTO appeals (id, created_at, updated_at, user_id, action_id, statement)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3
+)
+ON CONFLICT (action_id) DO NOTHING
+RETURNING *;
+
+-- name: GetAppeal :one
+SELECT * FROM appeals
+WHERE id = $1;
+
+-- name: ListAppealsByStatus :many
+SELECT * FROM appeals
+WHERE status = $1
+ORDER BY created_at ASC;
+
+-- name: ListAppealsForUser :many
+SELECT * FROM appeals
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: DecideAppeal :one
+UPDATE appeals
+SET
+    status = $2,
+    decided_by = $3,
+    decided_at = NOW(),
+    decision_note = $4,
+    updated_at = NOW()
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
@@ -1,12 +1,13 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, status, reply_to_id)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4
 )
 RETURNING *;
 
 -- name: ListChirps :many
 SELECT * FROM chirps
+WHERE status = 'published'
 ORDER BY created_at ASC;
 
 -- name: GetChirp :one
@@ -15,9 +16,23 @@ WHERE id = $1;
 
 -- name: ListChirpsFromAuthorID :many
 SELECT * FROM chirps
-WHERE user_id = $1
+WHERE user_id = $1 AND status = 'published'
 ORDER BY created_at ASC;
 
+-- name: ListRecentChirpsFromAuthorID :many
+SELECT * FROM chirps
+WHERE user_id = $1 AND created_at > $2
+ORDER BY created_at DESC;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET
+    body = $1,
+    status = $2,
+    updated_at = NOW()
+WHERE id = $3
+RETURNING *;
+
 -- name: DeleteAllChirps :exec
 DELETE FROM chirps;
 
@@ -25,3 +40,43 @@ DELETE FROM chirps;
 DELETE FROM chirps
 WHERE id = $1;
 
+-- name: UpdateChirpStatus :exec
+UPDATE chirps
+SET
+    status = $1,
+    updated_at = NOW()
+WHERE id = $2;
+
+-- name: ListRecentPublishedChirps :many
+SELECT * FROM chirps
+WHERE status = 'published' AND created_at > $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: ListPublishedChirpsAfter :many
+SELECT chirps.* FROM chirps
+WHERE chirps.status = 'published'
+    AND (chirps.created_at, chirps.id) > (@after_time::timestamp, @after_id::uuid)
+    AND chirps.created_at < @until_time::timestamp
+    AND NOT EXISTS (
+        SELECT 1 FROM users
+        WHERE users.id = chirps.user_id
+            AND users.account_status = 'shadow_banned'
+            AND (users.account_status_until IS NULL OR users.account_status_until > NOW())
+    )
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT @batch_size;
+
+-- name: ListPublicChirpsAfter :many
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.status = 'published'
+    AND (chirps.created_at, chirps.id) > (@after_time::timestamp, @after_id::uuid)
+    AND chirps.created_at < @until_time::timestamp
+    AND NOT users.is_protected
+    AND NOT (
+        users.account_status = 'shadow_banned'
+        AND (users.account_status_until IS NULL OR users.account_status_until > NOW())
+    )
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT @batch_size;
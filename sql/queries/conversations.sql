@@ -0,0 +1,88 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, creator_id, is_group, last_message_at)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, NOW()
+)
+RETURNING *;
+
+-- name: GetConversation :one
+SELECT * FROM conversations
+WHERE id = $1;
+
+-- name: FindDirectConversation :one
+SELECT conversations.* FROM conversations
+JOIN conversation_members AS mine ON mine.conversation_id = conversations.id
+JOIN conversation_members AS theirs ON theirs.conversation_id = conversations.id
+WHERE NOT conversations.is_group
+    AND mine.user_id = @user_id AND mine.left_at IS NULL
+    AND theirs.user_id = @other_id AND theirs.left_at IS NULL
+ORDER BY conversations.created_at DESC
+LIMIT 1;
+
+-- name: ListConversationsForUser :many
+SELECT conversations.*, (
+    SELECT COUNT(*) FROM messages
+    WHERE messages.conversation_id = conversations.id
+        AND messages.sender_id <> @user_id
+        AND messages.created_at > COALESCE(conversation_members.last_read_at, conversation_members.joined_at)
+) AS unread_count
+FROM conversations
+JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversation_members.user_id = @user_id
+    AND conversation_members.left_at IS NULL
+    AND (conversations.last_message_at, conversations.id) < (@before_time::timestamp, @before_id::uuid)
+ORDER BY conversations.last_message_at DESC, conversations.id DESC
+LIMIT @page_size;
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET
+    last_message_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: AddConversationMember :exec
+INSERT INTO conversation_members (conversation_id, user_id, joined_at)
+VALUES (
+    $1, $2, NOW()
+);
+
+-- name: GetConversationMember :one
+SELECT * FROM conversation_members
+WHERE conversation_id = $1 AND user_id = $2;
+
+-- name: ListConversationMembers :many
+SELECT * FROM conversation_members
+WHERE conversation_id = $1
+ORDER BY joined_at, user_id;
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_members
+SET last_read_at = @read_at::timestamp
+WHERE conversation_id = @conversation_id
+    AND user_id = @user_id
+    AND (last_read_at IS NULL OR last_read_at < @read_at::timestamp);
+
+-- name: LeaveConversation :execrows
+UPDATE conversation_members
+SET left_at = NOW()
+WHERE conversation_id = $1 AND user_id = $2 AND left_at IS NULL;
+
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetMessage :one
+SELECT * FROM messages
+WHERE id = $1;
+
+-- name: ListMessages :many
+SELECT * FROM messages
+WHERE conversation_id = @conversation_id
+    AND created_at >= @joined_at
+    AND (created_at, id) < (@before_time::timestamp, @before_id::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT @page_size;
@@ -0,0 +1,2 @@
+-- name: NotifyEvent :exec
+SELECT pg_notify(@channel::text, @payload::text);
@@ -0,0 +1,33 @@
+-- name: CreateFollowRequest :exec
+INSERT INTO follow_requests (requester_id, target_id, created_at)
+VALUES (
+    $1, $2, NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteFollowRequest :execrows
+DELETE FROM follow_requests
+WHERE requester_id = $1 AND target_id = $2;
+
+-- name: DeleteFollowRequestsBetween :exec
+DELETE FROM follow_requests
+WHERE (requester_id = $1 AND target_id = $2)
+   OR (requester_id = $2 AND target_id = $1);
+
+-- name: ListFollowRequests :many
+SELECT * FROM follow_requests
+WHERE target_id = @target_id
+    AND (created_at, requester_id) < (@before_time::timestamp, @before_id::uuid)
+ORDER BY created_at DESC, requester_id DESC
+LIMIT @page_size;
+
+-- name: ApproveAllFollowRequests :many
+WITH approved AS (
+    DELETE FROM follow_requests
+    WHERE target_id = $1
+    RETURNING requester_id, target_id
+)
+INSERT INTO follows (follower_id, followee_id, created_at)
+SELECT requester_id, target_id, NOW() FROM approved
+ON CONFLICT DO NOTHING
+RETURNING follower_id;
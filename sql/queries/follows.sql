@@ -0,0 +1,58 @@
+-- name: CreateFollow :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+    $1, $2, NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteFollow :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = $1 AND followee_id = $2)
+   OR (follower_id = $2 AND followee_id = $1);
+
+-- name: ListFollowers :many
+SELECT * FROM follows
+WHERE followee_id = @followee_id
+    AND (created_at, follower_id) < (@before_time::timestamp, @before_id::uuid)
+ORDER BY created_at DESC, follower_id DESC
+LIMIT @page_size;
+
+-- name: ListFollowing :many
+SELECT * FROM follows
+WHERE follower_id = @follower_id
+    AND (created_at, followee_id) < (@before_time::timestamp, @before_id::uuid)
+ORDER BY created_at DESC, followee_id DESC
+LIMIT @page_size;
+
+-- name: CountFollowers :one
+SELECT COUNT(*) FROM follows
+WHERE followee_id = $1;
+
+-- name: CountFollowing :one
+SELECT COUNT(*) FROM follows
+WHERE follower_id = $1;
+
+-- name: IsFollowing :one
+SELECT EXISTS (
+    SELECT 1 FROM follows
+    WHERE follower_id = $1 AND followee_id = $2
+);
+
+-- name: ListFolloweeIDs :many
+SELECT followee_id FROM follows
+WHERE follower_id = $1;
+
+-- name: ListFriendsOfFriends :many
+SELECT second.followee_id AS user_id, COUNT(*) AS mutual_count
+FROM follows AS first
+JOIN follows AS second ON second.follower_id = first.followee_id
+WHERE first.follower_id = @user_id
+    AND second.followee_id <> @user_id
+    AND second.followee_id NOT IN (SELECT followee_id FROM follows WHERE follower_id = @user_id)
+GROUP BY second.followee_id
+ORDER BY mutual_count DESC
+LIMIT @candidate_limit;
@@ -0,0 +1,15 @@
+-- name: CreateHandleHistory :exec
+INSERT INTO handle_history (id, user_id, handle, released_at)
+VALUES (
+    gen_random_uuid(), $1, $2, NOW()
+);
+
+-- name: GetUserIDByReleasedHandle :one
+SELECT user_id FROM handle_history
+WHERE LOWER(handle) = LOWER(@handle) AND released_at > @released_after
+ORDER BY released_at DESC
+LIMIT 1;
+
+-- name: CountHandleChangesSince :one
+SELECT COUNT(*) FROM handle_history
+WHERE user_id = $1 AND released_at > $2;
@@ -0,0 +1,74 @@
+-- name: CreateJob :exec
+INSERT INTO jobs (id, created_at, updated_at, type, payload, priority, max_attempts, run_at, unique_key)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6)
+ON CONFLICT DO NOTHING;
+
+-- name: ClaimJobs :many
+UPDATE jobs
+SET status = 'running', attempts = attempts + 1, locked_until = @lease_until::timestamp, updated_at = NOW()
+WHERE id IN (
+    SELECT due.id FROM jobs AS due
+    WHERE due.type = ANY(@types::text[])
+        AND (
+            (due.status = 'pending' AND due.run_at <= NOW())
+            OR (due.status = 'running' AND due.locked_until < NOW())
+        )
+    ORDER BY due.priority DESC, due.run_at
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteJob :exec
+UPDATE jobs
+SET status = 'succeeded', locked_until = NULL, finished_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: RetryJob :exec
+UPDATE jobs
+SET status = 'pending', run_at = $2, last_error = $3, locked_until = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: ReleaseJob :exec
+UPDATE jobs
+SET status = 'pending', attempts = GREATEST(attempts - 1, 0), run_at = NOW(), last_error = $2, locked_until = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: KillJob :exec
+UPDATE jobs
+SET status = 'dead', last_error = $2, locked_until = NULL, finished_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetJob :one
+SELECT * FROM jobs
+WHERE id = $1;
+
+-- name: ListJobs :many
+SELECT * FROM jobs
+WHERE status = @status
+    AND (@type::text = '' OR type = @type::text)
+    AND (created_at, id) < (@before_time::timestamp, @before_id::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT @page_size;
+
+-- name: CountJobsByStatus :many
+SELECT type, status, COUNT(*) AS count
+FROM jobs
+GROUP BY type, status
+ORDER BY type, status;
+
+-- name: RequeueDeadJob :one
+UPDATE jobs
+SET status = 'pending', attempts = 0, run_at = NOW(), finished_at = NULL, updated_at = NOW()
+WHERE id = $1
+    AND status = 'dead'
+    AND NOT EXISTS (
+        SELECT 1 FROM jobs AS queued
+        WHERE queued.unique_key = jobs.unique_key
+            AND queued.status IN ('pending', 'running')
+    )
+RETURNING *;
+
+-- name: DeleteSucceededJobs :exec
+DELETE FROM jobs
+WHERE status = 'succeeded' AND finished_at < $1;
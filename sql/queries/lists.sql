@@ -0,0 +1,87 @@
+-- name: CreateList :one
+INSERT INTO lists (id, created_at, updated_at, owner_id, name, description, is_private)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetList :one
+SELECT * FROM lists
+WHERE id = $1;
+
+-- name: UpdateList :one
+UPDATE lists
+SET
+    name = $1,
+    description = $2,
+    is_private = $3,
+    updated_at = NOW()
+WHERE id = $4
+RETURNING *;
+
+-- name: DeleteList :exec
+DELETE FROM lists
+WHERE id = $1;
+
+-- name: ListListsByOwner :many
+SELECT * FROM lists
+WHERE owner_id = @owner_id
+    AND (NOT is_private OR @include_private::boolean)
+ORDER BY created_at DESC;
+
+-- name: ListSubscribedLists :many
+SELECT lists.* FROM lists
+JOIN list_subscriptions ON list_subscriptions.list_id = lists.id
+WHERE list_subscriptions.user_id = $1
+    AND (NOT lists.is_private OR lists.owner_id = $1)
+ORDER BY list_subscriptions.created_at DESC;
+
+-- name: AddListMember :exec
+INSERT INTO list_members (list_id, user_id, created_at)
+VALUES (
+    $1, $2, NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveListMember :execrows
+DELETE FROM list_members
+WHERE list_id = $1 AND user_id = $2;
+
+-- name: DeleteListMembershipsBetween :exec
+DELETE FROM list_members
+USING lists
+WHERE list_members.list_id = lists.id
+    AND (
+        (lists.owner_id = $1 AND list_members.user_id = $2)
+        OR (lists.owner_id = $2 AND list_members.user_id = $1)
+    );
+
+-- name: CountListMembers :one
+SELECT COUNT(*) FROM list_members
+WHERE list_id = $1;
+
+-- name: ListListMembers :many
+SELECT * FROM list_members
+WHERE list_id = @list_id
+    AND (created_at, user_id) < (@before_time::timestamp, @before_id::uuid)
+ORDER BY created_at DESC, user_id DESC
+LIMIT @page_size;
+
+-- name: SubscribeToList :exec
+INSERT INTO list_subscriptions (list_id, user_id, created_at)
+VALUES (
+    $1, $2, NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnsubscribeFromList :exec
+DELETE FROM list_subscriptions
+WHERE list_id = $1 AND user_id = $2;
+
+-- name: ListListTimeline :many
+SELECT chirps.* FROM chirps
+WHERE chirps.status = 'published'
+    AND chirps.user_id IN (SELECT list_members.user_id FROM list_members WHERE list_members.list_id = @list_id)
+    AND (chirps.created_at, chirps.id) < (@before_time::timestamp, @before_id::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT @page_size;
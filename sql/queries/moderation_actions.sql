@@ -0,0 +1,25 @@
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (id, created_at, moderator_id, action, report_id, target_user_id, target_chirp_id, reason, appeal_id)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetModerationAction :one
+SELECT * FROM moderation_actions
+WHERE id = $1;
+
+-- name: ListModerationActionsForReport :many
+SELECT * FROM moderation_actions
+WHERE report_id = $1
+ORDER BY created_at ASC;
+
+-- name: ListModerationActionsForAppeal :many
+SELECT * FROM moderation_actions
+WHERE appeal_id = $1
+ORDER BY created_at ASC;
+
+-- name: ListModerationActionsForUser :many
+SELECT * FROM moderation_actions
+WHERE target_user_id = $1
+ORDER BY created_at DESC;
@@ -0,0 +1,17 @@
+-- name: CreateMutedWord :one
+INSERT INTO muted_words (id, created_at, user_id, phrase, expires_at)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3
+)
+ON CONFLICT (user_id, phrase) DO UPDATE
+SET expires_at = EXCLUDED.expires_at
+RETURNING *;
+
+-- name: ListActiveMutedWords :many
+SELECT * FROM muted_words
+WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at ASC;
+
+-- name: DeleteMutedWord :execrows
+DELETE FROM muted_words
+WHERE id = $1 AND user_id = $2;
@@ -0,0 +1,69 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (id, created_at, user_id, actor_id, type, chirp_id, source_chirp_id)
+SELECT gen_random_uuid(), NOW(), @user_id::uuid, @actor_id::uuid, @type::text, sqlc.narg('chirp_id')::uuid, sqlc.narg('source_chirp_id')::uuid
+WHERE @user_id::uuid <> @actor_id::uuid
+    AND NOT EXISTS (
+        SELECT 1 FROM notification_preferences
+        WHERE notification_preferences.user_id = @user_id::uuid
+            AND notification_preferences.type = @type::text
+            AND NOT notification_preferences.enabled
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks
+        WHERE (blocks.blocker_id = @user_id::uuid AND blocks.blocked_id = @actor_id::uuid)
+           OR (blocks.blocker_id = @actor_id::uuid AND blocks.blocked_id = @user_id::uuid)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM mutes
+        WHERE mutes.muter_id = @user_id::uuid AND mutes.muted_id = @actor_id::uuid
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM users
+        WHERE users.id = @actor_id::uuid
+            AND users.account_status = 'shadow_banned'
+            AND (users.account_status_until IS NULL OR users.account_status_until > NOW())
+    )
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: DeleteNotification :exec
+DELETE FROM notifications
+WHERE user_id = @user_id
+    AND actor_id = @actor_id
+    AND type = @type
+    AND chirp_id IS NOT DISTINCT FROM sqlc.narg('chirp_id')::uuid;
+
+-- name: DeleteNotificationsBetween :exec
+DELETE FROM notifications
+WHERE (user_id = $1 AND actor_id = $2)
+   OR (user_id = $2 AND actor_id = $1);
+
+-- name: ListNotifications :many
+SELECT * FROM notifications
+WHERE user_id = @user_id
+    AND (created_at, id) < (@before_time::timestamp, @before_id::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT @page_size;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = @user_id
+    AND read_at IS NULL
+    AND (cardinality(@ids::uuid[]) = 0 OR id = ANY(@ids::uuid[]));
+
+-- name: ListNotificationPreferences :many
+SELECT * FROM notification_preferences
+WHERE user_id = $1
+ORDER BY type;
+
+-- name: UpsertNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, type, enabled)
+VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled;
@@ -0,0 +1,36 @@
+-- name: CreateOutboxEvent :exec
+INSERT INTO outbox (id, created_at, event_type, payload, available_at)
+VALUES (gen_random_uuid(), NOW(), $1, $2, NOW());
+
+-- name: ClaimOutboxEvents :many
+UPDATE outbox
+SET available_at = @lease_until::timestamp, attempts = attempts + 1
+WHERE id IN (
+    SELECT due.id FROM outbox AS due
+    WHERE due.published_at IS NULL
+        AND due.dead_at IS NULL
+        AND due.available_at <= NOW()
+    ORDER BY due.created_at
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox
+SET published_at = NOW()
+WHERE id = $1;
+
+-- name: RetryOutboxEvent :exec
+UPDATE outbox
+SET available_at = $2, last_error = $3
+WHERE id = $1;
+
+-- name: KillOutboxEvent :exec
+UPDATE outbox
+SET dead_at = NOW(), last_error = $2
+WHERE id = $1;
+
+-- name: DeletePublishedOutboxEvents :exec
+DELETE FROM outbox
+WHERE published_at < $1;
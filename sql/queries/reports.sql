@@ -0,0 +1,51 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, reporter_id, reported_user_id, chirp_id, category, details)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5
+)
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetReport :one
+SELECT * FROM reports
+WHERE id = $1;
+
+-- name: GetReportByReporterAndChirp :one
+SELECT * FROM reports
+WHERE reporter_id = $1 AND chirp_id = $2;
+
+-- name: GetReportByReporterAndUser :one
+SELECT * FROM reports
+WHERE reporter_id = $1 AND reported_user_id = $2 AND chirp_id IS NULL;
+
+-- name: ListReportsByStatus :many
+SELECT * FROM reports
+WHERE status = $1
+ORDER BY created_at ASC;
+
+-- name: ClaimReport :one
+UPDATE reports
+SET
+    status = 'claimed',
+    claimed_by = $2,
+    claimed_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND status = 'open'
+RETURNING *;
+
+-- name: ResolveReport :one
+UPDATE reports
+SET
+    status = 'resolved',
+    resolution = $2,
+    resolved_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND status = 'claimed' AND claimed_by = $3
+RETURNING *;
+
+-- name: ListResolvedChirpsForTraining :many
+SELECT DISTINCT chirps.id, chirps.body, chirps.status
+FROM reports
+JOIN chirps ON chirps.id = reports.chirp_id
+WHERE reports.status = 'resolved'
+ORDER BY chirps.id;
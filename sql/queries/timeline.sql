@@ -0,0 +1,66 @@
+-- name: ListHomeTimeline :many
+SELECT chirps.* FROM chirps
+WHERE chirps.status = 'published'
+    AND (
+        chirps.user_id = @user_id
+        OR chirps.id IN (
+            SELECT timeline_entries.chirp_id FROM timeline_entries
+            WHERE timeline_entries.user_id = @user_id
+                AND (timeline_entries.created_at, timeline_entries.chirp_id) < (@before_time::timestamp, @before_id::uuid)
+        )
+        OR chirps.user_id IN (
+            SELECT follows.followee_id FROM follows
+            JOIN timeline_heavy_authors ON timeline_heavy_authors.user_id = follows.followee_id
+            WHERE follows.follower_id = @user_id
+        )
+    )
+    AND chirps.user_id NOT IN (SELECT muted_id FROM mutes WHERE muter_id = @user_id)
+    AND chirps.user_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = @user_id)
+    AND chirps.user_id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = @user_id)
+    AND (
+        chirps.user_id = @user_id
+        OR NOT EXISTS (
+            SELECT 1 FROM users
+            WHERE users.id = chirps.user_id
+                AND users.account_status = 'shadow_banned'
+                AND (users.account_status_until IS NULL OR users.account_status_until > NOW())
+        )
+    )
+    AND (chirps.created_at, chirps.id) < (@before_time::timestamp, @before_id::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT @page_size;
+
+-- name: FanOutChirp :exec
+INSERT INTO timeline_entries (user_id, chirp_id, author_id, created_at)
+SELECT follows.follower_id, chirps.id, chirps.user_id, chirps.created_at
+FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE chirps.id = $1
+ON CONFLICT DO NOTHING;
+
+-- name: BackfillTimeline :exec
+INSERT INTO timeline_entries (user_id, chirp_id, author_id, created_at)
+SELECT @user_id::uuid, recent.id, recent.user_id, recent.created_at
+FROM (
+    SELECT chirps.id, chirps.user_id, chirps.created_at FROM chirps
+    WHERE chirps.user_id = @author_id
+    ORDER BY chirps.created_at DESC
+    LIMIT @backfill_limit
+) AS recent
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteTimelineEntriesFromAuthor :exec
+DELETE FROM timeline_entries
+WHERE user_id = $1 AND author_id = $2;
+
+-- name: DeleteTimelineEntriesBetween :exec
+DELETE FROM timeline_entries
+WHERE (user_id = $1 AND author_id = $2)
+   OR (user_id = $2 AND author_id = $1);
+
+-- name: MarkHeavyAuthor :exec
+INSERT INTO timeline_heavy_authors (user_id, created_at)
+VALUES (
+    $1, NOW()
+)
+ON CONFLICT DO NOTHING;
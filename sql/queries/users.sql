@@ -0,0 +1,101 @@
+-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3
+)
+RETURNING id, created_at, updated_at, email, is_chirpy_red, handle;
+
+-- name: DeleteAllUsers :exec
+DELETE FROM users;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: GetUserByHandle :one
+SELECT * FROM users
+WHERE LOWER(handle) = LOWER(@handle);
+
+-- name: GetUserFromRefreshToken :one
+SELECT * FROM users
+WHERE id = (
+    SELECT user_id
+    FROM refresh_tokens
+    WHERE token = $1
+);
+
+-- name: UpdateUserEmailAndPassword :exec
+UPDATE users
+SET 
+    hashed_password = $1,
+    email = $2
+WHERE id = $3;
+
+-- name: UpdateToChirpyRed :exec
+UPDATE users
+SET
+    is_chirpy_red = true
+WHERE id = $1;
+
+-- name: UpdateUserAccountStatus :exec
+UPDATE users
+SET
+    account_status = $1,
+    account_status_until = $2,
+    account_status_reason = $3,
+    updated_at = NOW()
+WHERE id = $4;
+
+-- name: ListShadowBannedUserIDs :many
+SELECT id FROM users
+WHERE account_status = 'shadow_banned'
+    AND (account_status_until IS NULL OR account_status_until > NOW());
+
+-- name: UpdateUserProtected :one
+UPDATE users
+SET
+    is_protected = $1,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: ListProtectedUserIDsHiddenFrom :many
+SELECT users.id FROM users
+WHERE users.is_protected
+    AND users.id <> $1
+    AND NOT EXISTS (
+        SELECT 1 FROM follows
+        WHERE follows.follower_id = $1 AND follows.followee_id = users.id
+    );
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET
+    display_name = $1,
+    bio = $2,
+    location = $3,
+    website = $4,
+    avatar_url = $5,
+    updated_at = NOW()
+WHERE id = $6
+RETURNING *;
+
+-- name: UpdateUserHandle :one
+UPDATE users
+SET
+    handle = $1,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: UpdateUserDMSettings :one
+UPDATE users
+SET
+    dms_followers_only = $1,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING *;
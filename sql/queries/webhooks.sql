@@ -0,0 +1,127 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (id, created_at, updated_at, user_id, url, secret, events)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks
+WHERE id = $1;
+
+-- name: ListWebhooksByUser :many
+SELECT * FROM webhooks
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: CountWebhooksByUser :one
+SELECT COUNT(*) FROM webhooks
+WHERE user_id = $1;
+
+-- name: UpdateWebhook :one
+UPDATE webhooks
+SET url = $1, events = $2, updated_at = NOW()
+WHERE id = $3
+RETURNING *;
+
+-- name: SetWebhookEnabled :one
+UPDATE webhooks
+SET
+    disabled_at = CASE WHEN @enabled::bool THEN NULL ELSE COALESCE(disabled_at, NOW()) END,
+    consecutive_failures = CASE WHEN @enabled::bool THEN 0 ELSE consecutive_failures END,
+    updated_at = NOW()
+WHERE id = @id
+RETURNING *;
+
+-- name: UpdateWebhookSecret :one
+UPDATE webhooks
+SET secret = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+WHERE id = $1;
+
+-- name: RecordWebhookSuccess :exec
+UPDATE webhooks
+SET consecutive_failures = 0
+WHERE id = $1;
+
+-- name: RecordWebhookFailure :one
+UPDATE webhooks
+SET
+    consecutive_failures = consecutive_failures + 1,
+    disabled_at = CASE
+        WHEN disabled_at IS NULL AND consecutive_failures + 1 >= @disable_threshold::int THEN NOW()
+        ELSE disabled_at
+    END
+WHERE id = @id
+RETURNING *;
+
+-- name: CreateWebhookDeliveries :exec
+INSERT INTO webhook_deliveries (id, created_at, webhook_id, event, payload, next_attempt_at)
+SELECT gen_random_uuid(), NOW(), webhooks.id, @event::text, @payload::text, NOW()
+FROM webhooks
+WHERE webhooks.user_id = @user_id
+    AND webhooks.disabled_at IS NULL
+    AND @event::text = ANY(webhooks.events);
+
+-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET next_attempt_at = @lease_until::timestamp
+FROM webhooks
+WHERE webhooks.id = webhook_deliveries.webhook_id
+    AND webhook_deliveries.id IN (
+        SELECT due.id FROM webhook_deliveries AS due
+        JOIN webhooks AS hooks ON hooks.id = due.webhook_id
+        WHERE due.status = 'pending'
+            AND due.next_attempt_at <= NOW()
+            AND hooks.disabled_at IS NULL
+        ORDER BY due.next_attempt_at
+        LIMIT @batch_size
+        FOR UPDATE OF due SKIP LOCKED
+    )
+RETURNING webhook_deliveries.id, webhook_deliveries.created_at, webhook_deliveries.webhook_id, webhook_deliveries.event, webhook_deliveries.payload, webhook_deliveries.attempts, webhooks.url, webhooks.secret;
+
+-- name: CreateWebhookAttempt :exec
+INSERT INTO webhook_attempts (id, created_at, delivery_id, status_code, error, duration_ms)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3, $4
+);
+
+-- name: MarkWebhookDeliveryAttempted :exec
+UPDATE webhook_deliveries
+SET
+    status = @status::text,
+    attempts = attempts + 1,
+    next_attempt_at = @next_attempt_at::timestamp,
+    last_status_code = sqlc.narg('last_status_code')::int,
+    last_error = @last_error::text,
+    delivered_at = CASE WHEN @status::text = 'delivered' THEN NOW() ELSE NULL END
+WHERE id = @id;
+
+-- name: FailPendingWebhookDeliveries :exec
+UPDATE webhook_deliveries
+SET status = 'failed', last_error = $1
+WHERE webhook_id = $2 AND status = 'pending';
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = @webhook_id
+    AND (created_at, id) < (@before_time::timestamp, @before_id::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT @page_size;
+
+-- name: ListWebhookAttempts :many
+SELECT * FROM webhook_attempts
+WHERE delivery_id = $1
+ORDER BY created_at ASC;
+
+-- name: DeleteFinishedWebhookDeliveries :exec
+DELETE FROM webhook_deliveries
+WHERE status <> 'pending' AND created_at < $1;
@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE chirps ADD COLUMN status TEXT NOT NULL DEFAULT 'published';
+
+-- +goose down
+ALTER TABLE chirps DROP COLUMN status;
@@ -0,0 +1,48 @@
+-- +goose up
+ALTER TABLE users ADD COLUMN is_moderator BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN account_status TEXT NOT NULL DEFAULT 'active';
+
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    reporter_id UUID,
+    reported_user_id UUID NOT NULL,
+    chirp_id UUID,
+    category TEXT NOT NULL,
+    details TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open',
+    claimed_by UUID,
+    claimed_at TIMESTAMP,
+    resolution TEXT,
+    resolved_at TIMESTAMP,
+    FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (reported_user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE,
+    FOREIGN KEY (claimed_by) REFERENCES users(id) ON DELETE SET NULL
+);
+CREATE UNIQUE INDEX reports_reporter_chirp_idx ON reports (reporter_id, chirp_id)
+    WHERE chirp_id IS NOT NULL;
+CREATE UNIQUE INDEX reports_reporter_user_idx ON reports (reporter_id, reported_user_id)
+    WHERE chirp_id IS NULL;
+
+CREATE TABLE moderation_actions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    moderator_id UUID,
+    action TEXT NOT NULL,
+    report_id UUID,
+    target_user_id UUID NOT NULL,
+    target_chirp_id UUID,
+    reason TEXT NOT NULL,
+    FOREIGN KEY (moderator_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (report_id) REFERENCES reports(id) ON DELETE SET NULL,
+    FOREIGN KEY (target_user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (target_chirp_id) REFERENCES chirps(id) ON DELETE SET NULL
+);
+
+-- +goose down
+DROP TABLE moderation_actions;
+DROP TABLE reports;
+ALTER TABLE users DROP COLUMN account_status;
+ALTER TABLE users DROP COLUMN is_moderator;
@@ -0,0 +1,13 @@
+-- +goose up
+CREATE TABLE muted_words (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    phrase TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (user_id, phrase)
+);
+
+-- +goose down
+DROP TABLE muted_words;
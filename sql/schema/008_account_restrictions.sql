@@ -0,0 +1,7 @@
+-- +goose up
+ALTER TABLE users ADD COLUMN account_status_until TIMESTAMP;
+ALTER TABLE users ADD COLUMN account_status_reason TEXT NOT NULL DEFAULT '';
+
+-- +goose down
+ALTER TABLE users DROP COLUMN account_status_reason;
+ALTER TABLE users DROP COLUMN account_status_until;
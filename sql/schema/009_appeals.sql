@@ -0,0 +1,22 @@
+-- +goose up
+CREATE TABLE appeals (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    action_id UUID NOT NULL UNIQUE,
+    statement TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    decided_by UUID,
+    decided_at TIMESTAMP,
+    decision_note TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (action_id) REFERENCES moderation_actions(id) ON DELETE CASCADE,
+    FOREIGN KEY (decided_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+ALTER TABLE moderation_actions ADD COLUMN appeal_id UUID REFERENCES appeals(id) ON DELETE SET NULL;
+
+-- +goose down
+ALTER TABLE moderation_actions DROP COLUMN appeal_id;
+DROP TABLE appeals;
@@ -0,0 +1,30 @@
+-- +goose up
+CREATE TABLE timeline_entries (
+    user_id UUID NOT NULL,
+    chirp_id UUID NOT NULL,
+    author_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, chirp_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE,
+    FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX timeline_entries_user_created_idx ON timeline_entries (user_id, created_at DESC, chirp_id DESC);
+CREATE INDEX timeline_entries_user_author_idx ON timeline_entries (user_id, author_id);
+CREATE INDEX timeline_entries_chirp_idx ON timeline_entries (chirp_id);
+
+CREATE TABLE timeline_heavy_authors (
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO timeline_entries (user_id, chirp_id, author_id, created_at)
+SELECT follows.follower_id, chirps.id, chirps.user_id, chirps.created_at
+FROM follows
+JOIN chirps ON chirps.user_id = follows.followee_id;
+
+-- +goose down
+DROP TABLE timeline_heavy_authors;
+DROP TABLE timeline_entries;
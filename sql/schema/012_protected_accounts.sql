@@ -0,0 +1,17 @@
+-- +goose up
+ALTER TABLE users ADD COLUMN is_protected BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE follow_requests (
+    requester_id UUID NOT NULL,
+    target_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (requester_id, target_id),
+    FOREIGN KEY (requester_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (target_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK (requester_id <> target_id)
+);
+CREATE INDEX follow_requests_target_idx ON follow_requests (target_id, created_at);
+
+-- +goose down
+DROP TABLE follow_requests;
+ALTER TABLE users DROP COLUMN is_protected;
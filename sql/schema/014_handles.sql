@@ -0,0 +1,20 @@
+-- +goose up
+ALTER TABLE users ADD COLUMN handle TEXT;
+UPDATE users SET handle = 'user_' || substr(replace(id::text, '-', ''), 1, 10);
+ALTER TABLE users ALTER COLUMN handle SET NOT NULL;
+CREATE UNIQUE INDEX users_handle_idx ON users (LOWER(handle));
+
+CREATE TABLE handle_history (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    handle TEXT NOT NULL,
+    released_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX handle_history_handle_idx ON handle_history (LOWER(handle), released_at);
+CREATE INDEX handle_history_user_idx ON handle_history (user_id, released_at);
+
+-- +goose down
+DROP TABLE handle_history;
+DROP INDEX users_handle_idx;
+ALTER TABLE users DROP COLUMN handle;
@@ -0,0 +1,32 @@
+-- +goose up
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    actor_id UUID NOT NULL,
+    type TEXT NOT NULL,
+    chirp_id UUID,
+    source_chirp_id UUID,
+    read_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE,
+    FOREIGN KEY (source_chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+CREATE INDEX notifications_user_created_idx ON notifications (user_id, created_at DESC, id DESC);
+CREATE INDEX notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+-- Repeating an action such as like, unlike, like again notifies only once.
+CREATE UNIQUE INDEX notifications_once_idx ON notifications (user_id, actor_id, type, COALESCE(chirp_id, user_id))
+    WHERE type IN ('like', 'rechirp', 'follow', 'follow_request');
+
+CREATE TABLE notification_preferences (
+    user_id UUID NOT NULL,
+    type TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    PRIMARY KEY (user_id, type),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose down
+DROP TABLE notification_preferences;
+DROP TABLE notifications;
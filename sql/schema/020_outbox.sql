@@ -0,0 +1,16 @@
+-- +goose up
+CREATE TABLE outbox (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    available_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    published_at TIMESTAMP
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (available_at) WHERE published_at IS NULL;
+
+-- +goose down
+DROP TABLE outbox;
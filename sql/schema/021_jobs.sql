@@ -0,0 +1,24 @@
+-- +goose up
+CREATE TABLE jobs (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL,
+    run_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    finished_at TIMESTAMP,
+    unique_key TEXT
+);
+
+CREATE INDEX jobs_due_idx ON jobs (priority DESC, run_at) WHERE status IN ('pending', 'running');
+CREATE INDEX jobs_status_idx ON jobs (status, created_at, id);
+CREATE UNIQUE INDEX jobs_unique_key_idx ON jobs (unique_key) WHERE status IN ('pending', 'running');
+
+-- +goose down
+DROP TABLE jobs;
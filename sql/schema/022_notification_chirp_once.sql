@@ -0,0 +1,16 @@
+-- +goose up
+-- A chirp notifies each replied-to or mentioned user once, however often
+-- its side effects are redelivered.
+DELETE FROM notifications AS duplicate
+USING notifications AS kept
+WHERE duplicate.type IN ('reply', 'mention')
+    AND kept.type = duplicate.type
+    AND kept.user_id = duplicate.user_id
+    AND COALESCE(kept.source_chirp_id, kept.chirp_id) = COALESCE(duplicate.source_chirp_id, duplicate.chirp_id)
+    AND (kept.created_at, kept.id) < (duplicate.created_at, duplicate.id);
+
+CREATE UNIQUE INDEX notifications_chirp_once_idx ON notifications (user_id, type, COALESCE(source_chirp_id, chirp_id))
+    WHERE type IN ('reply', 'mention');
+
+-- +goose down
+DROP INDEX notifications_chirp_once_idx;
@@ -0,0 +1,14 @@
+-- +goose up
+-- Events that keep failing are set aside rather than retried forever.
+ALTER TABLE outbox ADD COLUMN max_attempts INTEGER NOT NULL DEFAULT 20;
+ALTER TABLE outbox ADD COLUMN dead_at TIMESTAMP;
+
+DROP INDEX outbox_pending_idx;
+CREATE INDEX outbox_pending_idx ON outbox (available_at) WHERE published_at IS NULL AND dead_at IS NULL;
+
+-- +goose down
+DROP INDEX outbox_pending_idx;
+CREATE INDEX outbox_pending_idx ON outbox (available_at) WHERE published_at IS NULL;
+
+ALTER TABLE outbox DROP COLUMN dead_at;
+ALTER TABLE outbox DROP COLUMN max_attempts;
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/David-Bosnic/chirpy/internal/tags"
+	"github.com/google/uuid"
+)
+
+const (
+	// streamBuffer is how many chirps a client may fall behind before it is
+	// disconnected and has to resume with Last-Event-ID.
+	streamBuffer = 256
+	// streamReplayLimit caps how many missed chirps are replayed on
+	// reconnect; clients that were gone longer should reload instead.
+	streamReplayLimit = 1000
+	streamReplayBatch = 100
+	streamHeartbeat   = 15 * time.Second
+)
+
+// streamFilter narrows the stream to one author, one hashtag or the
+// viewer's home timeline. Unset fields match everything.
+type streamFilter struct {
+	authorID uuid.UUID
+	hashtag  string
+	// timeline holds the viewer and everyone they follow when the home
+	// timeline was requested.
+	timeline map[uuid.UUID]bool
+}
+
+func (f streamFilter) matches(chirp database.Chirp) bool {
+	if f.authorID != uuid.Nil && chirp.UserID != f.authorID {
+		return false
+	}
+	if f.timeline != nil && !f.timeline[chirp.UserID] {
+		return false
+	}
+	if f.hashtag != "" {
+		for _, hashtag := range tags.Hashtags(chirp.Body) {
+			if hashtag == f.hashtag {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// after reports whether c sorts after other in (created_at, id) order, the
+// order chirps are published to the stream in.
+func (c pageCursor) after(other pageCursor) bool {
+	if !c.CreatedAt.Equal(other.CreatedAt) {
+		return c.CreatedAt.After(other.CreatedAt)
+	}
+	return bytes.Compare(c.ID[:], other.ID[:]) > 0
+}
+
+func (cfg *apiConfig) parseStreamFilter(w http.ResponseWriter, r *http.Request, viewer *viewerFilter) (streamFilter, bool) {
+	filter := streamFilter{}
+	query := r.URL.Query()
+	if s := query.Get("author"); s != "" {
+		if handle, ok := strings.CutPrefix(s, "@"); ok {
+			user, err := cfg.queries.GetUserByHandle(r.Context(), handle)
+			if err != nil {
+				w.WriteHeader(404)
+				w.Write([]byte("User does not exist"))
+				return streamFilter{}, false
+			}
+			filter.authorID = user.ID
+		} else {
+			authorID, err := uuid.Parse(s)
+			if err != nil {
+				w.WriteHeader(400)
+				w.Write([]byte("Error parsing author"))
+				return streamFilter{}, false
+			}
+			filter.authorID = authorID
+		}
+	}
+	if s := query.Get("hashtag"); s != "" {
+		filter.hashtag = strings.ToLower(strings.TrimPrefix(s, "#"))
+	}
+	switch query.Get("timeline") {
+	case "":
+	case "home":
+		if viewer.viewerID == uuid.Nil {
+			w.WriteHeader(401)
+			w.Write([]byte("Log in to stream your home timeline"))
+			return streamFilter{}, false
+		}
+		followeeIDs, err := cfg.queries.ListFolloweeIDs(r.Context(), viewer.viewerID)
+		if err != nil {
+			log.Printf("Error listing followees: %s", err)
+			w.WriteHeader(500)
+			w.Write([]byte("Failed to open stream"))
+			return streamFilter{}, false
+		}
+		filter.timeline = map[uuid.UUID]bool{viewer.viewerID: true}
+		for _, id := range followeeIDs {
+			filter.timeline[id] = true
+		}
+	default:
+		w.WriteHeader(400)
+		w.Write([]byte("timeline must be home"))
+		return streamFilter{}, false
+	}
+	return filter, true
+}
+
+// writeStreamEvent sends the chirp as an SSE event whose ID is its cursor,
+// if the viewer may see it and it passes the filter.
+func writeStreamEvent(w http.ResponseWriter, viewer *viewerFilter, filter streamFilter, chirp database.Chirp) error {
+	if !filter.matches(chirp) {
+		return nil
+	}
+	visible := viewer.present([]database.Chirp{chirp}, false)
+	if len(visible) == 0 {
+		return nil
+	}
+	dat, err := json.Marshal(visible[0])
+	if err != nil {
+		return err
+	}
+	cursor := pageCursor{CreatedAt: chirp.CreatedAt, ID: chirp.ID}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: chirp\ndata: %s\n\n", cursor, dat)
+	return err
+}
+
+// handlerStream pushes newly published chirps as server-sent events. A
+// client that reconnects with Last-Event-ID first gets the chirps it missed,
+// read back from the database, and then the live stream.
+func (cfg *apiConfig) handlerStream(w http.ResponseWriter, r *http.Request) {
+	viewer, err := cfg.viewerFilterFromRequest(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	filter, ok := cfg.parseStreamFilter(w, r, viewer)
+	if !ok {
+		return
+	}
+	var last pageCursor
+	resuming := false
+	if s := r.Header.Get("Last-Event-ID"); s != "" {
+		last, err = parsePageCursor(s)
+		if err != nil {
+			w.WriteHeader(400)
+			w.Write([]byte("Error parsing Last-Event-ID"))
+			return
+		}
+		resuming = true
+	}
+
+	// Subscribe before replaying so nothing published during the replay is
+	// lost; live chirps the replay already covered are skipped.
+	sub := cfg.chirpStream.Subscribe(streamBuffer)
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(200)
+
+	if resuming {
+		until := time.Now().UTC()
+		for replayed := 0; replayed < streamReplayLimit; replayed += streamReplayBatch {
+			chirps, err := cfg.queries.ListPublishedChirpsAfter(r.Context(), database.ListPublishedChirpsAfterParams{
+				AfterTime: last.CreatedAt,
+				AfterID:   last.ID,
+				UntilTime: until,
+				BatchSize: streamReplayBatch,
+			})
+			if err != nil {
+				log.Printf("Error replaying stream: %s", err)
+				return
+			}
+			for _, chirp := range chirps {
+				err = writeStreamEvent(w, viewer, filter, chirp)
+				if err != nil {
+					return
+				}
+				last = pageCursor{CreatedAt: chirp.CreatedAt, ID: chirp.ID}
+			}
+			if len(chirps) < streamReplayBatch {
+				break
+			}
+		}
+	}
+	err = rc.Flush()
+	if err != nil {
+		log.Printf("Error flushing stream: %s", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-cfg.stopStreams:
+			return
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		case chirp, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client reconnects and
+				// resumes from the last event it got.
+				return
+			}
+			cursor := pageCursor{CreatedAt: chirp.CreatedAt, ID: chirp.ID}
+			if resuming && !cursor.after(last) {
+				continue
+			}
+			err = writeStreamEvent(w, viewer, filter, chirp)
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
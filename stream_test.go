@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestStreamFilterMatches(t *testing.T) {
+	authorID := uuid.New()
+	followeeID := uuid.New()
+	strangerID := uuid.New()
+	cases := []struct {
+		filter streamFilter
+		chirp  database.Chirp
+		want   bool
+	}{
+		{streamFilter{}, database.Chirp{UserID: strangerID, Body: "anything"}, true},
+		{streamFilter{authorID: authorID}, database.Chirp{UserID: authorID, Body: "mine"}, true},
+		{streamFilter{authorID: authorID}, database.Chirp{UserID: strangerID, Body: "not mine"}, false},
+		{streamFilter{hashtag: "go"}, database.Chirp{UserID: strangerID, Body: "I like #Go"}, true},
+		{streamFilter{hashtag: "go"}, database.Chirp{UserID: strangerID, Body: "I like #golang"}, false},
+		{streamFilter{timeline: map[uuid.UUID]bool{followeeID: true}}, database.Chirp{UserID: followeeID}, true},
+		{streamFilter{timeline: map[uuid.UUID]bool{followeeID: true}}, database.Chirp{UserID: strangerID}, false},
+		{streamFilter{authorID: authorID, hashtag: "go"}, database.Chirp{UserID: authorID, Body: "no tag"}, false},
+	}
+	for i, c := range cases {
+		if got := c.filter.matches(c.chirp); got != c.want {
+			t.Errorf("Case %d: Got: %v, Expected: %v", i, got, c.want)
+		}
+	}
+}
+
+func TestPageCursorAfter(t *testing.T) {
+	now := time.Now()
+	low := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	high := uuid.MustParse("ffffffff-0000-0000-0000-000000000000")
+	if !(pageCursor{CreatedAt: now.Add(time.Second), ID: low}).after(pageCursor{CreatedAt: now, ID: high}) {
+		t.Errorf("Expected a later cursor to sort after an earlier one")
+	}
+	if !(pageCursor{CreatedAt: now, ID: high}).after(pageCursor{CreatedAt: now, ID: low}) {
+		t.Errorf("Expected ties on time to be broken by ID")
+	}
+	if (pageCursor{CreatedAt: now, ID: low}).after(pageCursor{CreatedAt: now, ID: low}) {
+		t.Errorf("Expected a cursor not to sort after itself")
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/David-Bosnic/chirpy/internal/suggest"
+	"github.com/David-Bosnic/chirpy/internal/tags"
+	"github.com/google/uuid"
+)
+
+const (
+	suggestionsCandidateLimit = 200
+	suggestionsActivityWindow = 7 * 24 * time.Hour
+	suggestionsHashtagWindow  = 30 * 24 * time.Hour
+	suggestionsRecentChirps   = 5000
+	// suggestionsCached is how many ranked suggestions are kept per user, so
+	// that accounts followed or blocked since the last refresh can be
+	// dropped without running out.
+	suggestionsCached = 100
+
+	suggestionsTTL             = 30 * time.Minute
+	suggestionsIdle            = 24 * time.Hour
+	suggestionsRefreshInterval = 5 * time.Minute
+)
+
+// computeSuggestions gathers friends-of-friends, shared hashtag and recent
+// activity signals for the user and ranks every account they turn up.
+func (cfg *apiConfig) computeSuggestions(ctx context.Context, userID uuid.UUID) ([]suggest.Suggestion, error) {
+	now := time.Now()
+	candidates := map[uuid.UUID]*suggest.Candidate{}
+	candidate := func(id uuid.UUID) *suggest.Candidate {
+		c, ok := candidates[id]
+		if !ok {
+			c = &suggest.Candidate{UserID: id}
+			candidates[id] = c
+		}
+		return c
+	}
+
+	friendsOfFriends, err := cfg.queries.ListFriendsOfFriends(ctx, database.ListFriendsOfFriendsParams{
+		UserID:         userID,
+		CandidateLimit: suggestionsCandidateLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range friendsOfFriends {
+		candidate(row.UserID).MutualFollows = row.MutualCount
+	}
+
+	ownChirps, err := cfg.queries.ListRecentChirpsFromAuthorID(ctx, database.ListRecentChirpsFromAuthorIDParams{
+		UserID:    userID,
+		CreatedAt: now.Add(-suggestionsHashtagWindow),
+	})
+	if err != nil {
+		return nil, err
+	}
+	ownHashtags := map[string]bool{}
+	for _, chirp := range ownChirps {
+		for _, hashtag := range tags.Hashtags(chirp.Body) {
+			ownHashtags[hashtag] = true
+		}
+	}
+
+	recentChirps, err := cfg.queries.ListRecentPublishedChirps(ctx, database.ListRecentPublishedChirpsParams{
+		CreatedAt: now.Add(-suggestionsActivityWindow),
+		Limit:     suggestionsRecentChirps,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sharedHashtags := map[uuid.UUID]map[string]bool{}
+	for _, chirp := range recentChirps {
+		if chirp.UserID == userID {
+			continue
+		}
+		c := candidate(chirp.UserID)
+		c.RecentChirps++
+		if chirp.CreatedAt.After(c.LastChirpAt) {
+			c.LastChirpAt = chirp.CreatedAt
+		}
+		for _, hashtag := range tags.Hashtags(chirp.Body) {
+			if !ownHashtags[hashtag] {
+				continue
+			}
+			if sharedHashtags[chirp.UserID] == nil {
+				sharedHashtags[chirp.UserID] = map[string]bool{}
+			}
+			sharedHashtags[chirp.UserID][hashtag] = true
+		}
+	}
+	for id, shared := range sharedHashtags {
+		candidates[id].SharedHashtags = len(shared)
+	}
+
+	pool := make([]suggest.Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		pool = append(pool, *c)
+	}
+	return suggest.Rank(pool, now, suggestionsCached), nil
+}
+
+// excludeSuggestions returns up to limit of the ranked suggestions, leaving
+// out the viewer, accounts they already follow, accounts they muted and
+// accounts hidden from them by a block or shadow ban.
+func excludeSuggestions(ranked []suggest.Suggestion, viewer *viewerFilter, followeeIDs []uuid.UUID, limit int) []suggest.Suggestion {
+	following := map[uuid.UUID]bool{}
+	for _, id := range followeeIDs {
+		following[id] = true
+	}
+	suggestions := []suggest.Suggestion{}
+	for _, suggestion := range ranked {
+		if len(suggestions) == limit {
+			break
+		}
+		id := suggestion.UserID
+		if id == viewer.viewerID || following[id] || viewer.muted[id] || !viewer.canSeeProfile(id) {
+			continue
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions
+}
+
+func (cfg *apiConfig) handlerSuggestionsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	limit := 20
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > 50 {
+			w.WriteHeader(400)
+			w.Write([]byte("limit must be between 1 and 50"))
+			return
+		}
+	}
+	ranked, err := cfg.suggestions.Get(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error computing suggestions: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to get suggestions"))
+		return
+	}
+	// The cache can be up to suggestionsTTL old, so exclusions are applied
+	// against the current follow graph on every request.
+	viewer, err := cfg.newViewerFilter(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error building viewer filter: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to get suggestions"))
+		return
+	}
+	followeeIDs, err := cfg.queries.ListFolloweeIDs(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error listing followees: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to get suggestions"))
+		return
+	}
+	dat, err := json.Marshal(excludeSuggestions(ranked, viewer, followeeIDs, limit))
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
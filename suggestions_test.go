@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/David-Bosnic/chirpy/internal/suggest"
+	"github.com/google/uuid"
+)
+
+func TestExcludeSuggestions(t *testing.T) {
+	viewerID := uuid.New()
+	followedID := uuid.New()
+	mutedID := uuid.New()
+	blockedID := uuid.New()
+	shadowBannedID := uuid.New()
+	protectedID := uuid.New()
+	strangerID := uuid.New()
+	otherID := uuid.New()
+	viewer := &viewerFilter{
+		viewerID:     viewerID,
+		blocked:      map[uuid.UUID]bool{blockedID: true},
+		muted:        map[uuid.UUID]bool{mutedID: true},
+		shadowBanned: map[uuid.UUID]bool{shadowBannedID: true},
+		protected:    map[uuid.UUID]bool{protectedID: true},
+	}
+	ranked := []suggest.Suggestion{}
+	for _, id := range []uuid.UUID{viewerID, followedID, mutedID, blockedID, shadowBannedID, protectedID, strangerID, otherID} {
+		ranked = append(ranked, suggest.Suggestion{UserID: id})
+	}
+
+	// Protected accounts can still be suggested, since following them
+	// starts with a request.
+	cases := []struct {
+		limit int
+		want  []uuid.UUID
+	}{
+		{20, []uuid.UUID{protectedID, strangerID, otherID}},
+		{2, []uuid.UUID{protectedID, strangerID}},
+	}
+	for _, c := range cases {
+		got := excludeSuggestions(ranked, viewer, []uuid.UUID{followedID}, c.limit)
+		if len(got) != len(c.want) {
+			t.Errorf("limit %d: Got %d suggestions, Expected %d: %v", c.limit, len(got), len(c.want), got)
+			continue
+		}
+		for i, id := range c.want {
+			if got[i].UserID != id {
+				t.Errorf("limit %d, case %d: Got: %s, Expected: %s", c.limit, i, got[i].UserID, id)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+)
+
+const (
+	// timelineFanOutLimit is the follower count above which an author's
+	// chirps are merged into timelines at read time instead of being written
+	// to every follower's timeline. Authors stay merged on read once they
+	// cross it, since their older chirps were never fanned out.
+	timelineFanOutLimit = 10000
+	// timelineBackfillLimit is how many recent chirps are copied into a
+	// timeline when its owner follows someone new.
+	timelineBackfillLimit = 100
+)
+
+// fanOutChirp writes a new chirp into its author's followers' timelines.
+// Entries already written are left alone, so it is safe to repeat.
+func (cfg *apiConfig) fanOutChirp(ctx context.Context, chirp database.Chirp) error {
+	followers, err := cfg.queries.CountFollowers(ctx, chirp.UserID)
+	if err != nil {
+		return err
+	}
+	if followers > timelineFanOutLimit {
+		return cfg.queries.MarkHeavyAuthor(ctx, chirp.UserID)
+	}
+	return cfg.queries.FanOutChirp(ctx, chirp.ID)
+}
+
+// writeChirpPage presents a page of chirps to the viewer and writes it. The
+// next cursor follows the last row the query returned, so chirps dropped for
+// muted phrases do not end the listing early.
+func writeChirpPage(w http.ResponseWriter, r *http.Request, viewer *viewerFilter, chirps []database.Chirp, limit int32) {
+	page := Page[Chirp]{
+		Items: viewer.present(chirps, r.URL.Query().Get("collapse_muted") == "true"),
+	}
+	if len(chirps) > 0 {
+		last := chirps[len(chirps)-1]
+		page.NextCursor = nextCursor(len(chirps), limit, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	dat, err := json.Marshal(page)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerTimeline(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	viewer, err := cfg.newViewerFilter(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error building viewer filter: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to get timeline"))
+		return
+	}
+	chirps, err := cfg.queries.ListHomeTimeline(r.Context(), database.ListHomeTimelineParams{
+		UserID:     userID,
+		BeforeTime: cursor.CreatedAt,
+		BeforeID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		log.Printf("Error listing timeline: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to get timeline"))
+		return
+	}
+	writeChirpPage(w, r, viewer, chirps, limit)
+}
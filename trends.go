@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/David-Bosnic/chirpy/internal/trends"
+	"github.com/google/uuid"
+)
+
+const (
+	trendsRefreshInterval = time.Minute
+	// trendsWarmUp is how much history is read at startup to rebuild tag
+	// baselines.
+	trendsWarmUp = 7 * 24 * time.Hour
+)
+
+var trendsConfig = trends.Config{
+	HalfLife:         2 * time.Hour,
+	BaselineHalfLife: 3 * 24 * time.Hour,
+	Window:           24 * time.Hour,
+	MinAuthors:       3,
+	Prior:            1,
+}
+
+// fetchTrendPosts reads chirps for the trends aggregator. Trends are shown
+// to everyone, so chirps from protected accounts are left out.
+func (cfg *apiConfig) fetchTrendPosts(ctx context.Context, afterTime time.Time, afterID uuid.UUID, until time.Time, limit int32) ([]trends.Post, error) {
+	chirps, err := cfg.queries.ListPublicChirpsAfter(ctx, database.ListPublicChirpsAfterParams{
+		AfterTime: afterTime,
+		AfterID:   afterID,
+		UntilTime: until,
+		BatchSize: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	posts := make([]trends.Post, 0, len(chirps))
+	for _, chirp := range chirps {
+		posts = append(posts, trends.Post{
+			ID:        chirp.ID,
+			AuthorID:  chirp.UserID,
+			Body:      chirp.Body,
+			CreatedAt: chirp.CreatedAt,
+		})
+	}
+	return posts, nil
+}
+
+func (cfg *apiConfig) handlerTrendsList(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if s := r.URL.Query().Get("limit"); s != "" {
+		var err error
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > 50 {
+			w.WriteHeader(400)
+			w.Write([]byte("limit must be between 1 and 50"))
+			return
+		}
+	}
+	dat, err := json.Marshal(cfg.trends.Trends(limit))
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
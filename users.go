@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxLocationLength    = 30
+	maxURLLength         = 2048
+)
+
+func formatUser(user database.User) User {
+	return User{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		Handle:      user.Handle,
+		IsChirpyRed: user.IsChirpyRed,
+		IsProtected: user.IsProtected,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		Location:    user.Location,
+		Website:     user.Website,
+		AvatarURL:   user.AvatarUrl,
+
+		DMsFollowersOnly: user.DmsFollowersOnly,
+	}
+}
+
+func (cfg *apiConfig) handlerUsersGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	viewer, err := cfg.viewerFilterFromRequest(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	user, err := cfg.queries.GetUserByID(r.Context(), userID)
+	if err != nil || !viewer.canSeeProfile(user.ID) {
+		w.WriteHeader(404)
+		w.Write([]byte("User does not exist"))
+		return
+	}
+	resp := formatUser(user)
+	if viewer.viewerID != user.ID {
+		resp.Email = ""
+	}
+	err = cfg.followCounts(r.Context(), &resp)
+	if err != nil {
+		log.Printf("Error counting follows: %s", err)
+	}
+	dat, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerUsersUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticateWriter(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	// Omitted fields keep their current value; an empty string clears one.
+	type parameters struct {
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+		Location    *string `json:"location"`
+		Website     *string `json:"website"`
+		AvatarURL   *string `json:"avatar_url"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error decoding parameters"))
+		return
+	}
+	user, err := cfg.queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error getting user: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to update profile"))
+		return
+	}
+	profile := database.UpdateUserProfileParams{
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		Location:    user.Location,
+		Website:     user.Website,
+		AvatarUrl:   user.AvatarUrl,
+		ID:          user.ID,
+	}
+	fields := []struct {
+		name   string
+		value  *string
+		dst    *string
+		maxLen int
+		isURL  bool
+	}{
+		{"display_name", params.DisplayName, &profile.DisplayName, maxDisplayNameLength, false},
+		{"bio", params.Bio, &profile.Bio, maxBioLength, false},
+		{"location", params.Location, &profile.Location, maxLocationLength, false},
+		{"website", params.Website, &profile.Website, maxURLLength, true},
+		{"avatar_url", params.AvatarURL, &profile.AvatarUrl, maxURLLength, true},
+	}
+	for _, field := range fields {
+		if field.value == nil {
+			continue
+		}
+		value := strings.TrimSpace(*field.value)
+		if utf8.RuneCountInString(value) > field.maxLen {
+			w.WriteHeader(400)
+			w.Write([]byte(field.name + " is too long"))
+			return
+		}
+		if field.isURL && value != "" {
+			err = validateProfileURL(value)
+			if err != nil {
+				w.WriteHeader(400)
+				w.Write([]byte(field.name + ": " + err.Error()))
+				return
+			}
+		}
+		*field.dst = value
+	}
+	user, err = cfg.queries.UpdateUserProfile(r.Context(), profile)
+	if err != nil {
+		log.Printf("Error updating profile: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to update profile"))
+		return
+	}
+	resp := formatUser(user)
+	err = cfg.followCounts(r.Context(), &resp)
+	if err != nil {
+		log.Printf("Error counting follows: %s", err)
+	}
+	dat, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+// validateProfileURL accepts absolute http and https URLs only, so profile
+// links cannot carry javascript: or data: payloads.
+func validateProfileURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return errors.New("invalid URL")
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("URL must be an absolute http or https link")
+	}
+	return nil
+}
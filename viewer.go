@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/David-Bosnic/chirpy/internal/tags"
+	"github.com/google/uuid"
+)
+
+// viewerFilter decides which chirps a user may see in listings. Anonymous
+// viewers get a filter with a nil viewerID that only hides shadow-banned and
+// protected authors. A nil filter hides nothing.
+type viewerFilter struct {
+	viewerID     uuid.UUID
+	blocked      map[uuid.UUID]bool
+	muted        map[uuid.UUID]bool
+	shadowBanned map[uuid.UUID]bool
+	protected    map[uuid.UUID]bool
+	phrases      []mutedPhrase
+}
+
+func (cfg *apiConfig) newViewerFilter(ctx context.Context, viewerID uuid.UUID) (*viewerFilter, error) {
+	filter := &viewerFilter{
+		viewerID:     viewerID,
+		blocked:      map[uuid.UUID]bool{},
+		muted:        map[uuid.UUID]bool{},
+		shadowBanned: map[uuid.UUID]bool{},
+		protected:    map[uuid.UUID]bool{},
+	}
+	shadowBannedIDs, err := cfg.queries.ListShadowBannedUserIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range shadowBannedIDs {
+		filter.shadowBanned[id] = true
+	}
+	protectedIDs, err := cfg.queries.ListProtectedUserIDsHiddenFrom(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range protectedIDs {
+		filter.protected[id] = true
+	}
+	if viewerID == uuid.Nil {
+		return filter, nil
+	}
+	blockedIDs, err := cfg.queries.ListBlockedEitherWayUserIDs(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range blockedIDs {
+		filter.blocked[id] = true
+	}
+	mutedIDs, err := cfg.queries.ListMutedUserIDs(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range mutedIDs {
+		filter.muted[id] = true
+	}
+	mutedWords, err := cfg.queries.ListActiveMutedWords(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, mutedWord := range mutedWords {
+		phrase := newMutedPhrase(mutedWord.Phrase)
+		phrase.expiresAt = mutedWord.ExpiresAt
+		filter.phrases = append(filter.phrases, phrase)
+	}
+	return filter, nil
+}
+
+// viewerFilterFromRequest builds a filter for the bearer token's user, or an
+// anonymous one when the request has no Authorization header.
+func (cfg *apiConfig) viewerFilterFromRequest(r *http.Request) (*viewerFilter, error) {
+	if r.Header.Get("Authorization") == "" {
+		return cfg.newViewerFilter(r.Context(), uuid.Nil)
+	}
+	viewerID, err := cfg.authenticate(r)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.newViewerFilter(r.Context(), viewerID)
+}
+
+// canSeeProfile reports whether the viewer may see the user at all. Blocks
+// in either direction and shadow bans hide a user; users always see
+// themselves.
+func (f *viewerFilter) canSeeProfile(userID uuid.UUID) bool {
+	if f == nil || userID == f.viewerID {
+		return true
+	}
+	return !f.blocked[userID] && !f.shadowBanned[userID]
+}
+
+// canSee reports whether the viewer may see chirps by the author. On top of
+// canSeeProfile, a protected account hides its chirps from viewers who do not
+// follow it.
+func (f *viewerFilter) canSee(authorID uuid.UUID) bool {
+	if f == nil || authorID == f.viewerID {
+		return true
+	}
+	return f.canSeeProfile(authorID) && !f.protected[authorID]
+}
+
+// allows reports whether a chirp's author belongs in the viewer's listings.
+// Muted phrases are handled separately by present.
+func (f *viewerFilter) allows(chirp database.Chirp) bool {
+	if f == nil {
+		return true
+	}
+	return f.canSee(chirp.UserID) && !f.muted[chirp.UserID]
+}
+
+func (f *viewerFilter) filter(chirps []database.Chirp) []database.Chirp {
+	if f == nil {
+		return chirps
+	}
+	visible := []database.Chirp{}
+	for _, chirp := range chirps {
+		if f.allows(chirp) {
+			visible = append(visible, chirp)
+		}
+	}
+	return visible
+}
+
+// mutedPhraseIn returns the first of the viewer's muted phrases found in the
+// chirp. The viewer's own chirps never match. Expiry is checked here rather
+// than only when the filter is built, since a stream keeps its filter for as
+// long as it stays open.
+func (f *viewerFilter) mutedPhraseIn(chirp database.Chirp) (string, bool) {
+	if f == nil || chirp.UserID == f.viewerID || len(f.phrases) == 0 {
+		return "", false
+	}
+	now := time.Now()
+	hashtags := tags.Hashtags(chirp.Body)
+	for _, phrase := range f.phrases {
+		if phrase.expired(now) {
+			continue
+		}
+		if phrase.matches(chirp.Body, hashtags) {
+			return phrase.phrase, true
+		}
+	}
+	return "", false
+}
+
+// present formats the chirps the viewer is allowed to see. Chirps containing
+// a muted phrase are dropped, or collapsed to an empty body when collapse is
+// set so clients can show a placeholder.
+func (f *viewerFilter) present(chirps []database.Chirp, collapse bool) []Chirp {
+	formattedChirps := []Chirp{}
+	for _, chirp := range f.filter(chirps) {
+		formattedChirp := addTagsToChirp(chirp)
+		if phrase, ok := f.mutedPhraseIn(chirp); ok {
+			if !collapse {
+				continue
+			}
+			formattedChirp.Body = ""
+			formattedChirp.Collapsed = true
+			formattedChirp.MutedPhrase = phrase
+		}
+		formattedChirps = append(formattedChirps, formattedChirp)
+	}
+	return formattedChirps
+}
+
+// mutedPhrase matches a muted keyword as a whole word or phrase, or a muted
+// hashtag (a phrase starting with '#') against the chirp's hashtags.
+type mutedPhrase struct {
+	phrase    string
+	hashtag   string
+	re        *regexp.Regexp
+	expiresAt sql.NullTime
+}
+
+func newMutedPhrase(phrase string) mutedPhrase {
+	if strings.HasPrefix(phrase, "#") {
+		return mutedPhrase{
+			phrase:  phrase,
+			hashtag: strings.ToLower(strings.TrimPrefix(phrase, "#")),
+		}
+	}
+	return mutedPhrase{
+		phrase: phrase,
+		re:     regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}])` + regexp.QuoteMeta(phrase) + `(?:$|[^\p{L}\p{N}])`),
+	}
+}
+
+func (m mutedPhrase) expired(now time.Time) bool {
+	return m.expiresAt.Valid && !m.expiresAt.Time.After(now)
+}
+
+func (m mutedPhrase) matches(body string, hashtags []string) bool {
+	if m.re == nil {
+		for _, hashtag := range hashtags {
+			if hashtag == m.hashtag {
+				return true
+			}
+		}
+		return false
+	}
+	return m.re.MatchString(body)
+}
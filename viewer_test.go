@@ -0,0 +1,218 @@
+package main
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestViewerFilterPresent(t *testing.T) {
+	viewerID := uuid.New()
+	blockedID := uuid.New()
+	mutedID := uuid.New()
+	friendID := uuid.New()
+	shadowBannedID := uuid.New()
+	protectedID := uuid.New()
+	filter := &viewerFilter{
+		viewerID:     viewerID,
+		blocked:      map[uuid.UUID]bool{blockedID: true},
+		muted:        map[uuid.UUID]bool{mutedID: true},
+		shadowBanned: map[uuid.UUID]bool{shadowBannedID: true, viewerID: true},
+		protected:    map[uuid.UUID]bool{protectedID: true},
+		phrases: []mutedPhrase{
+			newMutedPhrase("spoilers"),
+			newMutedPhrase("#finale"),
+		},
+	}
+	chirps := []database.Chirp{
+		{ID: uuid.New(), UserID: friendID, Body: "plain chirp"},
+		{ID: uuid.New(), UserID: blockedID, Body: "from someone blocked"},
+		{ID: uuid.New(), UserID: mutedID, Body: "from someone muted"},
+		{ID: uuid.New(), UserID: shadowBannedID, Body: "from someone shadow-banned"},
+		{ID: uuid.New(), UserID: protectedID, Body: "from a protected account"},
+		{ID: uuid.New(), UserID: friendID, Body: "Major SPOILERS ahead"},
+		{ID: uuid.New(), UserID: friendID, Body: "what a #Finale"},
+		{ID: uuid.New(), UserID: friendID, Body: "nospoilersinsideaword"},
+		{ID: uuid.New(), UserID: viewerID, Body: "my own spoilers"},
+	}
+
+	visible := filter.present(chirps, false)
+	// The viewer is shadow-banned too but still sees their own chirps.
+	want := []string{"plain chirp", "nospoilersinsideaword", "my own spoilers"}
+	if len(visible) != len(want) {
+		t.Fatalf("Got %d chirps, Expected %d: %v", len(visible), len(want), visible)
+	}
+	for i, body := range want {
+		if visible[i].Body != body {
+			t.Errorf("Case %d: Got: %q, Expected: %q", i, visible[i].Body, body)
+		}
+	}
+
+	collapsed := filter.present(chirps, true)
+	if len(collapsed) != 5 {
+		t.Fatalf("Got %d chirps, Expected 5 with muted phrases collapsed", len(collapsed))
+	}
+	if !collapsed[1].Collapsed || collapsed[1].Body != "" || collapsed[1].MutedPhrase != "spoilers" {
+		t.Errorf("Expected chirp with muted keyword to be collapsed, got %+v", collapsed[1])
+	}
+	if !collapsed[2].Collapsed || collapsed[2].MutedPhrase != "#finale" {
+		t.Errorf("Expected chirp with muted hashtag to be collapsed, got %+v", collapsed[2])
+	}
+}
+
+func TestViewerFilterBlocksAndMutes(t *testing.T) {
+	viewerID := uuid.New()
+	blockedID := uuid.New()
+	mutedID := uuid.New()
+	strangerID := uuid.New()
+	// ListBlockedEitherWayUserIDs puts users the viewer blocked and users who
+	// blocked the viewer in the same set.
+	filter := &viewerFilter{
+		viewerID:     viewerID,
+		blocked:      map[uuid.UUID]bool{blockedID: true},
+		muted:        map[uuid.UUID]bool{mutedID: true},
+		shadowBanned: map[uuid.UUID]bool{},
+		protected:    map[uuid.UUID]bool{},
+	}
+	cases := []struct {
+		name       string
+		filter     *viewerFilter
+		userID     uuid.UUID
+		seeProfile bool
+		seeChirps  bool
+		inListings bool
+	}{
+		{"blocked", filter, blockedID, false, false, false},
+		{"muted", filter, mutedID, true, true, false},
+		{"stranger", filter, strangerID, true, true, true},
+		{"self", filter, viewerID, true, true, true},
+		{"no filter", nil, blockedID, true, true, true},
+	}
+	for _, c := range cases {
+		chirp := database.Chirp{ID: uuid.New(), UserID: c.userID}
+		if got := c.filter.canSeeProfile(c.userID); got != c.seeProfile {
+			t.Errorf("%s: canSeeProfile Got: %v, Expected: %v", c.name, got, c.seeProfile)
+		}
+		if got := c.filter.canSee(c.userID); got != c.seeChirps {
+			t.Errorf("%s: canSee Got: %v, Expected: %v", c.name, got, c.seeChirps)
+		}
+		if got := c.filter.allows(chirp); got != c.inListings {
+			t.Errorf("%s: allows Got: %v, Expected: %v", c.name, got, c.inListings)
+		}
+		if got := len(c.filter.filter([]database.Chirp{chirp})) == 1; got != c.inListings {
+			t.Errorf("%s: filter Got: %v, Expected: %v", c.name, got, c.inListings)
+		}
+	}
+
+	// Anonymous viewers have no blocks or mutes of their own.
+	anonymous := &viewerFilter{
+		blocked:      map[uuid.UUID]bool{},
+		muted:        map[uuid.UUID]bool{},
+		shadowBanned: map[uuid.UUID]bool{},
+		protected:    map[uuid.UUID]bool{},
+	}
+	for _, id := range []uuid.UUID{blockedID, mutedID} {
+		if !anonymous.allows(database.Chirp{UserID: id}) {
+			t.Errorf("Expected an anonymous viewer to see chirps by %s", id)
+		}
+	}
+}
+
+func TestViewerFilterProtected(t *testing.T) {
+	ownerID := uuid.New()
+	followerID := uuid.New()
+	strangerID := uuid.New()
+	// ListProtectedUserIDsHiddenFrom leaves out accounts the viewer follows
+	// and the viewer's own.
+	filters := map[string]*viewerFilter{
+		"owner":     {viewerID: ownerID, protected: map[uuid.UUID]bool{}},
+		"follower":  {viewerID: followerID, protected: map[uuid.UUID]bool{}},
+		"stranger":  {viewerID: strangerID, protected: map[uuid.UUID]bool{ownerID: true}},
+		"anonymous": {protected: map[uuid.UUID]bool{ownerID: true}},
+	}
+	cases := []struct {
+		viewer     string
+		seeProfile bool
+		seeChirps  bool
+	}{
+		{"owner", true, true},
+		{"follower", true, true},
+		{"stranger", true, false},
+		{"anonymous", true, false},
+	}
+	chirp := database.Chirp{ID: uuid.New(), UserID: ownerID, Body: "followers only"}
+	for _, c := range cases {
+		filter := filters[c.viewer]
+		if got := filter.canSeeProfile(ownerID); got != c.seeProfile {
+			t.Errorf("%s: canSeeProfile Got: %v, Expected: %v", c.viewer, got, c.seeProfile)
+		}
+		if got := filter.canSee(ownerID); got != c.seeChirps {
+			t.Errorf("%s: canSee Got: %v, Expected: %v", c.viewer, got, c.seeChirps)
+		}
+		if got := len(filter.present([]database.Chirp{chirp}, true)) == 1; got != c.seeChirps {
+			t.Errorf("%s: present Got: %v, Expected: %v", c.viewer, got, c.seeChirps)
+		}
+		// The live stream goes through the same filter.
+		w := httptest.NewRecorder()
+		if err := writeStreamEvent(w, filter, streamFilter{}, chirp); err != nil {
+			t.Fatal(err)
+		}
+		if got := w.Body.Len() > 0; got != c.seeChirps {
+			t.Errorf("%s: stream Got: %v, Expected: %v", c.viewer, got, c.seeChirps)
+		}
+	}
+}
+
+func TestViewerFilterMutedPhraseExpiry(t *testing.T) {
+	now := time.Now()
+	phrase := func(text string, expiresAt sql.NullTime) mutedPhrase {
+		p := newMutedPhrase(text)
+		p.expiresAt = expiresAt
+		return p
+	}
+	filter := &viewerFilter{
+		viewerID: uuid.New(),
+		phrases: []mutedPhrase{
+			phrase("forever", sql.NullTime{}),
+			phrase("later", sql.NullTime{Time: now.Add(time.Hour), Valid: true}),
+			phrase("earlier", sql.NullTime{Time: now.Add(-time.Minute), Valid: true}),
+			phrase("#expired", sql.NullTime{Time: now.Add(-time.Hour), Valid: true}),
+		},
+	}
+	cases := []struct {
+		body  string
+		muted bool
+	}{
+		{"muted forever", true},
+		{"muted until later", true},
+		{"muted until earlier", false},
+		{"an #expired hashtag", false},
+	}
+	for _, c := range cases {
+		chirp := database.Chirp{ID: uuid.New(), UserID: uuid.New(), Body: c.body}
+		if _, got := filter.mutedPhraseIn(chirp); got != c.muted {
+			t.Errorf("%q: Got: %v, Expected: %v", c.body, got, c.muted)
+		}
+		if got := len(filter.present([]database.Chirp{chirp}, false)) == 0; got != c.muted {
+			t.Errorf("%q: present hid it: %v, Expected: %v", c.body, got, c.muted)
+		}
+	}
+
+	// A filter outlives its phrases' expiry when a stream holds it open.
+	expiring := &viewerFilter{
+		viewerID: uuid.New(),
+		phrases:  []mutedPhrase{phrase("soon", sql.NullTime{Time: time.Now().Add(50 * time.Millisecond), Valid: true})},
+	}
+	chirp := database.Chirp{ID: uuid.New(), UserID: uuid.New(), Body: "over soon"}
+	if _, muted := expiring.mutedPhraseIn(chirp); !muted {
+		t.Fatal("Expected the phrase to be muted before it expires")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, muted := expiring.mutedPhraseIn(chirp); muted {
+		t.Error("Expected the phrase to stop matching once it expires")
+	}
+}
@@ -0,0 +1,607 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/David-Bosnic/chirpy/internal/database"
+	"github.com/David-Bosnic/chirpy/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+const (
+	maxWebhooksPerUser  = 10
+	maxWebhookURLLength = 2048
+
+	webhookTimeout          = 10 * time.Second
+	webhookDispatchInterval = 5 * time.Second
+	webhookBatchSize        = 20
+	// webhookLease hides a claimed delivery from other instances while it
+	// is being sent. It must outlast webhookTimeout.
+	webhookLease = time.Minute
+	// webhookDisableThreshold is how many failed attempts in a row disable
+	// a webhook.
+	webhookDisableThreshold = 20
+	webhookRetention        = 30 * 24 * time.Hour
+	webhookPruneInterval    = time.Hour
+)
+
+// Delivery statuses.
+const (
+	webhookDeliveryPending   = "pending"
+	webhookDeliveryDelivered = "delivered"
+	webhookDeliveryFailed    = "failed"
+)
+
+type Webhook struct {
+	ID                  uuid.UUID  `json:"id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	URL                 string     `json:"url"`
+	Events              []string   `json:"events"`
+	Enabled             bool       `json:"enabled"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	// Secret is only returned when it is created or rotated.
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID        `json:"id"`
+	CreatedAt      time.Time        `json:"created_at"`
+	Event          string           `json:"event"`
+	Status         string           `json:"status"`
+	Attempts       int32            `json:"attempts"`
+	NextAttemptAt  *time.Time       `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int32           `json:"last_status_code,omitempty"`
+	LastError      string           `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time       `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage  `json:"payload,omitempty"`
+	AttemptLog     []WebhookAttempt `json:"attempt_log,omitempty"`
+}
+
+type WebhookAttempt struct {
+	CreatedAt  time.Time `json:"created_at"`
+	StatusCode *int32    `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int32     `json:"duration_ms"`
+}
+
+// webhookPayload is the body of every delivery.
+type webhookPayload struct {
+	Event     string            `json:"event"`
+	CreatedAt time.Time         `json:"created_at"`
+	Data      NotificationGroup `json:"data"`
+}
+
+func formatWebhook(webhook database.Webhook) Webhook {
+	return Webhook{
+		ID:                  webhook.ID,
+		CreatedAt:           webhook.CreatedAt,
+		UpdatedAt:           webhook.UpdatedAt,
+		URL:                 webhook.Url,
+		Events:              webhook.Events,
+		Enabled:             !webhook.DisabledAt.Valid,
+		DisabledAt:          nullTimePtr(webhook.DisabledAt),
+		ConsecutiveFailures: webhook.ConsecutiveFailures,
+	}
+}
+
+func formatWebhookDelivery(delivery database.WebhookDelivery) WebhookDelivery {
+	formatted := WebhookDelivery{
+		ID:             delivery.ID,
+		CreatedAt:      delivery.CreatedAt,
+		Event:          delivery.Event,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: nullInt32Ptr(delivery.LastStatusCode),
+		LastError:      delivery.LastError,
+		DeliveredAt:    nullTimePtr(delivery.DeliveredAt),
+	}
+	if delivery.Status == webhookDeliveryPending {
+		formatted.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	return formatted
+}
+
+func nullInt32Ptr(n sql.NullInt32) *int32 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int32
+}
+
+// validateWebhookEvents checks that every event is a notification type and
+// returns them sorted without duplicates.
+func validateWebhookEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("at least one event is required")
+	}
+	seen := map[string]bool{}
+	valid := []string{}
+	for _, event := range events {
+		if _, ok := notificationTypes[event]; !ok {
+			return nil, fmt.Errorf("unknown event %q", event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			valid = append(valid, event)
+		}
+	}
+	sort.Strings(valid)
+	return valid, nil
+}
+
+func validateWebhookURL(raw string) error {
+	if len(raw) > maxWebhookURLLength {
+		return fmt.Errorf("URL must be at most %d characters", maxWebhookURLLength)
+	}
+	return webhooks.ValidateURL(raw)
+}
+
+// enqueueWebhooks queues a delivery of the notification to each of the
+// user's webhooks subscribed to its type. Sending happens in the
+// dispatcher.
+func enqueueWebhooks(ctx context.Context, q *database.Queries, notification database.Notification) error {
+	payload, err := json.Marshal(webhookPayload{
+		Event:     notification.Type,
+		CreatedAt: notification.CreatedAt,
+		Data:      groupNotifications([]database.Notification{notification})[0],
+	})
+	if err != nil {
+		return err
+	}
+	return q.CreateWebhookDeliveries(ctx, database.CreateWebhookDeliveriesParams{
+		Event:   notification.Type,
+		Payload: string(payload),
+		UserID:  notification.UserID,
+	})
+}
+
+// dispatchWebhooks sends due deliveries every webhookDispatchInterval and
+// prunes old ones every webhookPruneInterval until ctx is done. Deliveries
+// are claimed with SKIP LOCKED, so every instance can run it.
+func (cfg *apiConfig) dispatchWebhooks(ctx context.Context) {
+	dispatch := time.NewTicker(webhookDispatchInterval)
+	defer dispatch.Stop()
+	prune := time.NewTicker(webhookPruneInterval)
+	defer prune.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-dispatch.C:
+			err := cfg.sendDueWebhooks(ctx)
+			if err != nil {
+				log.Printf("Error dispatching webhooks: %s", err)
+			}
+		case <-prune.C:
+			err := cfg.queries.DeleteFinishedWebhookDeliveries(ctx, time.Now().UTC().Add(-webhookRetention))
+			if err != nil {
+				log.Printf("Error pruning webhook deliveries: %s", err)
+			}
+		}
+	}
+}
+
+// sendDueWebhooks claims due deliveries in batches and sends each batch
+// concurrently, until none are left.
+func (cfg *apiConfig) sendDueWebhooks(ctx context.Context) error {
+	for {
+		claimed, err := cfg.queries.ClaimWebhookDeliveries(ctx, database.ClaimWebhookDeliveriesParams{
+			LeaseUntil: time.Now().UTC().Add(webhookLease),
+			BatchSize:  webhookBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+		var wg sync.WaitGroup
+		for _, delivery := range claimed {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cfg.attemptWebhook(ctx, delivery)
+			}()
+		}
+		wg.Wait()
+		if len(claimed) < webhookBatchSize {
+			return nil
+		}
+	}
+}
+
+// attemptWebhook sends one delivery and records the outcome: a success, a
+// retry with backoff, or a final failure once webhooks.MaxAttempts is
+// reached. Too many failures in a row disable the webhook.
+func (cfg *apiConfig) attemptWebhook(ctx context.Context, delivery database.ClaimWebhookDeliveriesRow) {
+	result := cfg.webhookSender.Send(ctx, delivery.Url, delivery.Secret, delivery.ID, delivery.Event, []byte(delivery.Payload))
+	statusCode := sql.NullInt32{Int32: int32(result.StatusCode), Valid: result.StatusCode != 0}
+	errMsg := ""
+	if result.Err != nil {
+		errMsg = result.Err.Error()
+	} else if !result.OK() {
+		errMsg = fmt.Sprintf("unexpected status %d", result.StatusCode)
+	}
+	err := cfg.queries.CreateWebhookAttempt(ctx, database.CreateWebhookAttemptParams{
+		DeliveryID: delivery.ID,
+		StatusCode: statusCode,
+		Error:      errMsg,
+		DurationMs: int32(result.Duration.Milliseconds()),
+	})
+	if err != nil {
+		log.Printf("Error logging webhook attempt: %s", err)
+	}
+
+	attempts := int(delivery.Attempts) + 1
+	now := time.Now().UTC()
+	update := database.MarkWebhookDeliveryAttemptedParams{
+		Status:         webhookDeliveryDelivered,
+		NextAttemptAt:  now,
+		LastStatusCode: statusCode,
+		LastError:      errMsg,
+		ID:             delivery.ID,
+	}
+	if !result.OK() {
+		update.Status = webhookDeliveryPending
+		update.NextAttemptAt = now.Add(webhooks.Backoff(attempts))
+		if attempts >= webhooks.MaxAttempts {
+			update.Status = webhookDeliveryFailed
+		}
+	}
+	err = cfg.queries.MarkWebhookDeliveryAttempted(ctx, update)
+	if err != nil {
+		log.Printf("Error recording webhook delivery: %s", err)
+	}
+
+	if result.OK() {
+		err = cfg.queries.RecordWebhookSuccess(ctx, delivery.WebhookID)
+		if err != nil {
+			log.Printf("Error recording webhook success: %s", err)
+		}
+		return
+	}
+	webhook, err := cfg.queries.RecordWebhookFailure(ctx, database.RecordWebhookFailureParams{
+		DisableThreshold: webhookDisableThreshold,
+		ID:               delivery.WebhookID,
+	})
+	if err != nil {
+		log.Printf("Error recording webhook failure: %s", err)
+		return
+	}
+	if webhook.DisabledAt.Valid {
+		err = cfg.queries.FailPendingWebhookDeliveries(ctx, database.FailPendingWebhookDeliveriesParams{
+			LastError: "webhook disabled after repeated failures",
+			WebhookID: webhook.ID,
+		})
+		if err != nil {
+			log.Printf("Error failing deliveries of disabled webhook: %s", err)
+		}
+	}
+}
+
+// ownedWebhook authenticates the caller and resolves {webhookID} to one of
+// their webhooks. Other users' webhooks look missing.
+func (cfg *apiConfig) ownedWebhook(w http.ResponseWriter, r *http.Request) (database.Webhook, bool) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return database.Webhook{}, false
+	}
+	webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing webhookID"))
+		return database.Webhook{}, false
+	}
+	webhook, err := cfg.queries.GetWebhook(r.Context(), webhookID)
+	if err != nil || webhook.UserID != userID {
+		w.WriteHeader(404)
+		w.Write([]byte("Webhook does not exist"))
+		return database.Webhook{}, false
+	}
+	return webhook, true
+}
+
+func writeWebhook(w http.ResponseWriter, code int, webhook Webhook) {
+	dat, err := json.Marshal(webhook)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(code)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerWebhooksCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	type parameters struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error decoding parameters"))
+		return
+	}
+	err = validateWebhookURL(params.URL)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	events, err := validateWebhookEvents(params.Events)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	count, err := cfg.queries.CountWebhooksByUser(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error counting webhooks: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to create webhook"))
+		return
+	}
+	if count >= maxWebhooksPerUser {
+		w.WriteHeader(400)
+		w.Write([]byte(fmt.Sprintf("Accounts can have at most %d webhooks", maxWebhooksPerUser)))
+		return
+	}
+	webhook, err := cfg.queries.CreateWebhook(r.Context(), database.CreateWebhookParams{
+		UserID: userID,
+		Url:    params.URL,
+		Secret: webhooks.NewSecret(),
+		Events: events,
+	})
+	if err != nil {
+		log.Printf("Error creating webhook: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to create webhook"))
+		return
+	}
+	formatted := formatWebhook(webhook)
+	formatted.Secret = webhook.Secret
+	writeWebhook(w, 201, formatted)
+}
+
+func (cfg *apiConfig) handlerWebhooksList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+	hooks, err := cfg.queries.ListWebhooksByUser(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error listing webhooks: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list webhooks"))
+		return
+	}
+	formatted := []Webhook{}
+	for _, webhook := range hooks {
+		formatted = append(formatted, formatWebhook(webhook))
+	}
+	dat, err := json.Marshal(formatted)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) handlerWebhooksGet(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := cfg.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+	writeWebhook(w, 200, formatWebhook(webhook))
+}
+
+// handlerWebhooksUpdate changes the URL or events, or re-enables a webhook
+// that was disabled, which also resets its failure count.
+func (cfg *apiConfig) handlerWebhooksUpdate(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := cfg.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+	type parameters struct {
+		URL     *string  `json:"url"`
+		Events  []string `json:"events"`
+		Enabled *bool    `json:"enabled"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error decoding parameters"))
+		return
+	}
+	update := database.UpdateWebhookParams{
+		Url:    webhook.Url,
+		Events: webhook.Events,
+		ID:     webhook.ID,
+	}
+	if params.URL != nil {
+		err = validateWebhookURL(*params.URL)
+		if err != nil {
+			w.WriteHeader(400)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		update.Url = *params.URL
+	}
+	if params.Events != nil {
+		update.Events, err = validateWebhookEvents(params.Events)
+		if err != nil {
+			w.WriteHeader(400)
+			w.Write([]byte(err.Error()))
+			return
+		}
+	}
+	webhook, err = cfg.queries.UpdateWebhook(r.Context(), update)
+	if err != nil {
+		log.Printf("Error updating webhook: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to update webhook"))
+		return
+	}
+	if params.Enabled != nil {
+		webhook, err = cfg.queries.SetWebhookEnabled(r.Context(), database.SetWebhookEnabledParams{
+			Enabled: *params.Enabled,
+			ID:      webhook.ID,
+		})
+		if err != nil {
+			log.Printf("Error updating webhook: %s", err)
+			w.WriteHeader(500)
+			w.Write([]byte("Failed to update webhook"))
+			return
+		}
+		if !*params.Enabled {
+			err = cfg.queries.FailPendingWebhookDeliveries(r.Context(), database.FailPendingWebhookDeliveriesParams{
+				LastError: "webhook disabled",
+				WebhookID: webhook.ID,
+			})
+			if err != nil {
+				log.Printf("Error failing deliveries of disabled webhook: %s", err)
+			}
+		}
+	}
+	writeWebhook(w, 200, formatWebhook(webhook))
+}
+
+func (cfg *apiConfig) handlerWebhooksDelete(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := cfg.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.queries.DeleteWebhook(r.Context(), webhook.ID)
+	if err != nil {
+		log.Printf("Error deleting webhook: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to delete webhook"))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// handlerWebhooksRotateSecret replaces the signing secret. Deliveries
+// already queued are signed with the new one.
+func (cfg *apiConfig) handlerWebhooksRotateSecret(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := cfg.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+	webhook, err := cfg.queries.UpdateWebhookSecret(r.Context(), database.UpdateWebhookSecretParams{
+		Secret: webhooks.NewSecret(),
+		ID:     webhook.ID,
+	})
+	if err != nil {
+		log.Printf("Error rotating webhook secret: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to rotate webhook secret"))
+		return
+	}
+	formatted := formatWebhook(webhook)
+	formatted.Secret = webhook.Secret
+	writeWebhook(w, 200, formatted)
+}
+
+func (cfg *apiConfig) handlerWebhookDeliveriesList(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := cfg.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	deliveries, err := cfg.queries.ListWebhookDeliveries(r.Context(), database.ListWebhookDeliveriesParams{
+		WebhookID:  webhook.ID,
+		BeforeTime: cursor.CreatedAt,
+		BeforeID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		log.Printf("Error listing webhook deliveries: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to list webhook deliveries"))
+		return
+	}
+	page := Page[WebhookDelivery]{Items: []WebhookDelivery{}}
+	for _, delivery := range deliveries {
+		page.Items = append(page.Items, formatWebhookDelivery(delivery))
+	}
+	if len(deliveries) > 0 {
+		last := deliveries[len(deliveries)-1]
+		page.NextCursor = nextCursor(len(deliveries), limit, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	dat, err := json.Marshal(page)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+// handlerWebhookDeliveriesGet returns one delivery with its payload and
+// every attempt made.
+func (cfg *apiConfig) handlerWebhookDeliveriesGet(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := cfg.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Error parsing deliveryID"))
+		return
+	}
+	delivery, err := cfg.queries.GetWebhookDelivery(r.Context(), deliveryID)
+	if err != nil || delivery.WebhookID != webhook.ID {
+		w.WriteHeader(404)
+		w.Write([]byte("Delivery does not exist"))
+		return
+	}
+	attempts, err := cfg.queries.ListWebhookAttempts(r.Context(), delivery.ID)
+	if err != nil {
+		log.Printf("Error listing webhook attempts: %s", err)
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to get webhook delivery"))
+		return
+	}
+	formatted := formatWebhookDelivery(delivery)
+	formatted.Payload = json.RawMessage(delivery.Payload)
+	formatted.AttemptLog = []WebhookAttempt{}
+	for _, attempt := range attempts {
+		formatted.AttemptLog = append(formatted.AttemptLog, WebhookAttempt{
+			CreatedAt:  attempt.CreatedAt,
+			StatusCode: nullInt32Ptr(attempt.StatusCode),
+			Error:      attempt.Error,
+			DurationMs: attempt.DurationMs,
+		})
+	}
+	dat, err := json.Marshal(formatted)
+	if err != nil {
+		log.Printf("Error marshaling JSON: %s", err)
+		return
+	}
+	w.WriteHeader(200)
+	w.Write(dat)
+}
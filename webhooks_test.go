@@ -0,0 +1,22 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateWebhookEvents(t *testing.T) {
+	events, err := validateWebhookEvents([]string{"reply", "follow", "mention", "reply"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"follow", "mention", "reply"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("Got: %v, Expected: %v", events, want)
+	}
+	if _, err := validateWebhookEvents([]string{"reply", "password_reset"}); err == nil {
+		t.Errorf("Expected an unknown event to be refused")
+	}
+	if _, err := validateWebhookEvents(nil); err == nil {
+		t.Errorf("Expected at least one event to be required")
+	}
+}